@@ -1,6 +1,7 @@
 package qualys
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,7 +11,7 @@ import (
 
 // getAvailableAppliances loads Appliances from the Qualys API returning the Network ID of the appliances and filters out
 // the appliances that are NOT "Online"
-func (session *Session) getAvailableAppliances(engine string) (networkID int, appliances []string, err error) {
+func (session *Session) getAvailableAppliances(ctx context.Context, engine string) (networkID int, appliances []string, err error) {
 	appliances = make([]string, 0)
 	var output = &QAppliances{}
 
@@ -20,7 +21,7 @@ func (session *Session) getAvailableAppliances(engine string) (networkID int, ap
 	fields["ids"] = engine
 
 	// Execute POST call to Qualys API for appliance informatino
-	if err = session.post(session.Config.Address()+qsAppliance, fields, output); err == nil {
+	if err = session.post(ctx, session.Config.Address()+qsAppliance, fields, output); err == nil {
 
 		// Process the appliances returned from the API
 		networkID, appliances = session.processApplianceResults(output)
@@ -35,12 +36,17 @@ func (session *Session) getAvailableAppliances(engine string) (networkID int, ap
 
 // GetApplianceInformation loads the information for the appliance ids that are passed in
 func (session *Session) GetApplianceInformation(appliances []string) (output *QAppliances, err error) {
+	return session.GetApplianceInformationContext(session.ctx, appliances)
+}
+
+// GetApplianceInformationContext is GetApplianceInformation with a context that aborts the API calls when cancelled
+func (session *Session) GetApplianceInformationContext(ctx context.Context, appliances []string) (output *QAppliances, err error) {
 	var fields = make(map[string]string)
 	fields["action"] = "list"
 	fields["ids"] = strings.Join(appliances, ",")
 
 	output = &QAppliances{}
-	err = session.post(session.Config.Address()+qsAppliance, fields, output)
+	err = session.post(ctx, session.Config.Address()+qsAppliance, fields, output)
 
 	return output, err
 }
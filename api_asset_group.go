@@ -1,13 +1,21 @@
 package qualys
 
 import (
+	"context"
+	"fmt"
 	"github.com/nortonlifelock/log"
+	"strconv"
 	"strings"
 )
 
 // LoadAssetGroups loads the asset groups passed in through an int slice from Qualys and returns the Assignment Group
 // output from Qualys
 func (session *Session) LoadAssetGroups(ids []int) (ags *QSAGListOutput, err error) {
+	return session.LoadAssetGroupsContext(session.ctx, ids)
+}
+
+// LoadAssetGroupsContext is LoadAssetGroups with a context that aborts the API calls when cancelled
+func (session *Session) LoadAssetGroupsContext(ctx context.Context, ids []int) (ags *QSAGListOutput, err error) {
 
 	// API Flags
 	var fields = make(map[string]string)
@@ -22,9 +30,371 @@ func (session *Session) LoadAssetGroups(ids []int) (ags *QSAGListOutput, err err
 
 	ags = &QSAGListOutput{}
 
-	if err = session.post(session.Config.Address()+qsAssetGroup, fields, ags); err != nil {
+	if err = session.post(ctx, session.Config.Address()+qsAssetGroup, fields, ags); err != nil {
 		session.lstream.Send(log.Errorf(err, "nil response while calling api [%s]", qsAssetGroup))
 	}
 
 	return ags, err
 }
+
+// Business impact values of an asset group
+const (
+	BusinessImpactCritical = "Critical"
+	BusinessImpactHigh     = "High"
+	BusinessImpactMedium   = "Medium"
+	BusinessImpactLow      = "Low"
+	BusinessImpactMinor    = "Minor"
+)
+
+// AssetGroupDefinition describes an asset group to create, or the desired state of an asset group to reconcile
+type AssetGroupDefinition struct {
+	// ID identifies the asset group to reconcile, the asset group is looked up by its title when the ID is not set
+	ID    int
+	Title string
+
+	// IPs and Ranges are the IPs (e.g. 10.0.0.1) and IP ranges (e.g. 10.0.0.1-10.0.0.254) of the asset group. CIDR blocks
+	// are also accepted
+	IPs    []string
+	Ranges []string
+
+	ApplianceIDs       []int
+	DefaultApplianceID int
+	BusinessImpact     string
+	Domains            []string
+
+	// Comments replace the comments of the asset group when they are set. The comments of the asset group are left as they
+	// are when Comments is empty, unless ClearComments is set
+	Comments      string
+	ClearComments bool
+
+	// NetworkID can only be set when the asset group is created
+	NetworkID int
+}
+
+// AssetGroupUpdate holds the changes made to an asset group by UpdateAssetGroup. Fields left at their zero value are
+// not changed
+type AssetGroupUpdate struct {
+	Title              string
+	BusinessImpact     string
+	Comments           *string
+	DefaultApplianceID int
+
+	AddIPs    []string
+	RemoveIPs []string
+
+	AddApplianceIDs    []int
+	RemoveApplianceIDs []int
+
+	AddDomains    []string
+	RemoveDomains []string
+}
+
+// Empty determines whether the update holds no changes
+func (update AssetGroupUpdate) Empty() bool {
+	return len(update.fields()) == 0
+}
+
+// fields converts the update to the parameters of the edit call, leaving out the parameters that aren't changed
+func (update AssetGroupUpdate) fields() (fields map[string]string) {
+	fields = make(map[string]string)
+
+	if len(update.Title) > 0 {
+		fields["set_title"] = update.Title
+	}
+
+	if len(update.BusinessImpact) > 0 {
+		fields["set_business_impact"] = update.BusinessImpact
+	}
+
+	if update.Comments != nil {
+		fields["set_comments"] = *update.Comments
+	}
+
+	setInt(fields, "set_default_appliance_id", update.DefaultApplianceID)
+	setList(fields, "add_ips", update.AddIPs)
+	setList(fields, "remove_ips", update.RemoveIPs)
+	setList(fields, "add_appliance_ids", intArrayToStringArray(update.AddApplianceIDs))
+	setList(fields, "remove_appliance_ids", intArrayToStringArray(update.RemoveApplianceIDs))
+	setList(fields, "add_domains", update.AddDomains)
+	setList(fields, "remove_domains", update.RemoveDomains)
+
+	return fields
+}
+
+// LoadAssetGroupByTitle loads the asset group with the title. A nil asset group is returned when no asset group has the
+// title
+func (session *Session) LoadAssetGroupByTitle(title string) (group *QSAssetGroup, err error) {
+	return session.LoadAssetGroupByTitleContext(session.ctx, title)
+}
+
+// LoadAssetGroupByTitleContext is LoadAssetGroupByTitle with a context that aborts the API call when cancelled
+func (session *Session) LoadAssetGroupByTitleContext(ctx context.Context, title string) (group *QSAssetGroup, err error) {
+	var fields = make(map[string]string)
+	fields["action"] = "list"
+	fields["show_attributes"] = "ALL"
+	fields["title"] = title
+
+	var ags = &QSAGListOutput{}
+	if err = session.post(ctx, session.Config.Address()+qsAssetGroup, fields, ags); err == nil {
+		for _, ag := range ags.Groups {
+			if ag.Title != nil && ag.Title.Text == title {
+				group = ag
+				break
+			}
+		}
+	} else {
+		session.lstream.Send(log.Errorf(err, "nil response while calling api [%s]", qsAssetGroup))
+	}
+
+	return group, err
+}
+
+// CreateAssetGroup creates an asset group from the definition and returns the ID of the new asset group
+func (session *Session) CreateAssetGroup(definition AssetGroupDefinition) (id int, err error) {
+	return session.CreateAssetGroupContext(session.ctx, definition)
+}
+
+// CreateAssetGroupContext is CreateAssetGroup with a context that aborts the API call when cancelled
+func (session *Session) CreateAssetGroupContext(ctx context.Context, definition AssetGroupDefinition) (id int, err error) {
+	if len(definition.Title) > 0 {
+		var fields = make(map[string]string)
+		fields["action"] = "add"
+		fields["title"] = definition.Title
+
+		setList(fields, "ips", append(append([]string{}, definition.IPs...), definition.Ranges...))
+		setList(fields, "appliance_ids", intArrayToStringArray(definition.ApplianceIDs))
+		setInt(fields, "default_appliance_id", definition.DefaultApplianceID)
+		setList(fields, "domains", definition.Domains)
+		setInt(fields, "network_id", definition.NetworkID)
+
+		if len(definition.BusinessImpact) > 0 {
+			fields["business_impact"] = definition.BusinessImpact
+		}
+
+		if len(definition.Comments) > 0 {
+			fields["comments"] = definition.Comments
+		}
+
+		var ret = &simpleReturn{}
+		if err = session.post(ctx, session.Config.Address()+qsAssetGroup, fields, ret); err == nil {
+			for _, item := range ret.Response.Items {
+				if item.Key == "ID" {
+					if id, err = strconv.Atoi(item.Value); err != nil {
+						err = fmt.Errorf("error occurred while converting Qualys asset group Id to INT [%s]", err.Error())
+					}
+				}
+			}
+
+			if id == 0 && err == nil {
+				err = fmt.Errorf("the ID of asset group [%s] was not returned from Qualys", definition.Title)
+			}
+		} else {
+			err = fmt.Errorf("error while creating asset group [%s] - %w", definition.Title, err)
+		}
+	} else {
+		err = fmt.Errorf("cannot create an asset group without a title")
+	}
+
+	return id, err
+}
+
+// UpdateAssetGroup applies the update to the asset group. Nothing is sent to Qualys when the update is empty
+func (session *Session) UpdateAssetGroup(id int, update AssetGroupUpdate) (err error) {
+	return session.UpdateAssetGroupContext(session.ctx, id, update)
+}
+
+// UpdateAssetGroupContext is UpdateAssetGroup with a context that aborts the API call when cancelled
+func (session *Session) UpdateAssetGroupContext(ctx context.Context, id int, update AssetGroupUpdate) (err error) {
+	var fields = update.fields()
+	if len(fields) > 0 {
+		fields["action"] = "edit"
+		fields["id"] = strconv.Itoa(id)
+
+		if err = session.post(ctx, session.Config.Address()+qsAssetGroup, fields, &simpleReturn{}); err != nil {
+			err = fmt.Errorf("error while updating asset group [%d] - %w", id, err)
+		}
+	}
+
+	return err
+}
+
+// DeleteAssetGroup deletes the asset group
+func (session *Session) DeleteAssetGroup(id int) (err error) {
+	return session.DeleteAssetGroupContext(session.ctx, id)
+}
+
+// DeleteAssetGroupContext is DeleteAssetGroup with a context that aborts the API call when cancelled
+func (session *Session) DeleteAssetGroupContext(ctx context.Context, id int) (err error) {
+	var fields = make(map[string]string)
+	fields["action"] = "delete"
+	fields["id"] = strconv.Itoa(id)
+
+	if err = session.post(ctx, session.Config.Address()+qsAssetGroup, fields, &simpleReturn{}); err != nil {
+		err = fmt.Errorf("error while deleting asset group [%d] - %w", id, err)
+	}
+
+	return err
+}
+
+// ReconcileAssetGroup brings the asset group of the definition to the state of the definition. The asset group is created
+// when it does not exist, otherwise only the differences between the definition and the asset group are sent to Qualys.
+// The ID of the asset group is returned along with the changes that were applied
+func (session *Session) ReconcileAssetGroup(definition AssetGroupDefinition) (id int, update AssetGroupUpdate, err error) {
+	return session.ReconcileAssetGroupContext(session.ctx, definition)
+}
+
+// ReconcileAssetGroupContext is ReconcileAssetGroup with a context that aborts the API call when cancelled
+func (session *Session) ReconcileAssetGroupContext(ctx context.Context, definition AssetGroupDefinition) (id int, update AssetGroupUpdate, err error) {
+	var current *QSAssetGroup
+
+	if definition.ID > 0 {
+		var ags *QSAGListOutput
+		if ags, err = session.LoadAssetGroupsContext(ctx, []int{definition.ID}); err == nil {
+			if len(ags.Groups) > 0 {
+				current = ags.Groups[0]
+			} else {
+				err = fmt.Errorf("asset group [%d] was not found in Qualys - %w", definition.ID, ErrNotFound)
+			}
+		}
+	} else {
+		current, err = session.LoadAssetGroupByTitleContext(ctx, definition.Title)
+	}
+
+	if err == nil {
+		if current == nil {
+			if id, err = session.CreateAssetGroupContext(ctx, definition); err == nil {
+				session.lstream.Send(log.Infof("Created asset group [%s] with ID [%d]", definition.Title, id))
+			}
+		} else {
+			id = current.ID
+			if update, err = DiffAssetGroup(current, definition); err == nil && !update.Empty() {
+				if err = session.UpdateAssetGroupContext(ctx, id, update); err == nil {
+					session.lstream.Send(log.Infof("Updated asset group [%d] [%v]", id, update.fields()))
+				}
+			}
+		}
+	}
+
+	return id, update, err
+}
+
+// DiffAssetGroup determines the changes required to bring the current asset group to the state of the definition. IPs are
+// compared by the addresses they cover, so an IP range of the definition matches the same addresses listed as individual
+// IPs by Qualys, and only the addresses that differ are added or removed
+func DiffAssetGroup(current *QSAssetGroup, definition AssetGroupDefinition) (update AssetGroupUpdate, err error) {
+	if current == nil {
+		return update, fmt.Errorf("nil asset group")
+	}
+
+	if definition.NetworkID > 0 && definition.NetworkID != current.NetworkID {
+		return update, fmt.Errorf("the network of asset group [%d] cannot be changed from [%d] to [%d]", current.ID, current.NetworkID, definition.NetworkID)
+	}
+
+	if len(definition.Title) > 0 && (current.Title == nil || current.Title.Text != definition.Title) {
+		update.Title = definition.Title
+	}
+
+	if len(definition.BusinessImpact) > 0 && !strings.EqualFold(definition.BusinessImpact, current.BusinessImpact) {
+		update.BusinessImpact = definition.BusinessImpact
+	}
+
+	var comments string
+	if current.Comments != nil {
+		comments = current.Comments.Text
+	}
+
+	if (len(definition.Comments) > 0 || definition.ClearComments) && definition.Comments != comments {
+		update.Comments = &definition.Comments
+	}
+
+	if definition.DefaultApplianceID > 0 && definition.DefaultApplianceID != current.DefaultApplianceID {
+		update.DefaultApplianceID = definition.DefaultApplianceID
+	}
+
+	if update.AddIPs, update.RemoveIPs, err = diffIPs(append(append([]string{}, current.IPs...), current.Ranges...), append(append([]string{}, definition.IPs...), definition.Ranges...)); err == nil {
+		var appliances = make([]int, 0)
+		for _, appliance := range strings.Split(current.Appliances, ",") {
+			if appliance = strings.TrimSpace(appliance); len(appliance) > 0 {
+				var applianceID int
+				if applianceID, err = strconv.Atoi(appliance); err == nil {
+					appliances = append(appliances, applianceID)
+				} else {
+					err = fmt.Errorf("invalid appliance ID [%s] on asset group [%d]", appliance, current.ID)
+					break
+				}
+			}
+		}
+
+		update.AddApplianceIDs, update.RemoveApplianceIDs = diffInts(appliances, definition.ApplianceIDs)
+	}
+
+	if err == nil {
+		update.AddDomains, update.RemoveDomains = diffStrings(current.Domains, definition.Domains)
+	}
+
+	return update, err
+}
+
+// diffIPs returns the IPs that must be added to and removed from the current IPs to reach the desired IPs
+func diffIPs(current []string, desired []string) (add []string, remove []string, err error) {
+	var currentRanges, desiredRanges []ipRange
+	var currentOther, desiredOther []string
+	if currentRanges, currentOther, err = parseIPRanges(current); err == nil {
+		if desiredRanges, desiredOther, err = parseIPRanges(desired); err == nil {
+			add = ipRangeStrings(subtractIPRanges(desiredRanges, currentRanges))
+			remove = ipRangeStrings(subtractIPRanges(currentRanges, desiredRanges))
+
+			var addOther, removeOther = diffStrings(currentOther, desiredOther)
+			add = append(add, addOther...)
+			remove = append(remove, removeOther...)
+		}
+	}
+
+	return add, remove, err
+}
+
+// diffStrings returns the values in desired that are missing from current, and the values in current that are missing
+// from desired. Values are compared without regard to case
+func diffStrings(current []string, desired []string) (add []string, remove []string) {
+	var currentSet = make(map[string]bool)
+	for _, value := range current {
+		currentSet[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+
+	var desiredSet = make(map[string]bool)
+	for _, value := range desired {
+		var key = strings.ToLower(strings.TrimSpace(value))
+		if !desiredSet[key] && !currentSet[key] && len(key) > 0 {
+			add = append(add, strings.TrimSpace(value))
+		}
+		desiredSet[key] = true
+	}
+
+	var removed = make(map[string]bool)
+	for _, value := range current {
+		var key = strings.ToLower(strings.TrimSpace(value))
+		if !desiredSet[key] && !removed[key] && len(key) > 0 {
+			remove = append(remove, strings.TrimSpace(value))
+			removed[key] = true
+		}
+	}
+
+	return add, remove
+}
+
+// diffInts is diffStrings for integers
+func diffInts(current []int, desired []int) (add []int, remove []int) {
+	var addStrings, removeStrings = diffStrings(intArrayToStringArray(current), intArrayToStringArray(desired))
+
+	for _, value := range addStrings {
+		number, _ := strconv.Atoi(value)
+		add = append(add, number)
+	}
+
+	for _, value := range removeStrings {
+		number, _ := strconv.Atoi(value)
+		remove = append(remove, number)
+	}
+
+	return add, remove
+}
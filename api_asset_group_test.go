@@ -0,0 +1,53 @@
+package qualys
+
+import (
+	"testing"
+)
+
+func TestDiffAssetGroupComments(t *testing.T) {
+	var commented = &QSAssetGroup{ID: 1, Comments: &CData{Text: "owned by the network team"}}
+	var uncommented = &QSAssetGroup{ID: 2}
+
+	tests := []struct {
+		name       string
+		current    *QSAssetGroup
+		definition AssetGroupDefinition
+		comments   *string
+	}{
+		{"comments left out", commented, AssetGroupDefinition{}, nil},
+		{"comments unchanged", commented, AssetGroupDefinition{Comments: "owned by the network team"}, nil},
+		{"comments changed", commented, AssetGroupDefinition{Comments: "owned by the server team"}, stringPointer("owned by the server team")},
+		{"comments added", uncommented, AssetGroupDefinition{Comments: "owned by the server team"}, stringPointer("owned by the server team")},
+		{"comments cleared", commented, AssetGroupDefinition{ClearComments: true}, stringPointer("")},
+		{"no comments to clear", uncommented, AssetGroupDefinition{ClearComments: true}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			update, err := DiffAssetGroup(test.current, test.definition)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (update.Comments == nil) != (test.comments == nil) || (update.Comments != nil && *update.Comments != *test.comments) {
+				t.Errorf("expected the comments update [%v], got [%v]", describe(test.comments), describe(update.Comments))
+			}
+
+			if _, sent := update.fields()["set_comments"]; sent != (test.comments != nil) {
+				t.Errorf("expected set_comments to be sent [%v], got %v", test.comments != nil, update.fields())
+			}
+		})
+	}
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func describe(value *string) string {
+	if value == nil {
+		return "unchanged"
+	}
+
+	return "\"" + *value + "\""
+}
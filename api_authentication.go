@@ -0,0 +1,229 @@
+package qualys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/nortonlifelock/domain"
+	"github.com/nortonlifelock/log"
+)
+
+const (
+	// BasicAuthMethod sends the credentials from the AuthInfo of the source config with every request to Qualys. This is the
+	// default when no method is specified in the source config
+	BasicAuthMethod = "basic"
+
+	// SessionAuthMethod logs into the Qualys session API and authenticates following requests using the QualysSession cookie
+	// that is returned. The session is re-established transparently when the cookie expires. The QPS REST APIs (e.g. WAS) do
+	// not support session authentication so basic auth is still used against those endpoints
+	SessionAuthMethod = "session"
+
+	qualysSessionCookie = "QualysSession"
+)
+
+// authenticator holds the credentials parsed from the source config along with the Qualys session cookie when session
+// authentication is used. A single authenticator is shared between all the requests of a Session
+type authenticator struct {
+	method      string
+	credentials domain.BasicAuth
+
+	lock     sync.Mutex
+	cookie   *http.Cookie
+	inflight *loginCall
+}
+
+// loginCall is a login to the Qualys session API that is in flight, which is shared by every request that needs the
+// session cookie until the login completes
+type loginCall struct {
+	done   chan bool
+	cookie *http.Cookie
+	err    error
+}
+
+// newAuthenticator parses the credentials and the authentication method from the AuthInfo of the source config
+// the method is read from the "auth_method" JSON key and can hold either "basic" or "session"
+func newAuthenticator(config domain.SourceConfig) (auth *authenticator, err error) {
+	type parseAuthInfo struct {
+		domain.BasicAuth
+		Method string `json:"auth_method"`
+	}
+
+	parse := &parseAuthInfo{}
+	if err = json.Unmarshal([]byte(config.AuthInfo()), parse); err == nil {
+		auth = &authenticator{
+			method:      strings.ToLower(parse.Method),
+			credentials: parse.BasicAuth,
+		}
+
+		if len(auth.method) == 0 {
+			auth.method = BasicAuthMethod
+		}
+
+		if auth.method != BasicAuthMethod && auth.method != SessionAuthMethod {
+			err = fmt.Errorf("unsupported Qualys authentication method [%s]", parse.Method)
+		}
+	} else {
+		err = fmt.Errorf("error while parsing authentication information - %s", err.Error())
+	}
+
+	return auth, err
+}
+
+// usesCookie determines whether the request should be authenticated with the Qualys session cookie. Only the v2 API
+// endpoints accept the session cookie
+func (auth *authenticator) usesCookie(request *http.Request) bool {
+	return auth.method == SessionAuthMethod && strings.HasPrefix(request.URL.Path, "/api/2.0/")
+}
+
+// expire discards the session cookie so the next request logs in again. The cookie is only discarded if it is the same
+// cookie that the caller used, otherwise another request has already logged in again
+func (auth *authenticator) expire(cookie *http.Cookie) {
+	auth.lock.Lock()
+	defer auth.lock.Unlock()
+
+	if auth.cookie != nil && cookie != nil && auth.cookie.Value == cookie.Value {
+		auth.cookie = nil
+	}
+}
+
+// authorize attaches the credentials to the request. When session authentication is used the cookie that was attached
+// is returned so that it can be expired if Qualys rejects it
+func (session *Session) authorize(request *http.Request) (cookie *http.Cookie, err error) {
+	if session.auth.usesCookie(request) {
+		if cookie, err = session.sessionCookie(request.Context()); err == nil {
+			// the header is cleared first as the same request is re-sent when the session expires
+			request.Header.Del("Cookie")
+			request.AddCookie(cookie)
+		}
+	} else {
+		request.SetBasicAuth(session.auth.credentials.Username, session.auth.credentials.Password)
+	}
+
+	return cookie, err
+}
+
+// sessionCookie returns the Qualys session cookie, logging in when there is no cookie. Requests that need the cookie
+// while a login is in flight wait for that login rather than logging in again, and the lock of the authenticator is not
+// held while the login is in flight. The login runs under the context of the session so that a waiting request whose
+// context is cancelled abandons the login without failing it for the other waiting requests
+func (session *Session) sessionCookie(ctx context.Context) (cookie *http.Cookie, err error) {
+	session.auth.lock.Lock()
+	if cookie = session.auth.cookie; cookie != nil {
+		session.auth.lock.Unlock()
+		return cookie, nil
+	}
+
+	var call = session.auth.inflight
+	if call == nil {
+		call = &loginCall{done: make(chan bool)}
+		session.auth.inflight = call
+		go session.runLogin(call)
+	}
+	session.auth.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-call.done:
+		cookie, err = call.cookie, call.err
+	}
+
+	return cookie, err
+}
+
+// runLogin logs in for the in-flight login call and hands the cookie to its waiting requests. The call is completed even
+// when the login panics so that no request is left waiting
+func (session *Session) runLogin(call *loginCall) {
+	defer handleRoutinePanic(session.lstream)
+
+	call.err = fmt.Errorf("the login to the Qualys session API was interrupted")
+	defer func() {
+		session.auth.lock.Lock()
+		if call.err == nil {
+			session.auth.cookie = call.cookie
+		}
+		session.auth.inflight = nil
+		session.auth.lock.Unlock()
+
+		close(call.done)
+	}()
+
+	call.cookie, call.err = session.login(session.ctx)
+}
+
+// login authenticates against the Qualys session API and returns the session cookie. The credentials are sent in the
+// body of the request rather than the query string so they don't end up in any access logs
+func (session *Session) login(ctx context.Context) (cookie *http.Cookie, err error) {
+	var form = url.Values{}
+	form.Set("action", "login")
+	form.Set("username", session.auth.credentials.Username)
+	form.Set("password", session.auth.credentials.Password)
+
+	var request *http.Request
+	if request, err = http.NewRequestWithContext(ctx, http.MethodPost, session.Config.Address()+qsSession, strings.NewReader(form.Encode())); err == nil {
+		request.Header.Set("X-Requested-With", "Aegis")
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var response *http.Response
+		if response, err = session.getFunnelForEndpoint(request.URL.Host + request.URL.Path).Do(request); response != nil {
+			defer response.Body.Close()
+
+			for _, responseCookie := range response.Cookies() {
+				if responseCookie.Name == qualysSessionCookie {
+					cookie = &http.Cookie{Name: responseCookie.Name, Value: responseCookie.Value}
+					break
+				}
+			}
+
+			if cookie != nil {
+				err = nil
+				session.lstream.Send(log.Debugf("logged into the Qualys session API"))
+			} else {
+				data, _ := ioutil.ReadAll(response.Body)
+				var apiErr = newAPIError(request, response, data)
+				if apiErr.kind() == nil {
+					// Qualys did not explain why no session was opened, so the failure is reported as an authentication failure
+					err = fmt.Errorf("failed to log into Qualys, no session cookie returned [Status: %d] - %w", response.StatusCode, ErrAuthentication)
+				} else {
+					err = apiErr
+				}
+			}
+		} else if err == nil {
+			err = fmt.Errorf("no response from Qualys while logging in")
+		}
+	}
+
+	return cookie, err
+}
+
+// Logout ends the Qualys session that was opened when session authentication is used. It does nothing when basic
+// authentication is used or when no session has been opened yet
+func (session *Session) Logout() (err error) {
+	return session.LogoutContext(session.ctx)
+}
+
+// LogoutContext is Logout with a context that aborts the API call when cancelled
+func (session *Session) LogoutContext(ctx context.Context) (err error) {
+	session.auth.lock.Lock()
+	var loggedIn = session.auth.cookie != nil
+	session.auth.lock.Unlock()
+
+	if loggedIn {
+		var fields = make(map[string]string)
+		fields["action"] = "logout"
+
+		if err = session.post(ctx, session.Config.Address()+qsSession, fields, &simpleReturn{}); err == nil {
+			session.auth.lock.Lock()
+			session.auth.cookie = nil
+			session.auth.lock.Unlock()
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,357 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nortonlifelock/domain"
+	"github.com/pkg/errors"
+)
+
+// sessionServer plays the Qualys session API. Every login opens a new session whose cookie holds the number of the login,
+// and the other endpoints answer with the rejections in order before answering with a successful SIMPLE_RETURN
+type sessionServer struct {
+	// block delays the logins until it is closed when set
+	block chan bool
+
+	// rejections are returned by the appliance endpoint before it answers with a SIMPLE_RETURN
+	rejections []testResponse
+
+	lock     sync.Mutex
+	logins   []url.Values
+	queries  []url.Values
+	cookies  []string
+	logouts  []string
+	sessions int
+}
+
+func (server *sessionServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var cookie string
+	if sessionCookie, err := request.Cookie(qualysSessionCookie); err == nil {
+		cookie = sessionCookie.Value
+	}
+
+	switch request.URL.Path {
+	case qsSession:
+		var query = request.URL.Query()
+		_ = request.ParseForm()
+
+		if request.PostForm.Get("action") == "login" {
+			if server.block != nil {
+				<-server.block
+			}
+
+			server.lock.Lock()
+			server.logins = append(server.logins, query)
+			server.sessions++
+			var value = fmt.Sprintf("%d", server.sessions)
+			server.lock.Unlock()
+
+			http.SetCookie(writer, &http.Cookie{Name: qualysSessionCookie, Value: value})
+			_, _ = writer.Write([]byte(simpleReturnBody(0, "Logged in")))
+		} else if query.Get("action") == "logout" {
+			server.lock.Lock()
+			server.logouts = append(server.logouts, cookie)
+			server.lock.Unlock()
+
+			_, _ = writer.Write([]byte(simpleReturnBody(0, "Logged out")))
+		}
+	default:
+		server.lock.Lock()
+		server.queries = append(server.queries, request.URL.Query())
+		server.cookies = append(server.cookies, cookie)
+
+		var rejection *testResponse
+		if len(server.rejections) > 0 {
+			rejection = &server.rejections[0]
+			server.rejections = server.rejections[1:]
+		}
+		server.lock.Unlock()
+
+		if rejection != nil {
+			writer.WriteHeader(rejection.status)
+			_, _ = writer.Write([]byte(rejection.body))
+		} else {
+			_, _ = writer.Write([]byte(simpleReturnBody(0, "OK")))
+		}
+	}
+}
+
+// sessionRecord holds what the session server received
+type sessionRecord struct {
+	logins  []url.Values
+	queries []url.Values
+	cookies []string
+	logouts []string
+}
+
+func (server *sessionServer) received() sessionRecord {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	return sessionRecord{
+		logins:  append([]url.Values(nil), server.logins...),
+		queries: append([]url.Values(nil), server.queries...),
+		cookies: append([]string(nil), server.cookies...),
+		logouts: append([]string(nil), server.logouts...),
+	}
+}
+
+// newSessionAuthTest returns a session that authenticates against the test server with a session cookie
+func newSessionAuthTest(handler *sessionServer) (session *Session, server *httptest.Server) {
+	server = httptest.NewServer(handler)
+
+	session = newTestSession(server, qsSession, qsAppliance)
+	session.auth = &authenticator{
+		method:      SessionAuthMethod,
+		credentials: domain.BasicAuth{Username: "user", Password: "secret"},
+	}
+
+	return session, server
+}
+
+func callAppliance(ctx context.Context, session *Session) error {
+	return session.post(ctx, session.Config.Address()+qsAppliance, map[string]string{"action": "list"}, &simpleReturn{})
+}
+
+func TestSessionCookieSharedLogin(t *testing.T) {
+	var handler = &sessionServer{block: make(chan bool)}
+	var session, server = newSessionAuthTest(handler)
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	var errs = make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- callAppliance(context.Background(), session)
+		}()
+	}
+
+	// the requests pile up behind the first login before it completes
+	time.Sleep(50 * time.Millisecond)
+	close(handler.block)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var received = handler.received()
+	if len(received.logins) != 1 {
+		t.Fatalf("expected the requests to share a single login, got [%d] logins", len(received.logins))
+	}
+
+	for _, cookie := range received.cookies {
+		if cookie != "1" {
+			t.Fatalf("expected every request to be sent with the cookie of the login, got %v", received.cookies)
+		}
+	}
+}
+
+func TestSessionCookieCancelledWaiter(t *testing.T) {
+	var handler = &sessionServer{block: make(chan bool)}
+	var session, server = newSessionAuthTest(handler)
+	defer server.Close()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var cancelled = make(chan error, 1)
+	go func() {
+		cancelled <- callAppliance(ctx, session)
+	}()
+
+	var waiting = make(chan error, 1)
+	go func() {
+		waiting <- callAppliance(context.Background(), session)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the cancelled request to give up on the login, got [%v]", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the cancelled request to stop waiting on the login")
+	}
+
+	close(handler.block)
+	if err := <-waiting; err != nil {
+		t.Fatalf("expected the login to complete for the request that was still waiting, got [%v]", err)
+	}
+
+	if logins := handler.received().logins; len(logins) != 1 {
+		t.Errorf("expected a single login, got [%d]", len(logins))
+	}
+}
+
+func TestSessionCookieReauthentication(t *testing.T) {
+	tests := []struct {
+		name      string
+		rejection testResponse
+	}{
+		{"401", testResponse{status: http.StatusUnauthorized}},
+		{"session expired", testResponse{status: http.StatusBadRequest, body: simpleReturnBody(qsSessionExpiredCode, "session expired")}},
+		{"session not found", testResponse{status: http.StatusBadRequest, body: simpleReturnBody(qsSessionNotFoundCode, "session not found")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var handler = &sessionServer{rejections: []testResponse{test.rejection}}
+			var session, server = newSessionAuthTest(handler)
+			defer server.Close()
+
+			if err := callAppliance(context.Background(), session); err != nil {
+				t.Fatal(err)
+			}
+
+			var received = handler.received()
+			if len(received.logins) != 2 {
+				t.Fatalf("expected the session to be re-established, got [%d] logins", len(received.logins))
+			}
+
+			if len(received.cookies) != 2 || received.cookies[0] != "1" || received.cookies[1] != "2" {
+				t.Fatalf("expected the request to be resent with the cookie of the new session, got %v", received.cookies)
+			}
+
+			if received.queries[1].Get("action") != "list" {
+				t.Errorf("expected the resent request to keep its parameters, got %v", received.queries[1])
+			}
+		})
+	}
+}
+
+func TestSessionCookieReauthenticatesOnce(t *testing.T) {
+	var handler = &sessionServer{rejections: []testResponse{{status: http.StatusUnauthorized}, {status: http.StatusUnauthorized}}}
+	var session, server = newSessionAuthTest(handler)
+	defer server.Close()
+
+	if err := callAppliance(context.Background(), session); !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("expected the request to fail with an authentication error once the new session is rejected, got [%v]", err)
+	}
+
+	if logins := handler.received().logins; len(logins) != 2 {
+		t.Errorf("expected a single re-login, got [%d] logins", len(logins))
+	}
+}
+
+func TestLogout(t *testing.T) {
+	var handler = &sessionServer{}
+	var session, server = newSessionAuthTest(handler)
+	defer server.Close()
+
+	// no session is open yet, so there is nothing to log out of
+	if err := session.Logout(); err != nil || len(handler.received().logouts) != 0 {
+		t.Fatalf("expected no logout before logging in, got [%v]", err)
+	}
+
+	if err := callAppliance(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := session.Logout(); err != nil {
+		t.Fatal(err)
+	}
+
+	if logouts := handler.received().logouts; len(logouts) != 1 || logouts[0] != "1" {
+		t.Fatalf("expected the session to be logged out with its cookie, got %v", logouts)
+	}
+
+	if session.auth.cookie != nil {
+		t.Fatal("expected the cookie to be cleared by the logout")
+	}
+
+	// the next request opens a new session
+	if err := callAppliance(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+
+	var received = handler.received()
+	if len(received.logins) != 2 || received.cookies[len(received.cookies)-1] != "2" {
+		t.Errorf("expected a new login after the logout, got [%d] logins and the cookies %v", len(received.logins), received.cookies)
+	}
+}
+
+func TestCloseLogsOutWithCancelledContext(t *testing.T) {
+	var handler = &sessionServer{}
+	var session, server = newSessionAuthTest(handler)
+	defer server.Close()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	session.ctx = ctx
+
+	if err := callAppliance(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+
+	// the context of the session is usually cancelled by the time the session is closed
+	cancel()
+	if err := session.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if logouts := handler.received().logouts; len(logouts) != 1 {
+		t.Errorf("expected the session to be logged out, got [%d] logouts", len(logouts))
+	}
+}
+
+func TestLoginSendsCredentialsInBody(t *testing.T) {
+	var credentials url.Values
+	var query url.Values
+	var server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		query = request.URL.Query()
+		_ = request.ParseForm()
+		credentials = request.PostForm
+
+		http.SetCookie(writer, &http.Cookie{Name: qualysSessionCookie, Value: "1"})
+		_, _ = writer.Write([]byte(simpleReturnBody(0, "Logged in")))
+	}))
+	defer server.Close()
+
+	var session = newTestSession(server, qsSession)
+	session.auth = &authenticator{method: SessionAuthMethod, credentials: domain.BasicAuth{Username: "user", Password: "secret"}}
+
+	cookie, err := session.login(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cookie == nil || cookie.Value != "1" {
+		t.Fatalf("expected the session cookie, got [%v]", cookie)
+	}
+
+	if credentials.Get("action") != "login" || credentials.Get("username") != "user" || credentials.Get("password") != "secret" {
+		t.Errorf("expected the credentials in the body, got %v", credentials)
+	}
+
+	if len(query) > 0 {
+		t.Errorf("expected nothing in the query string, got %v", query)
+	}
+}
+
+func TestLoginWithoutCookie(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusUnauthorized)
+		_, _ = writer.Write([]byte(simpleReturnBody(qsBadLoginCode, "Bad Login/Password")))
+	}))
+	defer server.Close()
+
+	var session = newTestSession(server, qsSession)
+	session.auth = &authenticator{method: SessionAuthMethod}
+
+	if _, err := session.login(context.Background()); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("expected a rejected login to match ErrAuthentication, got [%v]", err)
+	}
+}
@@ -1,6 +1,7 @@
 package qualys
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/nortonlifelock/domain"
@@ -101,6 +102,11 @@ const (
 )
 
 func (session *Session) GetCloudAccountEvaluations(accountID string) (evaluations []AccountEvaluationContent, cloudAccountType string, err error) {
+	return session.GetCloudAccountEvaluationsContext(session.ctx, accountID)
+}
+
+// GetCloudAccountEvaluationsContext is GetCloudAccountEvaluations with a context that aborts the API calls when cancelled
+func (session *Session) GetCloudAccountEvaluationsContext(ctx context.Context, accountID string) (evaluations []AccountEvaluationContent, cloudAccountType string, err error) {
 	var possibleAccountTypes = []string{AWS_CLOUD_ACCOUNT, AZURE_CLOUD_ACCOUNT, GOOGLE_CLOUD_ACCOUNT}
 
 	// from looking at the API documentation, I don't see a way to find the cloud account type by using the cloud account ID alone
@@ -108,7 +114,7 @@ func (session *Session) GetCloudAccountEvaluations(accountID string) (evaluation
 	for _, possibleCloudAccountType := range possibleAccountTypes {
 		var possibleEvals []AccountEvaluationContent
 
-		if possibleEvals, err = session.GetCloudAccountEvaluationsWithCloudAccountType(accountID, possibleCloudAccountType); err == nil {
+		if possibleEvals, err = session.GetCloudAccountEvaluationsWithCloudAccountTypeContext(ctx, accountID, possibleCloudAccountType); err == nil {
 			for _, eval := range possibleEvals {
 				if eval.FailedResources > 0 || eval.PassedResources > 0 {
 					evaluations = possibleEvals
@@ -130,6 +136,11 @@ func (session *Session) GetCloudAccountEvaluations(accountID string) (evaluation
 }
 
 func (session *Session) GetCloudAccountEvaluationsWithCloudAccountType(accountID string, cloudAccountType string) (evaluations []AccountEvaluationContent, err error) {
+	return session.GetCloudAccountEvaluationsWithCloudAccountTypeContext(session.ctx, accountID, cloudAccountType)
+}
+
+// GetCloudAccountEvaluationsWithCloudAccountTypeContext is GetCloudAccountEvaluationsWithCloudAccountType with a context that aborts the API calls when cancelled
+func (session *Session) GetCloudAccountEvaluationsWithCloudAccountTypeContext(ctx context.Context, accountID string, cloudAccountType string) (evaluations []AccountEvaluationContent, err error) {
 	evaluations = make([]AccountEvaluationContent, 0)
 	accountEvaluation := &AccountEvaluationResponse{}
 
@@ -138,7 +149,7 @@ func (session *Session) GetCloudAccountEvaluationsWithCloudAccountType(accountID
 
 	for !lastAccPage {
 		var req *http.Request
-		req, err = http.NewRequest(http.MethodGet, session.Config.Address()+fmt.Sprintf("/cloudview-api/rest/v1/%s/evaluations/%s?pageNo=%d&sortOrder=asc", cloudAccountType, accountID, accPage), nil)
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, session.Config.Address()+fmt.Sprintf("/cloudview-api/rest/v1/%s/evaluations/%s?pageNo=%d&sortOrder=asc", cloudAccountType, accountID, accPage), nil)
 		if err == nil {
 			err = session.makeRequest(req, func(resp *http.Response) (err error) {
 				var body []byte
@@ -152,7 +163,7 @@ func (session *Session) GetCloudAccountEvaluationsWithCloudAccountType(accountID
 				return err
 			})
 		} else {
-			err = fmt.Errorf("error while making request - %s", err.Error())
+			err = fmt.Errorf("error while making request - %w", err)
 		}
 
 		if err == nil {
@@ -168,6 +179,11 @@ func (session *Session) GetCloudAccountEvaluationsWithCloudAccountType(accountID
 }
 
 func (session *Session) GetCloudEvaluationFindings(accountID string, content AccountEvaluationContent, policyName string, cloudAccountType string) (findings []domain.Finding, err error) {
+	return session.GetCloudEvaluationFindingsContext(session.ctx, accountID, content, policyName, cloudAccountType)
+}
+
+// GetCloudEvaluationFindingsContext is GetCloudEvaluationFindings with a context that aborts the API calls when cancelled
+func (session *Session) GetCloudEvaluationFindingsContext(ctx context.Context, accountID string, content AccountEvaluationContent, policyName string, cloudAccountType string) (findings []domain.Finding, err error) {
 	findings = make([]domain.Finding, 0)
 	var last bool
 	var page int
@@ -176,7 +192,7 @@ func (session *Session) GetCloudEvaluationFindings(accountID string, content Acc
 		evaluationResult := &EvaluationResult{}
 
 		var req *http.Request
-		req, err = http.NewRequest(http.MethodGet, session.Config.Address()+fmt.Sprintf("/cloudview-api/rest/v1/%s/evaluations/%s/resources/%s?pageNo=%d&sortOrder=asc", cloudAccountType, accountID, content.ControlID, page), nil)
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, session.Config.Address()+fmt.Sprintf("/cloudview-api/rest/v1/%s/evaluations/%s/resources/%s?pageNo=%d&sortOrder=asc", cloudAccountType, accountID, content.ControlID, page), nil)
 		if err == nil {
 			err = session.makeRequest(req, func(resp *http.Response) (err error) {
 				var body []byte
@@ -190,7 +206,11 @@ func (session *Session) GetCloudEvaluationFindings(accountID string, content Acc
 				return err
 			})
 		} else {
-			err = fmt.Errorf("error while making request - %s", err.Error())
+			err = fmt.Errorf("error while making request - %w", err)
+		}
+
+		if err != nil {
+			break
 		}
 
 		last = evaluationResult.Last
@@ -4,6 +4,7 @@ const (
 	qsVMScan              = "/api/2.0/fo/scan/"
 	qsScheduledScan       = "/api/2.0/fo/schedule/scan/"
 	qsAssetVMHost         = "/api/2.0/fo/asset/host/vm/detection/"
+	qsAssetHost           = "/api/2.0/fo/asset/host/"
 	qsVulnerabilities     = "/api/2.0/fo/knowledge_base/vuln/"
 	qsSearchList          = "/api/2.0/fo/qid/search_list/static/"
 	qsAssetGroup          = "/api/2.0/fo/asset/group/"
@@ -11,4 +12,22 @@ const (
 	qsOptionProfile       = "/api/2.0/fo/subscription/option_profile/"
 	qsOptionProfileDelete = "/api/2.0/fo/subscription/option_profile/vm/"
 	qsHostStatusFromScan  = "/api/2.0/fo/scan/summary/"
+	qsSession             = "/api/2.0/fo/session/"
+	qsAssetIP             = "/api/2.0/fo/asset/ip/"
+	qsAssetExcludedIP     = "/api/2.0/fo/asset/excluded_ip/"
+
+	qpsHostAssetSearch = "/qps/rest/2.0/search/am/hostasset"
+	qpsHostAssetGet    = "/qps/rest/2.0/get/am/hostasset/<id>"
+	qpsHostAssetUpdate = "/qps/rest/2.0/update/am/hostasset"
+	qpsTagSearch       = "/qps/rest/2.0/search/am/tag"
+	qpsTagCreate       = "/qps/rest/2.0/create/am/tag"
+	qpsTagUpdate       = "/qps/rest/2.0/update/am/tag/<id>"
+	qpsTagDelete       = "/qps/rest/2.0/delete/am/tag/<id>"
+)
+
+// SIMPLE_RETURN codes returned by Qualys alongside a 409 when a request is rejected by the API limits
+const (
+	qsRequestLockedCode    = 1920
+	qsRateLimitCode        = 1960
+	qsConcurrencyLimitCode = 1965
 )
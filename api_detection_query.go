@@ -0,0 +1,231 @@
+package qualys
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values accepted by the DetectionQuery fields that hold a fixed set of values
+const (
+	// DetectionStatusNew and the following statuses are the values of the Status filter
+	DetectionStatusNew      = "New"
+	DetectionStatusActive   = "Active"
+	DetectionStatusReOpened = "Re-Opened"
+	DetectionStatusFixed    = "Fixed"
+
+	// TagSelectorAny and TagSelectorAll are the values of the TagIncludeSelector and TagExcludeSelector
+	TagSelectorAny = "any"
+	TagSelectorAll = "all"
+
+	// HostMetadataAll and the following values are the cloud providers of the HostMetadata
+	HostMetadataAll    = "all"
+	HostMetadataEC2    = "ec2"
+	HostMetadataAzure  = "azure"
+	HostMetadataGoogle = "google"
+
+	// NoTruncationLimit returns every host in a single response rather than paging through the hosts
+	NoTruncationLimit = -1
+)
+
+// qualysDateFormat is the format of the date parameters accepted by the Qualys v2 API
+const qualysDateFormat = "2006-01-02T15:04:05Z"
+
+// DetectionQuery holds the parameters of a Host List Detection API call. Fields left at their zero value are not sent
+// to Qualys so that the API defaults apply. The query is validated before it is sent so that invalid parameters are
+// reported without spending an API call
+type DetectionQuery struct {
+	// HostFilter selects the hosts whose detections are loaded
+	HostFilter
+
+	// Detections are filtered by QID, severity, search list and status
+	QIDs                 []int
+	Severities           []int
+	IncludeSearchListIDs []int
+	ExcludeSearchListIDs []int
+	Status               []string
+
+	// Detections are filtered by when they were last updated and when the host was last processed
+	DetectionUpdatedSince  *time.Time
+	DetectionUpdatedBefore *time.Time
+	VMProcessedAfter       *time.Time
+	VMProcessedBefore      *time.Time
+
+	// KernelFilter sets the arf_kernel_filter flag. Can hold values [0,4]
+	// 0 vulnerabilities are not filtered based on kernel activity
+	// 1 exclude kernel related vulnerabilities that are not exploitable (found on non-running kernels)
+	// 2 only include kernel related vulnerabilities that are not exploitable (found on non-running kernels)
+	// 3 only include kernel related vulnerabilities that are exploitable (found on running kernels)
+	// 4 only include kernel related vulnerabilities
+	KernelFilter int
+
+	// The output options determine which information is returned with the detections. ShowResults is a pointer as the
+	// results are returned by Qualys unless they are explicitly excluded
+	ShowResults      *bool
+	ShowIGs          bool
+	ShowReopenedInfo bool
+
+	// HostMetadataFields limits the cloud metadata requested through the HostMetadata of the HostFilter
+	HostMetadataFields []string
+
+	// Partitions splits the hosts into ranges of host IDs which are loaded concurrently by GetDetections. The hosts are
+	// loaded through a single range when it is not set
+	Partitions int
+}
+
+// NewHostDetectionQuery returns the query used by GetHostDetections, which loads the New, Active, Re-Opened and Fixed
+// detections of the hosts in the asset groups 2500 hosts at a time along with the tags and cloud metadata of the hosts
+func NewHostDetectionQuery(groups []string, kernelFilterFlag int) (query *DetectionQuery) {
+	return &DetectionQuery{
+		HostFilter: HostFilter{
+			AssetGroupIDs:   groups,
+			ShowTags:        true,
+			HostMetadata:    HostMetadataAll,
+			TruncationLimit: 2500,
+		},
+		Status:           defaultDetectionStatus(),
+		KernelFilter:     kernelFilterFlag,
+		ShowReopenedInfo: true,
+	}
+}
+
+// NewTagDetectionQuery returns the query used by GetTagDetections, which loads the New, Active, Re-Opened and Fixed
+// detections of the hosts holding all of the tags along with the tags and cloud metadata of the hosts
+func NewTagDetectionQuery(tags []string, kernelFilterFlag int) (query *DetectionQuery) {
+	return &DetectionQuery{
+		HostFilter: HostFilter{
+			TagSetInclude:      tags,
+			TagIncludeSelector: TagSelectorAll,
+			ShowTags:           true,
+			HostMetadata:       HostMetadataAll,
+			TruncationLimit:    NoTruncationLimit,
+		},
+		Status:           defaultDetectionStatus(),
+		KernelFilter:     kernelFilterFlag,
+		ShowReopenedInfo: true,
+	}
+}
+
+// NewHostSpecificDetectionQuery returns the query used by GetHostSpecificDetections, which loads the New, Active,
+// Re-Opened and Fixed detections of the IPs within the asset groups 2500 hosts at a time along with the tags and cloud
+// metadata of the hosts
+func NewHostSpecificDetectionQuery(ips []string, groups []string, kernelFilterFlag int) (query *DetectionQuery) {
+	return &DetectionQuery{
+		HostFilter: HostFilter{
+			IPs:             ips,
+			AssetGroupIDs:   groups,
+			ShowTags:        true,
+			HostMetadata:    HostMetadataAll,
+			TruncationLimit: 2500,
+		},
+		Status:           defaultDetectionStatus(),
+		KernelFilter:     kernelFilterFlag,
+		ShowReopenedInfo: true,
+	}
+}
+
+// If the status parameter is not passed to the API, by default, the output contains detections with New, Active or
+// Re-Opened <STATUS> only
+func defaultDetectionStatus() []string {
+	return []string{DetectionStatusNew, DetectionStatusActive, DetectionStatusReOpened, DetectionStatusFixed}
+}
+
+// Validate checks the query for parameters that would be rejected by Qualys
+func (query *DetectionQuery) Validate() (err error) {
+	if query == nil {
+		return fmt.Errorf("nil detection query")
+	}
+
+	if err = query.HostFilter.validate("detection query"); err == nil {
+		switch {
+		case query.KernelFilter < 0 || query.KernelFilter > 4:
+			err = fmt.Errorf("invalid kernel filter [%d], must be within [0,4]", query.KernelFilter)
+		case query.Partitions < 0:
+			err = fmt.Errorf("invalid partition count [%d]", query.Partitions)
+		case len(query.HostMetadataFields) > 0 && len(query.HostMetadata) == 0:
+			err = fmt.Errorf("host metadata fields require the host metadata to be set")
+		case query.DetectionUpdatedSince != nil && query.DetectionUpdatedBefore != nil && query.DetectionUpdatedSince.After(*query.DetectionUpdatedBefore):
+			err = fmt.Errorf("detection updated since [%s] is after detection updated before [%s]", query.DetectionUpdatedSince, query.DetectionUpdatedBefore)
+		case query.VMProcessedAfter != nil && query.VMProcessedBefore != nil && query.VMProcessedAfter.After(*query.VMProcessedBefore):
+			err = fmt.Errorf("vm processed after [%s] is after vm processed before [%s]", query.VMProcessedAfter, query.VMProcessedBefore)
+		}
+	}
+
+	for _, severity := range query.Severities {
+		if err == nil && (severity < 1 || severity > 5) {
+			err = fmt.Errorf("invalid severity [%d], must be within [1,5]", severity)
+		}
+	}
+
+	for _, status := range query.Status {
+		if err == nil {
+			switch status {
+			case DetectionStatusNew, DetectionStatusActive, DetectionStatusReOpened, DetectionStatusFixed:
+			default:
+				err = fmt.Errorf("invalid detection status [%s]", status)
+			}
+		}
+	}
+
+	return err
+}
+
+// fields validates the query and converts it to the parameters of the Host List Detection API call
+func (query *DetectionQuery) fields() (fields map[string]string, err error) {
+	if err = query.Validate(); err == nil {
+		fields = make(map[string]string)
+		fields["action"] = "list"
+
+		query.HostFilter.setFields(fields)
+
+		setList(fields, "qids", intArrayToStringArray(query.QIDs))
+		setList(fields, "severities", intArrayToStringArray(query.Severities))
+		setList(fields, "include_search_list_ids", intArrayToStringArray(query.IncludeSearchListIDs))
+		setList(fields, "exclude_search_list_ids", intArrayToStringArray(query.ExcludeSearchListIDs))
+		setList(fields, "status", query.Status)
+
+		setDate(fields, "detection_updated_since", query.DetectionUpdatedSince)
+		setDate(fields, "detection_updated_before", query.DetectionUpdatedBefore)
+		setDate(fields, "vm_processed_after", query.VMProcessedAfter)
+		setDate(fields, "vm_processed_before", query.VMProcessedBefore)
+
+		fields["arf_kernel_filter"] = strconv.Itoa(query.KernelFilter)
+
+		if query.ShowResults != nil && !*query.ShowResults {
+			fields["show_results"] = "0"
+		}
+
+		if query.ShowIGs {
+			fields["show_igs"] = "1"
+		}
+
+		if query.ShowReopenedInfo {
+			fields["show_reopened_info"] = "1" // Show the additional information related to vulnerabilities that have been Reopened in Qualys
+		}
+
+		if len(query.HostMetadata) > 0 {
+			setList(fields, "host_metadata_fields", query.HostMetadataFields)
+		}
+	}
+
+	return fields, err
+}
+
+func setList(fields map[string]string, key string, values []string) {
+	if len(values) > 0 {
+		fields[key] = strings.Join(values, ",")
+	}
+}
+
+func setInt(fields map[string]string, key string, value int) {
+	if value > 0 {
+		fields[key] = strconv.Itoa(value)
+	}
+}
+
+func setDate(fields map[string]string, key string, value *time.Time) {
+	if value != nil && !value.IsZero() {
+		fields[key] = value.UTC().Format(qualysDateFormat)
+	}
+}
@@ -0,0 +1,164 @@
+package qualys
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectionQueryValidate(t *testing.T) {
+	var earlier = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var later = earlier.Add(24 * time.Hour)
+
+	tests := []struct {
+		name  string
+		query *DetectionQuery
+		err   string
+	}{
+		{"nil query", nil, "nil detection query"},
+		{"empty query", &DetectionQuery{}, ""},
+		{"host detection query", NewHostDetectionQuery([]string{"1", "2"}, 1), ""},
+		{"tag detection query", NewTagDetectionQuery([]string{"Production"}, 1), ""},
+		{"tags with asset groups", &DetectionQuery{HostFilter: HostFilter{TagSetInclude: []string{"1"}, AssetGroupIDs: []string{"2"}}}, "asset tags along with asset groups"},
+		{"tags with IPs", &DetectionQuery{HostFilter: HostFilter{TagSetExclude: []string{"1"}, IPs: []string{"10.0.0.1"}}}, "asset tags along with asset groups"},
+		{"tag names and IDs mixed", &DetectionQuery{HostFilter: HostFilter{TagSetInclude: []string{"1"}, TagSetExclude: []string{"Production"}}}, "mix tag names and tag IDs"},
+		{"invalid tag selector", &DetectionQuery{HostFilter: HostFilter{TagIncludeSelector: "some"}}, "invalid tag selector"},
+		{"tag selector case insensitive", &DetectionQuery{HostFilter: HostFilter{TagIncludeSelector: "ALL"}}, ""},
+		{"negative host ID", &DetectionQuery{HostFilter: HostFilter{IDMin: -1}}, "invalid host ID range"},
+		{"inverted host ID range", &DetectionQuery{HostFilter: HostFilter{IDMin: 10, IDMax: 5}}, "invalid host ID range"},
+		{"open host ID range", &DetectionQuery{HostFilter: HostFilter{IDMin: 10}}, ""},
+		{"kernel filter out of range", &DetectionQuery{KernelFilter: 5}, "invalid kernel filter"},
+		{"truncation limit below no limit", &DetectionQuery{HostFilter: HostFilter{TruncationLimit: -2}}, "invalid truncation limit"},
+		{"negative partitions", &DetectionQuery{Partitions: -1}, "invalid partition count"},
+		{"metadata fields without metadata", &DetectionQuery{HostMetadataFields: []string{"compute/vmId"}}, "require the host metadata"},
+		{"invalid metadata", &DetectionQuery{HostFilter: HostFilter{HostMetadata: "aws"}}, "invalid host metadata"},
+		{"metadata case insensitive", &DetectionQuery{HostFilter: HostFilter{HostMetadata: "EC2"}}, ""},
+		{"inverted detection dates", &DetectionQuery{DetectionUpdatedSince: &later, DetectionUpdatedBefore: &earlier}, "detection updated since"},
+		{"inverted processed dates", &DetectionQuery{VMProcessedAfter: &later, VMProcessedBefore: &earlier}, "vm processed after"},
+		{"ordered dates", &DetectionQuery{DetectionUpdatedSince: &earlier, DetectionUpdatedBefore: &later}, ""},
+		{"invalid severity", &DetectionQuery{Severities: []int{3, 6}}, "invalid severity [6]"},
+		{"invalid status", &DetectionQuery{Status: []string{DetectionStatusNew, "Closed"}}, "invalid detection status [Closed]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err = test.query.Validate()
+			if len(test.err) == 0 && err != nil {
+				t.Fatalf("expected no error, got [%v]", err)
+			} else if len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("expected an error containing [%s], got [%v]", test.err, err)
+			}
+		})
+	}
+}
+
+func TestDetectionQueryFields(t *testing.T) {
+	var since = time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("EST", -5*60*60))
+	var hideResults = false
+
+	tests := []struct {
+		name     string
+		query    *DetectionQuery
+		expected map[string]string
+	}{
+		{
+			"defaults",
+			&DetectionQuery{},
+			map[string]string{"action": "list", "arf_kernel_filter": "0"},
+		},
+		{
+			"hosts and detections",
+			&DetectionQuery{
+				HostFilter: HostFilter{
+					AssetGroupIDs:   []string{"1", "2"},
+					HostIDs:         []int{3, 4},
+					IDMin:           5,
+					ShowTags:        true,
+					HostMetadata:    "EC2",
+					TruncationLimit: NoTruncationLimit,
+				},
+				QIDs:                  []int{6},
+				Status:                []string{DetectionStatusActive},
+				DetectionUpdatedSince: &since,
+				KernelFilter:          1,
+				ShowResults:           &hideResults,
+				ShowIGs:               true,
+				ShowReopenedInfo:      true,
+			},
+			map[string]string{
+				"action":                  "list",
+				"ag_ids":                  "1,2",
+				"ids":                     "3,4",
+				"id_min":                  "5",
+				"qids":                    "6",
+				"status":                  "Active",
+				"detection_updated_since": "2020-01-02T08:04:05Z",
+				"arf_kernel_filter":       "1",
+				"show_results":            "0",
+				"show_igs":                "1",
+				"show_reopened_info":      "1",
+				"show_tags":               "1",
+				"host_metadata":           "ec2",
+				"truncation_limit":        "0",
+			},
+		},
+		{
+			"tags",
+			&DetectionQuery{HostFilter: HostFilter{TagSetInclude: []string{"Production"}, TagIncludeSelector: "ALL", TruncationLimit: 100}},
+			map[string]string{
+				"action":               "list",
+				"use_tags":             "1",
+				"tag_set_by":           "name",
+				"tag_set_include":      "Production",
+				"tag_include_selector": "all",
+				"arf_kernel_filter":    "0",
+				"truncation_limit":     "100",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, err := test.query.fields()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fields) != len(test.expected) {
+				t.Errorf("expected [%d] fields, got [%d] %v", len(test.expected), len(fields), fields)
+			}
+
+			for key, value := range test.expected {
+				if fields[key] != value {
+					t.Errorf("expected [%s] to be [%s], got [%s]", key, value, fields[key])
+				}
+			}
+		})
+	}
+}
+
+func TestDetectionQueryConstructorFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *DetectionQuery
+	}{
+		{"host detections", NewHostDetectionQuery([]string{"1"}, 0)},
+		{"tag detections", NewTagDetectionQuery([]string{"Production"}, 0)},
+		{"host specific detections", NewHostSpecificDetectionQuery([]string{"10.0.0.1"}, []string{"1"}, 0)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, err := test.query.fields()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for key, value := range map[string]string{"show_reopened_info": "1", "show_tags": "1", "host_metadata": HostMetadataAll, "status": "New,Active,Re-Opened,Fixed"} {
+				if fields[key] != value {
+					t.Errorf("expected [%s] to be [%s], got [%s]", key, value, fields[key])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package qualys
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The sentinel errors categorize the errors returned by the Qualys API. An APIError matches the sentinel of its category
+// through errors.Is so that callers can branch on the kind of error without parsing the error message
+var (
+	// ErrAuthentication is matched when Qualys rejects the credentials or the session cookie
+	ErrAuthentication = errors.New("qualys authentication failed")
+
+	// ErrRateLimited is matched when the request was rejected by the Qualys rate limit
+	ErrRateLimited = errors.New("qualys rate limit exceeded")
+
+	// ErrConcurrencyLimited is matched when the request was rejected because too many API calls were running at once
+	ErrConcurrencyLimited = errors.New("qualys concurrency limit exceeded")
+
+	// ErrScanLimitReached is matched when a scan could not be launched because the subscription is already running the
+	// maximum number of concurrent scans
+	ErrScanLimitReached = errors.New("qualys concurrent scan limit reached")
+
+	// ErrNotFound is matched when the requested object does not exist in Qualys
+	ErrNotFound = errors.New("qualys object not found")
+
+	// ErrInvalidParameter is matched when Qualys rejects the parameters of the request
+	ErrInvalidParameter = errors.New("qualys invalid parameter")
+)
+
+// SIMPLE_RETURN codes used to categorize the errors returned by the v2 API
+const (
+	qsUnrecognizedParameterCode = 1901
+	qsMissingParameterCode      = 1903
+	qsInvalidParameterCode      = 1904
+	qsInvalidParameterValueCode = 1905
+	qsParameterTooLongCode      = 1908
+	qsBadLoginCode              = 2000
+	qsInactiveAccountCode       = 2002
+	qsRegistrationCode          = 2003
+	qsSessionExpiredCode        = 2011
+	qsSessionNotFoundCode       = 2012
+)
+
+// responseCode values of a QPS ServiceResponse
+const (
+	qpsSuccess                = "SUCCESS"
+	qpsInvalidCredentials     = "INVALID_CREDENTIALS"
+	qpsUnauthorizedAccess     = "UNAUTHORIZED_ACCESS"
+	qpsInsufficientPrivileges = "INSUFFICIENT_PRIVILEGES"
+	qpsNotFound               = "NOT_FOUND"
+	qpsInvalidRequest         = "INVALID_REQUEST"
+	qpsInvalidParameter       = "INVALID_PARAMETER"
+	qpsTooManyRequests        = "TOO_MANY_REQUESTS"
+)
+
+// requestIDHeader is the header Qualys uses to identify a request when it is reported to support
+const requestIDHeader = "X-Request-Id"
+
+// the scan limit is only reported through the TEXT of the SIMPLE_RETURN, so it is matched here once instead of by callers
+var scanLimitMessage = regexp.MustCompile(`(?s)You are allowed to run \d+ concurrent scans.*This limit has already been reached`)
+
+// APIError is returned when the Qualys API responds with an error, either through the HTTP status or through the
+// SIMPLE_RETURN (v2 API) or ServiceResponse (QPS REST API) body of the response
+type APIError struct {
+	// Endpoint is the URL of the request that failed
+	Endpoint string
+
+	// Status is the HTTP status of the response
+	Status int
+
+	// Code is the CODE of the SIMPLE_RETURN returned by the v2 API
+	Code int
+
+	// ResponseCode is the responseCode of the ServiceResponse returned by the QPS REST API
+	ResponseCode string
+
+	// Message is the TEXT of the SIMPLE_RETURN or the errorMessage of the ServiceResponse
+	Message string
+
+	// RequestID identifies the request for Qualys support when the header was returned
+	RequestID string
+}
+
+func (err *APIError) Error() string {
+	var code = fmt.Sprintf("%d", err.Code)
+	if len(err.ResponseCode) > 0 {
+		code = err.ResponseCode
+	}
+
+	var message = fmt.Sprintf("Qualys API [%s] Error Returned [Status: %d | Code: %s | Error: %s]", err.Endpoint, err.Status, code, err.Message)
+	if len(err.RequestID) > 0 {
+		message = fmt.Sprintf("%s [Request: %s]", message, err.RequestID)
+	}
+
+	return message
+}
+
+// Is matches the error against the sentinel error of its category
+func (err *APIError) Is(target error) bool {
+	return target != nil && target == err.kind()
+}
+
+// kind returns the sentinel error that categorizes the error, or nil when the error is not categorized
+func (err *APIError) kind() (kind error) {
+	if scanLimitMessage.MatchString(err.Message) {
+		return ErrScanLimitReached
+	}
+
+	switch err.Code {
+	case qsBadLoginCode, qsInactiveAccountCode, qsRegistrationCode, qsSessionExpiredCode, qsSessionNotFoundCode:
+		return ErrAuthentication
+	case qsRateLimitCode:
+		return ErrRateLimited
+	case qsConcurrencyLimitCode, qsRequestLockedCode:
+		return ErrConcurrencyLimited
+	case qsUnrecognizedParameterCode, qsMissingParameterCode, qsInvalidParameterCode, qsInvalidParameterValueCode, qsParameterTooLongCode:
+		return ErrInvalidParameter
+	}
+
+	switch strings.ToUpper(err.ResponseCode) {
+	case qpsInvalidCredentials, qpsUnauthorizedAccess, qpsInsufficientPrivileges:
+		return ErrAuthentication
+	case qpsNotFound:
+		return ErrNotFound
+	case qpsInvalidRequest, qpsInvalidParameter:
+		return ErrInvalidParameter
+	case qpsTooManyRequests:
+		return ErrRateLimited
+	}
+
+	switch err.Status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = ErrAuthentication
+	case http.StatusNotFound:
+		kind = ErrNotFound
+	case http.StatusBadRequest:
+		kind = ErrInvalidParameter
+	case http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	}
+
+	return kind
+}
+
+// serviceResponse holds the fields shared by every QPS REST API response that describe whether the call succeeded
+type serviceResponse struct {
+	XMLName      xml.Name `xml:"ServiceResponse"`
+	ResponseCode string   `xml:"responseCode"`
+	ErrorMessage string   `xml:"responseErrorDetails>errorMessage"`
+}
+
+// serviceResponseJSON is serviceResponse for the QPS REST API calls that are made with JSON
+type serviceResponseJSON struct {
+	ServiceResponse struct {
+		ResponseCode         string `json:"responseCode"`
+		ResponseErrorDetails struct {
+			ErrorMessage string `json:"errorMessage"`
+		} `json:"responseErrorDetails"`
+	} `json:"ServiceResponse"`
+}
+
+// newAPIError builds an APIError from the body of a response. Both the SIMPLE_RETURN of the v2 API and the
+// ServiceResponse of the QPS REST API, in XML or JSON, are recognized
+func newAPIError(request *http.Request, response *http.Response, data []byte) (err *APIError) {
+	err = &APIError{
+		Endpoint: request.URL.String(),
+	}
+
+	if response != nil {
+		err.Status = response.StatusCode
+		err.RequestID = response.Header.Get(requestIDHeader)
+	}
+
+	var ret simpleReturn
+	var qps serviceResponse
+	var qpsJSON serviceResponseJSON
+	if xml.Unmarshal(data, &ret) == nil {
+		err.Code = ret.Response.Code
+		err.Message = ret.Response.Message
+	} else if xml.Unmarshal(data, &qps) == nil {
+		err.ResponseCode = qps.ResponseCode
+		err.Message = qps.ErrorMessage
+	} else if json.Unmarshal(data, &qpsJSON) == nil && len(qpsJSON.ServiceResponse.ResponseCode) > 0 {
+		err.ResponseCode = qpsJSON.ServiceResponse.ResponseCode
+		err.Message = qpsJSON.ServiceResponse.ResponseErrorDetails.ErrorMessage
+	} else {
+		err.Message = strings.TrimSpace(string(data))
+	}
+
+	return err
+}
+
+// serviceResponseError returns an APIError when the data holds a QPS ServiceResponse with a responseCode other than
+// SUCCESS. Otherwise a nil error is returned
+func serviceResponseError(request *http.Request, response *http.Response, data []byte) (err error) {
+	// only the start of the body is checked before parsing as the v2 API responses can be very large
+	var head = data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+
+	if bytes.Contains(head, []byte("<ServiceResponse")) {
+		var qps serviceResponse
+		if xml.Unmarshal(data, &qps) == nil && len(qps.ResponseCode) > 0 && strings.ToUpper(qps.ResponseCode) != qpsSuccess {
+			err = newAPIError(request, response, data)
+		}
+	}
+
+	return err
+}
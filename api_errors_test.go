@@ -0,0 +1,140 @@
+package qualys
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestScanLimitMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		match   bool
+	}{
+		{"You are allowed to run 5 concurrent scans. This limit has already been reached.", true},
+		{"You are allowed to run 10 concurrent scans.\nThis limit has already been reached. Please try again later.", true},
+		{"You are allowed to run 5 concurrent scans.", false},
+		{"This limit has already been reached.", false},
+		{"Scan launched", false},
+	}
+
+	for _, test := range tests {
+		if match := scanLimitMessage.MatchString(test.message); match != test.match {
+			t.Errorf("expected a match of [%v] for [%s], got [%v]", test.match, test.message, match)
+		}
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *APIError
+		kind error
+	}{
+		{"bad login", &APIError{Status: 401, Code: qsBadLoginCode}, ErrAuthentication},
+		{"session expired", &APIError{Status: 200, Code: qsSessionExpiredCode}, ErrAuthentication},
+		{"rate limited", &APIError{Status: 409, Code: qsRateLimitCode}, ErrRateLimited},
+		{"concurrency limited", &APIError{Status: 409, Code: qsConcurrencyLimitCode}, ErrConcurrencyLimited},
+		{"request locked", &APIError{Status: 409, Code: qsRequestLockedCode}, ErrConcurrencyLimited},
+		{"missing parameter", &APIError{Status: 400, Code: qsMissingParameterCode}, ErrInvalidParameter},
+		{"invalid parameter value", &APIError{Status: 200, Code: qsInvalidParameterValueCode}, ErrInvalidParameter},
+		{"scan limit", &APIError{Status: 200, Code: 999, Message: "You are allowed to run 2 concurrent scans. This limit has already been reached."}, ErrScanLimitReached},
+		{"qps invalid credentials", &APIError{Status: 200, ResponseCode: qpsInvalidCredentials}, ErrAuthentication},
+		{"qps not found", &APIError{Status: 200, ResponseCode: "not_found"}, ErrNotFound},
+		{"qps invalid request", &APIError{Status: 200, ResponseCode: qpsInvalidRequest}, ErrInvalidParameter},
+		{"qps too many requests", &APIError{Status: 200, ResponseCode: qpsTooManyRequests}, ErrRateLimited},
+		{"status unauthorized", &APIError{Status: 401}, ErrAuthentication},
+		{"status forbidden", &APIError{Status: 403}, ErrAuthentication},
+		{"status not found", &APIError{Status: 404}, ErrNotFound},
+		{"status bad request", &APIError{Status: 400}, ErrInvalidParameter},
+		{"status too many requests", &APIError{Status: 429}, ErrRateLimited},
+		{"code before status", &APIError{Status: 400, Code: qsBadLoginCode}, ErrAuthentication},
+		{"uncategorized", &APIError{Status: 500, Code: 999}, nil},
+	}
+
+	var sentinels = []error{ErrAuthentication, ErrRateLimited, ErrConcurrencyLimited, ErrScanLimitReached, ErrNotFound, ErrInvalidParameter}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var wrapped = fmt.Errorf("error while calling Qualys - %w", test.err)
+
+			for _, sentinel := range sentinels {
+				if is := errors.Is(wrapped, sentinel); is != (sentinel == test.kind) {
+					t.Errorf("expected errors.Is against [%v] to be [%v]", sentinel, sentinel == test.kind)
+				}
+			}
+
+			var apiErr *APIError
+			if !errors.As(wrapped, &apiErr) || apiErr != test.err {
+				t.Error("expected errors.As to return the APIError")
+			}
+		})
+	}
+}
+
+func TestRateLimitErrorIs(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   *RateLimitError
+		kind  error
+		other error
+	}{
+		{"rate limit code", &RateLimitError{APIError: APIError{Status: 409, Code: qsRateLimitCode}}, ErrRateLimited, ErrConcurrencyLimited},
+		{"concurrency limit code", &RateLimitError{APIError: APIError{Status: 409, Code: qsConcurrencyLimitCode}}, ErrConcurrencyLimited, ErrRateLimited},
+		{"concurrency limit headers", &RateLimitError{APIError: APIError{Status: 409}, Rates: Rates{CLimit: 2, CRunning: 2}}, ErrConcurrencyLimited, ErrRateLimited},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !errors.Is(test.err, test.kind) {
+				t.Errorf("expected the error to match [%v]", test.kind)
+			}
+
+			if errors.Is(test.err, test.other) {
+				t.Errorf("expected the error not to match [%v]", test.other)
+			}
+
+			var apiErr *APIError
+			if !errors.As(test.err, &apiErr) || apiErr.Status != test.err.Status {
+				t.Error("expected the error to unwrap to its APIError")
+			}
+		})
+	}
+}
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		code         int
+		responseCode string
+		message      string
+		kind         error
+	}{
+		{"simple return", 400, simpleReturnBody(qsInvalidParameterCode, "bad parameter"), qsInvalidParameterCode, "", "bad parameter", ErrInvalidParameter},
+		{"service response xml", 200, "<ServiceResponse><responseCode>NOT_FOUND</responseCode><responseErrorDetails><errorMessage>missing</errorMessage></responseErrorDetails></ServiceResponse>", 0, qpsNotFound, "missing", ErrNotFound},
+		{"service response json", 200, `{"ServiceResponse":{"responseCode":"INVALID_CREDENTIALS","responseErrorDetails":{"errorMessage":"denied"}}}`, 0, qpsInvalidCredentials, "denied", ErrAuthentication},
+		{"plain text", 503, " unavailable \n", 0, "", "unavailable", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "https://qualysapi.qualys.com"+qsVulnerabilities, nil)
+			response := &http.Response{StatusCode: test.status, Header: http.Header{}}
+			response.Header.Set(requestIDHeader, "request-1")
+
+			var err = newAPIError(request, response, []byte(test.body))
+			if err.Status != test.status || err.Code != test.code || err.ResponseCode != test.responseCode || err.Message != test.message || err.RequestID != "request-1" {
+				t.Errorf("unexpected error fields %+v", *err)
+			}
+
+			if err.kind() != test.kind {
+				t.Errorf("expected the error to be categorized as [%v], got [%v]", test.kind, err.kind())
+			}
+		})
+	}
+}
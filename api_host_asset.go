@@ -0,0 +1,93 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nortonlifelock/log"
+)
+
+// hostAssetPageSize is the number of host assets requested per page of a HostAsset search
+const hostAssetPageSize = 100
+
+// SearchHostAssets loads the host assets matched by the criteria from the Qualys Asset Management API, following the pages
+// of the response until every host asset has been loaded. The criteria are combined with AND
+func (session *Session) SearchHostAssets(criteria ...SearchCriteria) (assets []HostAsset, err error) {
+	return session.SearchHostAssetsContext(session.ctx, criteria...)
+}
+
+// SearchHostAssetsContext is SearchHostAssets with a context that stops the paging of the host assets when cancelled
+func (session *Session) SearchHostAssetsContext(ctx context.Context, criteria ...SearchCriteria) (assets []HostAsset, err error) {
+	assets = make([]HostAsset, 0)
+
+	var request = NewQPSRequest(criteria...)
+	request.Preferences.LimitResults = hostAssetPageSize
+
+	var pager = session.NewQPSPager(session.Config.Address()+qpsHostAssetSearch, request, QPSXML).PageByID("id")
+	for {
+		var page hostAssetPage
+		if !pager.Next(ctx, &page) {
+			break
+		}
+
+		assets = append(assets, page.HostAsset...)
+	}
+
+	if err = pager.Err(); err != nil {
+		session.lstream.Send(log.Errorf(err, "error while searching host assets in Qualys"))
+	}
+
+	return assets, err
+}
+
+// GetHostAsset loads a single host asset by its Asset Management ID
+func (session *Session) GetHostAsset(assetID int) (asset *HostAsset, err error) {
+	return session.GetHostAssetContext(session.ctx, assetID)
+}
+
+// GetHostAssetContext is GetHostAsset with a context that aborts the API call when cancelled
+func (session *Session) GetHostAssetContext(ctx context.Context, assetID int) (asset *HostAsset, err error) {
+	var path = strings.Replace(session.Config.Address()+qpsHostAssetGet, "<id>", strconv.Itoa(assetID), 1)
+
+	var page hostAssetPage
+	if _, err = session.QPSCall(ctx, http.MethodGet, path, nil, QPSXML, &page); err == nil {
+		if len(page.HostAsset) > 0 {
+			asset = &page.HostAsset[0]
+		} else {
+			err = fmt.Errorf("host asset [%d] was not returned from Qualys - %w", assetID, ErrNotFound)
+		}
+	}
+
+	return asset, err
+}
+
+// GetHostAssetByHostID loads the host asset of a host ID used by the VM APIs, such as the host ID of a QHost. A nil asset
+// is returned without error when no host asset exists for the host
+func (session *Session) GetHostAssetByHostID(ctx context.Context, hostID int) (asset *HostAsset, err error) {
+	var assets map[int]*HostAsset
+	if assets, err = session.GetHostAssetsByHostIDs(ctx, []int{hostID}); err == nil {
+		asset = assets[hostID]
+	}
+
+	return asset, err
+}
+
+// GetHostAssetsByHostIDs loads the host assets of the host IDs used by the VM APIs through a single search, keyed by host
+// ID. Host IDs without a host asset are left out of the map
+func (session *Session) GetHostAssetsByHostIDs(ctx context.Context, hostIDs []int) (assets map[int]*HostAsset, err error) {
+	assets = make(map[int]*HostAsset, len(hostIDs))
+
+	if len(hostIDs) > 0 {
+		var found []HostAsset
+		if found, err = session.SearchHostAssetsContext(ctx, CriteriaIn("qwebHostId", intArrayToStringArray(hostIDs)...)); err == nil {
+			for index := range found {
+				assets[found[index].QWebHostID] = &found[index]
+			}
+		}
+	}
+
+	return assets, err
+}
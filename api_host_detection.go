@@ -1,49 +1,44 @@
 package qualys
 
 import (
+	"context"
 	"fmt"
 	"github.com/nortonlifelock/log"
-	"strconv"
 	"strings"
-	"sync"
 )
 
-func (session *Session) GetTagDetections(tags []string, kernelFilterFlag int) (out <-chan QHost, err error) {
-	// Check for valid list of groups
-	if tags != nil && len(tags) > 0 {
-		// Handle the API request fields for Qualys
-
-		var fields = make(map[string]string)
-		fields["action"] = "list"
-		fields["truncation_limit"] = "0"   // Pull groups of 2500 assets at a time until all assets are loaded
-		fields["show_reopened_info"] = "1" // Show the additional information related to vulnerabilities that have been Reopened in Qualys
-		fields["arf_kernel_filter"] = strconv.Itoa(kernelFilterFlag)
-		fields["status"] = "New,Active,Re-Opened,Fixed" // If this parameter is not passed to the API, by default, the output contains detections with New, Active or Re-Opened <STATUS> only
-		fields["use_tags"] = "1"
-
-		// we can provide either the name of the tag, or the id of the tag
-		// names are strings, ids are integers. here we check if the tags appear to be integers or strings
-		if _, convertErr := strconv.Atoi(tags[0]); convertErr == nil {
-			fields["tag_set_by"] = "id"
-		} else {
-			fields["tag_set_by"] = "name"
-		}
+// hostSpecificChunkSize is the number of IP addresses loaded per cursor by GetHostSpecificDetectionsCursors
+const hostSpecificChunkSize = 5000
 
-		fields["tag_include_selector"] = "all"
-		fields["tag_set_include"] = strings.Join(tags, ",")
+// GetTagDetections loads the vulnerability detections for each host that is tagged by the tags passed (either by name or by ID)
+// and returns them on the OUT channel back to the processor
+func (session *Session) GetTagDetections(tags []string, kernelFilterFlag int) (out <-chan QHost, err error) {
+	return session.GetTagDetectionsContext(session.ctx, tags, kernelFilterFlag)
+}
 
-		// TODO ec2 is currently the only metadata that Qualys supports
-		fields["host_metadata"] = "ec2"
-		fields["host_metadata_fields"] = "instanceId"
+// GetTagDetectionsContext is GetTagDetections with a context that stops the paging of the host detections when cancelled
+func (session *Session) GetTagDetectionsContext(ctx context.Context, tags []string, kernelFilterFlag int) (out <-chan QHost, err error) {
+	var cursor *HostDetectionCursor
+	if cursor, err = session.GetTagDetectionsCursor(tags, kernelFilterFlag); err == nil {
+		out = cursor.Hosts(ctx)
+	}
 
-		session.lstream.Send(log.Infof("Loading detections for hosts tagged by [%s] from Qualys", fields["tag_set_include"]))
+	return out, err
+}
 
-		out, _, err = session.getHostDetectionPostData(session.Config.Address()+qsAssetVMHost, fields)
+// GetTagDetectionsCursor returns a cursor that pages through the detections of each host that is tagged by the tags passed
+// (either by name or by ID). Unlike GetTagDetections the cursor reports the error that stopped the paging and can be
+// resumed from a checkpoint
+func (session *Session) GetTagDetectionsCursor(tags []string, kernelFilterFlag int) (cursor *HostDetectionCursor, err error) {
+	// Check for valid list of groups
+	if tags != nil && len(tags) > 0 {
+		session.lstream.Send(log.Infof("Loading detections for hosts tagged by [%s] from Qualys", strings.Join(tags, ",")))
+		cursor, err = session.GetDetectionsCursor(NewTagDetectionQuery(tags, kernelFilterFlag))
 	} else {
 		err = fmt.Errorf("empty group list passed to GetHostDetections")
 	}
 
-	return out, err
+	return cursor, err
 }
 
 // GetHostDetections Loads the vulnerability detections for each host that is part of the groups passed
@@ -55,25 +50,32 @@ func (session *Session) GetTagDetections(tags []string, kernelFilterFlag int) (o
 // 3 only include kernel related vulnerabilities that are exploitable (found on running kernels)
 // 4 only include kernel related vulnerabilities
 func (session *Session) GetHostDetections(groups []string, kernelFilterFlag int) (out <-chan QHost, err error) {
+	return session.GetHostDetectionsContext(session.ctx, groups, kernelFilterFlag)
+}
+
+// GetHostDetectionsContext is GetHostDetections with a context that stops the paging of the host detections when cancelled
+func (session *Session) GetHostDetectionsContext(ctx context.Context, groups []string, kernelFilterFlag int) (out <-chan QHost, err error) {
+	var cursor *HostDetectionCursor
+	if cursor, err = session.GetHostDetectionsCursor(groups, kernelFilterFlag); err == nil {
+		out = cursor.Hosts(ctx)
+	}
+
+	return out, err
+}
+
+// GetHostDetectionsCursor returns a cursor that pages through the detections of each host that is part of the groups
+// passed. Unlike GetHostDetections the cursor reports the error that stopped the paging and can be resumed from a checkpoint
+func (session *Session) GetHostDetectionsCursor(groups []string, kernelFilterFlag int) (cursor *HostDetectionCursor, err error) {
 	// Check for valid list of groups
 	if groups != nil && len(groups) > 0 {
-		// Handle the API request fields for Qualys
-		var fields = make(map[string]string)
-		fields["action"] = "list"
-		fields["truncation_limit"] = "2500"             // Pull groups of 2500 assets at a time until all assets are loaded
-		fields["show_reopened_info"] = "1"              // Show the additional information related to vulnerabilities that have been Reopened in Qualys
-		fields["status"] = "New,Active,Re-Opened,Fixed" // If this parameter is not passed to the API, by default, the output contains detections with New, Active or Re-Opened <STATUS> only
-		fields["arf_kernel_filter"] = strconv.Itoa(kernelFilterFlag)
-		fields["ag_ids"] = strings.Join(groups, ",")
-
-		session.lstream.Send(log.Infof("Loading [%s] Hosts from Qualys", fields["truncation_limit"]))
-
-		out, _, err = session.getHostDetectionPostData(session.Config.Address()+qsAssetVMHost, fields)
+		var query = NewHostDetectionQuery(groups, kernelFilterFlag)
+		session.lstream.Send(log.Infof("Loading [%d] Hosts from Qualys", query.TruncationLimit))
+		cursor, err = session.GetDetectionsCursor(query)
 	} else {
 		err = fmt.Errorf("empty group list passed to GetHostDetections")
 	}
 
-	return out, err
+	return cursor, err
 }
 
 // GetHostSpecificDetections loads vulnerabilities from the Host Detection API for specific IP addresses which are passed
@@ -85,110 +87,126 @@ func (session *Session) GetHostDetections(groups []string, kernelFilterFlag int)
 // 3 only include kernel related vulnerabilities that are exploitable (found on running kernels)
 // 4 only include kernel related vulnerabilities
 func (session *Session) GetHostSpecificDetections(ip []string, groups []string, kernelFilterFlag int) (output *QHostListDetectionOutput, err error) {
+	return session.GetHostSpecificDetectionsContext(session.ctx, ip, groups, kernelFilterFlag)
+}
 
-	if ip != nil && len(ip) > 0 {
-
-		var fields = make(map[string]string)
-		fields["action"] = "list"
+// GetHostSpecificDetectionsContext is GetHostSpecificDetections with a context that aborts the API call when cancelled
+func (session *Session) GetHostSpecificDetectionsContext(ctx context.Context, ip []string, groups []string, kernelFilterFlag int) (output *QHostListDetectionOutput, err error) {
 
-		// TODO: Correct this so that there can be more than 10000 results and we recurse over them
-		fields["truncation_limit"] = "0"   // 0 means no limit
-		fields["show_reopened_info"] = "1" // Show the additional information related to vulnerabilities that have been Reopened in Qualys
-		fields["status"] = "New,Active,Re-Opened,Fixed"
-		fields["arf_kernel_filter"] = strconv.Itoa(kernelFilterFlag)
-		fields["status"] = "New,Active,Re-Opened,Fixed" // If this parameter is not passed to the API, by default, the output contains detections with New, Active or Re-Opened <STATUS> only
+	if ip != nil && len(ip) > 0 {
 
-		// Concatenate the IP addresses together in a comma separated list of values
-		fields["ips"] = strings.Join(ip, ",")
-		fields["ag_ids"] = strings.Join(groups, ",")
+		var cursors []*HostDetectionCursor
+		if cursors, err = session.GetHostSpecificDetectionsCursors(ip, groups, kernelFilterFlag); err == nil {
+			output = &QHostListDetectionOutput{}
 
-		output = &QHostListDetectionOutput{}
+			for _, cursor := range cursors {
+				for host := range cursor.Hosts(ctx) {
+					output.Hosts = append(output.Hosts, host)
+				}
 
-		// Execute the post call against the API
-		err = session.post(session.Config.Address()+qsAssetVMHost, fields, output)
+				if err = cursor.Err(); err != nil {
+					break
+				}
+			}
+		}
 	}
 
 	return output, err
 }
 
-// getHostDetectionPostData is a recursive API call that pulls data from the Host Detection API in steps and reads the data
-// into the OUT channel which is passed back to the processor
-func (session *Session) getHostDetectionPostData(path string, fields map[string]string) (outReadOnly <-chan QHost, totalHosts int, err error) {
-	var out = make(chan QHost)
+// GetHostSpecificDetectionsCursors splits the IP addresses into chunks and returns a cursor per chunk which pages through
+// the detections of the IPs in the chunk. Streaming the cursors one after another loads the detections of every IP
+// without holding the whole response in memory
+func (session *Session) GetHostSpecificDetectionsCursors(ips []string, groups []string, kernelFilterFlag int) (cursors []*HostDetectionCursor, err error) {
+	cursors = make([]*HostDetectionCursor, 0)
 
-	go func(out chan<- QHost) {
-		defer handleRoutinePanic(session.lstream)
-		defer close(out)
-		var output = QHostListDetectionOutput{}
-
-		// Execute the POST call against the API
-		if err = session.post(path, fields, &output); err == nil {
+	for start := 0; start < len(ips) && err == nil; start += hostSpecificChunkSize {
+		var end = start + hostSpecificChunkSize
+		if end > len(ips) {
+			end = len(ips)
+		}
 
-			// Check the length of the host slice returned from Qualys
-			totalHosts = len(output.Hosts)
+		var cursor *HostDetectionCursor
+		if cursor, err = session.GetDetectionsCursor(NewHostSpecificDetectionQuery(ips[start:end], groups, kernelFilterFlag)); err == nil {
+			cursors = append(cursors, cursor)
+		}
+	}
 
-			session.lstream.Send(log.Infof("Host List Returned from Qualys"))
+	return cursors, err
+}
 
-			var recursiveWG = &sync.WaitGroup{}
+// GetDetections loads the detections of the hosts matched by the query and returns them on the OUT channel back to the
+// processor, following the pages of the response until every host has been loaded. The channel also closes when a page
+// fails to load, so the Err of every cursor returned must be checked once the channel closes before the hosts on the
+// channel are treated as complete
+func (session *Session) GetDetections(query *DetectionQuery) (out <-chan QHost, cursors []*HostDetectionCursor, err error) {
+	return session.GetDetectionsContext(session.ctx, query)
+}
 
-			// Determine if there was an error object in the return of the API call and call the next page of API
-			// results from Qualys
-			if output.Warning != nil {
+// GetDetectionsContext is GetDetections with a context that stops the paging of the host detections when cancelled. When
+// the query sets Partitions the partitions are loaded concurrently and merged onto the OUT channel
+func (session *Session) GetDetectionsContext(ctx context.Context, query *DetectionQuery) (out <-chan QHost, cursors []*HostDetectionCursor, err error) {
+	if cursors, err = session.GetDetectionsCursors(ctx, query); err == nil {
+		if len(cursors) == 1 {
+			out = cursors[0].Hosts(ctx)
+		} else {
+			out = session.MergeHostDetections(ctx, cursors)
+		}
+	}
 
-				recursiveWG.Add(1)
+	return out, cursors, err
+}
 
-				// Execute the next page load in a go routine to allow it to happen concurrently while we process the results from this call
-				session.lstream.Send(log.Infof("Loading Another [%s] Hosts from Qualys", fields["truncation_limit"]))
-				go func() {
-					defer handleRoutinePanic(session.lstream)
-					defer recursiveWG.Done()
-					var extrahosts int
+// CursorsErr returns the first error that stopped one of the cursors, or nil when every cursor loaded all of its pages
+func CursorsErr(cursors []*HostDetectionCursor) (err error) {
+	for _, cursor := range cursors {
+		if err = cursor.Err(); err != nil {
+			break
+		}
+	}
 
-					var recursiveOut <-chan QHost
+	return err
+}
 
-					// Initiate recursive call to the API to pull the next page
-					if recursiveOut, extrahosts, err = session.getHostDetectionPostData(output.Warning.URL, fields); err == nil {
-						totalHosts += extrahosts
+// GetDetectionsCursor returns a cursor that pages through the detections of the hosts matched by the query. The query is
+// validated before the cursor is returned
+func (session *Session) GetDetectionsCursor(query *DetectionQuery) (cursor *HostDetectionCursor, err error) {
+	var fields map[string]string
+	if fields, err = query.fields(); err == nil {
+		cursor = session.newHostDetectionCursor(session.Config.Address()+qsAssetVMHost, fields)
+	}
 
-						for {
-							if in, ok := <-recursiveOut; ok {
-								out <- in
-							} else {
-								break
-							}
-						}
-					}
-				}()
-			}
+	return cursor, err
+}
 
-			// Loop through the hosts returned in this call and push them to the OUT channel for processing
-			session.lstream.Send(log.Infof("Processing [%v] Hosts from Qualys Host List Detection API", len(output.Hosts)))
-			for _, host := range output.Hosts {
-				var detects = len(host.Detections)
-				// Ensure there were detections on the host before pushing it to the channel
-				session.lstream.Send(log.Infof("Pushing Host [%v] with [%v] Detections to channel for processing", host.HostID, detects))
-				// Push the host to the OUT channel for processing
-				out <- host
-			}
+// GetDetectionOutputContext loads the detections of the hosts matched by the query in a single API call and returns the
+// whole response. Only the first page is returned when the query sets a truncation limit
+func (session *Session) GetDetectionOutputContext(ctx context.Context, query *DetectionQuery) (output *QHostListDetectionOutput, err error) {
+	var fields map[string]string
+	if fields, err = query.fields(); err == nil {
+		output = &QHostListDetectionOutput{}
 
-			recursiveWG.Wait()
-		} else {
-			session.lstream.Send(log.Errorf(err, "Error While Loading Host List Detections from Qualys [%s]", err.Error()))
-		}
-	}(out)
+		// Execute the post call against the API
+		err = session.post(ctx, session.Config.Address()+qsAssetVMHost, fields, output)
+	}
 
-	return out, totalHosts, err
+	return output, err
 }
 
 // GetHostAGInfo returns a list of host details corresponding to the IPs that were inputted
 // a single IP may be provided, but is an expensive API call. It is much more efficient to query IPs in bulk
 func (session *Session) GetHostAGInfo(ips []string) (output *HostListOutput, err error) {
+	return session.GetHostAGInfoContext(session.ctx, ips)
+}
+
+// GetHostAGInfoContext is GetHostAGInfo with a context that aborts the API call when cancelled
+func (session *Session) GetHostAGInfoContext(ctx context.Context, ips []string) (output *HostListOutput, err error) {
 	var fields = make(map[string]string)
 	fields["action"] = "list"
 	fields["ips"] = strings.Join(ips, ",")
 	fields["details"] = "Basic/AGs"
 
 	output = &HostListOutput{}
-	err = session.post(session.Config.Address()+"/api/2.0/fo/asset/host/", fields, output)
+	err = session.post(ctx, session.Config.Address()+qsAssetHost, fields, output)
 	return output, err
 }
@@ -0,0 +1,194 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nortonlifelock/log"
+)
+
+// HostDetectionCursor pages through the Host List Detection API one page at a time. Once a page has been streamed onto
+// the channel returned by Hosts the next page is loaded, either through the id_min of the WARNING returned by Qualys or
+// through the URL of the WARNING when it does not hold an id_min
+//
+// Qualys returns the hosts ordered by their host ID, so the ID of the last host passed to the consumer is kept as a
+// checkpoint. A cursor created with the same parameters can be resumed from the checkpoint after a crash
+type HostDetectionCursor struct {
+	session *Session
+	path    string
+	fields  map[string]string
+
+	lock       sync.Mutex
+	started    bool
+	lastHostID int
+	hosts      int
+	responded  time.Time
+	err        error
+}
+
+// newHostDetectionCursor creates a cursor which pages through the hosts returned by the path and fields
+func (session *Session) newHostDetectionCursor(path string, fields map[string]string) (cursor *HostDetectionCursor) {
+	var copied = make(map[string]string)
+	for key, value := range fields {
+		copied[key] = value
+	}
+
+	return &HostDetectionCursor{
+		session: session,
+		path:    path,
+		fields:  copied,
+	}
+}
+
+// Resume sets the cursor to start loading the hosts after the host ID of a checkpoint returned by a previous cursor. Resume
+// must be called before Hosts
+func (cursor *HostDetectionCursor) Resume(checkpoint int) (err error) {
+	cursor.lock.Lock()
+	defer cursor.lock.Unlock()
+
+	if !cursor.started {
+		if checkpoint > 0 {
+			cursor.lastHostID = checkpoint
+			cursor.fields["id_min"] = strconv.Itoa(checkpoint + 1)
+		}
+	} else {
+		err = fmt.Errorf("cannot resume a host detection cursor that has already started")
+	}
+
+	return err
+}
+
+// Hosts starts paging through the host detections and returns the channel the hosts are pushed onto. The channel is
+// closed once every page has been loaded, an error occurs, or the context is cancelled. Err should be checked after the
+// channel closes. Hosts can only be called once per cursor, later calls return a closed channel and leave Err untouched
+func (cursor *HostDetectionCursor) Hosts(ctx context.Context) (hosts <-chan QHost) {
+	var out = make(chan QHost)
+
+	cursor.lock.Lock()
+	var started = cursor.started
+	cursor.started = true
+	cursor.lock.Unlock()
+
+	if !started {
+		go func() {
+			defer handleRoutinePanic(cursor.session.lstream)
+			defer close(out)
+
+			var err = cursor.page(ctx, out)
+			if err != nil {
+				cursor.session.lstream.Send(log.Errorf(err, "Error While Loading Host List Detections from Qualys [%s]", err.Error()))
+			}
+
+			cursor.lock.Lock()
+			cursor.err = err
+			cursor.lock.Unlock()
+		}()
+	} else {
+		// the hosts are already being pushed to the consumer of the first call, which owns Err
+		close(out)
+	}
+
+	return out
+}
+
+// page loads the pages of the cursor one after another until there are no more hosts to load
+func (cursor *HostDetectionCursor) page(ctx context.Context, out chan<- QHost) (err error) {
+	var path = cursor.path
+	var fields = cursor.fields
+
+	for len(path) > 0 && err == nil {
+		if err = ctx.Err(); err == nil {
+
+			var warning *QWarning
+			var pageHosts int
+			var responded time.Time
+			if warning, pageHosts, responded, err = cursor.session.streamHostDetections(ctx, path, fields, func(host QHost) (err error) {
+				select {
+				case <-ctx.Done():
+					err = ctx.Err()
+				case out <- host:
+					cursor.lock.Lock()
+					cursor.lastHostID = host.HostID
+					cursor.hosts++
+					cursor.lock.Unlock()
+				}
+
+				return err
+			}); err == nil {
+				cursor.session.lstream.Send(log.Infof("Processed [%v] Hosts from Qualys Host List Detection API", pageHosts))
+
+				cursor.lock.Lock()
+				if cursor.responded.IsZero() {
+					cursor.responded = responded
+				}
+				cursor.lock.Unlock()
+
+				path = ""
+				if warning != nil && len(warning.URL) > 0 {
+					cursor.session.lstream.Send(log.Infof("Loading Another [%s] Hosts from Qualys", fields["truncation_limit"]))
+					path, fields = nextPage(cursor.path, cursor.fields, warning)
+				}
+			}
+		}
+	}
+
+	return err
+}
+
+// nextPage determines the request for the page following a WARNING. The id_min of the WARNING URL is used along with the
+// original parameters of the request when present, otherwise the WARNING URL is followed as is
+func nextPage(basePath string, baseFields map[string]string, warning *QWarning) (path string, fields map[string]string) {
+	path = warning.URL
+	fields = make(map[string]string)
+
+	if next, err := url.Parse(warning.URL); err == nil {
+		if idMin := next.Query().Get("id_min"); len(idMin) > 0 {
+			path = basePath
+			for key, value := range baseFields {
+				fields[key] = value
+			}
+			fields["id_min"] = idMin
+		}
+	}
+
+	return path, fields
+}
+
+// Err returns the error that stopped the cursor, or nil when every page was loaded
+func (cursor *HostDetectionCursor) Err() (err error) {
+	cursor.lock.Lock()
+	defer cursor.lock.Unlock()
+
+	return cursor.err
+}
+
+// Checkpoint returns the ID of the last host that was passed to the consumer of the cursor. The checkpoint can be passed
+// to Resume to continue loading the hosts that follow it
+func (cursor *HostDetectionCursor) Checkpoint() (hostID int) {
+	cursor.lock.Lock()
+	defer cursor.lock.Unlock()
+
+	return cursor.lastHostID
+}
+
+// ResponseTime returns the DATETIME at which Qualys generated the first page loaded by the cursor, or the zero time when no
+// page has been loaded. Detections updated after it may not have been returned by the cursor, so it is the time to load
+// detections from on the next sync
+func (cursor *HostDetectionCursor) ResponseTime() (responded time.Time) {
+	cursor.lock.Lock()
+	defer cursor.lock.Unlock()
+
+	return cursor.responded
+}
+
+// Count returns the number of hosts that have been passed to the consumer of the cursor
+func (cursor *HostDetectionCursor) Count() (hosts int) {
+	cursor.lock.Lock()
+	defer cursor.lock.Unlock()
+
+	return cursor.hosts
+}
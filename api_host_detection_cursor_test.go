@@ -0,0 +1,116 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hostDetectionPages serves the hosts in pages of two, with a WARNING holding the id_min of the next page until the
+// last host has been returned
+func hostDetectionPages(hostIDs ...int) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		_ = request.ParseForm()
+		var idMin, _ = strconv.Atoi(request.Form.Get("id_min"))
+
+		var body strings.Builder
+		// the DATETIME of each page is a minute after the previous page so that the first page can be told apart
+		fmt.Fprintf(&body, "<HOST_LIST_VM_DETECTION_OUTPUT><RESPONSE><DATETIME>2020-01-01T00:%02d:00Z</DATETIME><HOST_LIST>", idMin)
+
+		var page []int
+		for _, hostID := range hostIDs {
+			if hostID >= idMin && len(page) < 2 {
+				page = append(page, hostID)
+			}
+		}
+
+		for _, hostID := range page {
+			fmt.Fprintf(&body, "<HOST><ID>%d</ID><IP>10.0.0.%d</IP></HOST>", hostID, hostID)
+		}
+		body.WriteString("</HOST_LIST>")
+
+		if len(page) > 0 && page[len(page)-1] < hostIDs[len(hostIDs)-1] {
+			fmt.Fprintf(&body, "<WARNING><CODE>1980</CODE><TEXT>more</TEXT><URL>%s?action=list&amp;id_min=%d</URL></WARNING>", request.URL.Path, page[len(page)-1]+1)
+		}
+
+		body.WriteString("</RESPONSE></HOST_LIST_VM_DETECTION_OUTPUT>")
+		_, _ = writer.Write([]byte(body.String()))
+	}
+}
+
+func TestHostDetectionCursor(t *testing.T) {
+	tests := []struct {
+		name      string
+		resume    int
+		expected  []int
+		responded time.Time
+	}{
+		{"all pages", 0, []int{1, 2, 5, 7, 9}, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"resumed from checkpoint", 2, []int{5, 7, 9}, time.Date(2020, 1, 1, 0, 3, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var server = httptest.NewServer(hostDetectionPages(1, 2, 5, 7, 9))
+			defer server.Close()
+
+			var session = newTestSession(server, qsAssetVMHost)
+			var cursor = session.newHostDetectionCursor(server.URL+qsAssetVMHost, map[string]string{"action": "list"})
+			if err := cursor.Resume(test.resume); err != nil {
+				t.Fatal(err)
+			}
+
+			var hostIDs []int
+			for host := range cursor.Hosts(context.Background()) {
+				hostIDs = append(hostIDs, host.HostID)
+			}
+
+			if err := cursor.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if fmt.Sprint(hostIDs) != fmt.Sprint(test.expected) {
+				t.Fatalf("expected hosts %v, got %v", test.expected, hostIDs)
+			}
+
+			if cursor.Checkpoint() != 9 || cursor.Count() != len(test.expected) {
+				t.Errorf("expected a checkpoint of [9] after [%d] hosts, got [%d] after [%d]", len(test.expected), cursor.Checkpoint(), cursor.Count())
+			}
+
+			if !cursor.ResponseTime().Equal(test.responded) {
+				t.Errorf("expected the response time of the first page [%s], got [%s]", test.responded, cursor.ResponseTime())
+			}
+		})
+	}
+}
+
+func TestHostDetectionCursorSecondCall(t *testing.T) {
+	var server = httptest.NewServer(hostDetectionPages(1, 2, 3))
+	defer server.Close()
+
+	var session = newTestSession(server, qsAssetVMHost)
+	var cursor = session.newHostDetectionCursor(server.URL+qsAssetVMHost, map[string]string{"action": "list"})
+
+	var first = cursor.Hosts(context.Background())
+	if _, open := <-cursor.Hosts(context.Background()); open {
+		t.Fatal("expected the second call to return a closed channel")
+	}
+
+	var count int
+	for range first {
+		count++
+	}
+
+	if err := cursor.Err(); err != nil || count != 3 {
+		t.Fatalf("expected the first call to load [3] hosts without an error, got [%d] hosts and [%v]", count, err)
+	}
+
+	if err := cursor.Resume(1); err == nil {
+		t.Error("expected resuming a started cursor to fail")
+	}
+}
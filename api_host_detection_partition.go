@@ -0,0 +1,231 @@
+package qualys
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nortonlifelock/log"
+)
+
+// hostListSelectionFields are the parameters of a DetectionQuery that are also understood by the Host List API, which
+// are used to select the same hosts when listing the host IDs to partition
+var hostListSelectionFields = []string{
+	"ag_ids",
+	"ips",
+	"ids",
+	"id_min",
+	"id_max",
+	"network_ids",
+	"os_pattern",
+	"use_tags",
+	"tag_set_by",
+	"tag_include_selector",
+	"tag_exclude_selector",
+	"tag_set_include",
+	"tag_set_exclude",
+	"vm_scan_since",
+	"vm_processed_after",
+	"vm_processed_before",
+}
+
+// GetDetectionsCursors returns the cursors that page through the detections of the hosts matched by the query. A single
+// cursor is returned unless the query sets Partitions, in which case the host IDs matched by the query are listed and
+// split into ranges of roughly equal size with a cursor per range. The cursors can be streamed together through
+// MergeHostDetections
+func (session *Session) GetDetectionsCursors(ctx context.Context, query *DetectionQuery) (cursors []*HostDetectionCursor, err error) {
+	cursors = make([]*HostDetectionCursor, 0)
+
+	if err = query.Validate(); err == nil {
+		if query.Partitions > 1 {
+
+			var ids []int
+			if ids, err = session.hostIDs(ctx, query); err == nil {
+				var ranges = partitionHostIDs(ids, query.Partitions)
+				session.lstream.Send(log.Infof("Loading detections for [%d] hosts in [%d] partitions", len(ids), len(ranges)))
+
+				for _, idRange := range ranges {
+					var partition = *query
+					partition.Partitions = 0
+					partition.IDMin = idRange[0]
+					partition.IDMax = idRange[1]
+
+					var cursor *HostDetectionCursor
+					if cursor, err = session.GetDetectionsCursor(&partition); err == nil {
+						cursors = append(cursors, cursor)
+					} else {
+						break
+					}
+				}
+			}
+		} else {
+			var cursor *HostDetectionCursor
+			if cursor, err = session.GetDetectionsCursor(query); err == nil {
+				cursors = append(cursors, cursor)
+			}
+		}
+	}
+
+	return cursors, err
+}
+
+// MergeHostDetections streams the hosts of the cursors onto a single channel. The cursors are loaded concurrently, with no
+// more cursors loading at once than the concurrency limit Qualys reported for the subscription. The Err method of each
+// cursor should be checked once the channel closes
+func (session *Session) MergeHostDetections(ctx context.Context, cursors []*HostDetectionCursor) (hosts <-chan QHost) {
+	var out = make(chan QHost)
+
+	var parallel = session.concurrencyLimit
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	go func() {
+		defer handleRoutinePanic(session.lstream)
+		defer close(out)
+
+		var permits = make(chan bool, parallel)
+		var wg = &sync.WaitGroup{}
+
+		func() {
+			for _, cursor := range cursors {
+				select {
+				case <-ctx.Done():
+					return
+				case permits <- true:
+				}
+
+				wg.Add(1)
+				go func(cursor *HostDetectionCursor) {
+					defer handleRoutinePanic(session.lstream)
+					defer wg.Done()
+					defer func() { <-permits }()
+
+					for host := range cursor.Hosts(ctx) {
+						select {
+						case <-ctx.Done():
+						case out <- host:
+						}
+					}
+				}(cursor)
+			}
+		}()
+
+		wg.Wait()
+	}()
+
+	return out
+}
+
+// hostIDs lists the IDs of the hosts matched by the query through the Host List API, which is far lighter than the Host
+// List Detection API as no host details are requested. The parameters are sent in the body like those of the detection
+// requests as the IP list of the query can be long
+func (session *Session) hostIDs(ctx context.Context, query *DetectionQuery) (ids []int, err error) {
+	var queryFields map[string]string
+	if queryFields, err = query.fields(); err == nil {
+
+		var fields = make(map[string]string)
+		fields["action"] = "list"
+		fields["details"] = "None"
+		fields["truncation_limit"] = "0"
+		for _, key := range hostListSelectionFields {
+			if value, ok := queryFields[key]; ok {
+				fields[key] = value
+			}
+		}
+
+		// the IDs of the ID_SET are collected as they are read, Qualys returns consecutive IDs as ranges
+		var idList, idRanges []string
+		if err = session.streamElements(ctx, session.Config.Address()+qsAssetHost, fields, func(decoder *xml.Decoder, element *xml.StartElement) (err error) {
+			var id string
+			switch element.Name.Local {
+			case "ID":
+				if err = decoder.DecodeElement(&id, element); err == nil {
+					idList = append(idList, id)
+				}
+			case "ID_RANGE":
+				if err = decoder.DecodeElement(&id, element); err == nil {
+					idRanges = append(idRanges, id)
+				}
+			}
+
+			return err
+		}); err == nil {
+			ids, err = parseIDSet(idList, idRanges)
+		}
+	}
+
+	return ids, err
+}
+
+// parseIDSet parses the IDs and ID ranges (e.g. 1-5) of an ID_SET returned by Qualys into the IDs they hold
+func parseIDSet(idList []string, idRanges []string) (ids []int, err error) {
+	ids = make([]int, 0, len(idList))
+
+	for _, id := range idList {
+		var parsed int
+		if parsed, err = strconv.Atoi(strings.TrimSpace(id)); err == nil {
+			ids = append(ids, parsed)
+		} else {
+			break
+		}
+	}
+
+	for _, idRange := range idRanges {
+		if err == nil {
+			var bounds = strings.Split(strings.TrimSpace(idRange), "-")
+			if len(bounds) == 2 {
+				var low, high int
+				if low, err = strconv.Atoi(bounds[0]); err == nil {
+					if high, err = strconv.Atoi(bounds[1]); err == nil {
+						for id := low; id <= high; id++ {
+							ids = append(ids, id)
+						}
+					}
+				}
+			} else {
+				err = fmt.Errorf("invalid ID range [%s]", idRange)
+			}
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("error while parsing the IDs returned from Qualys - %s", err.Error())
+	}
+
+	return ids, err
+}
+
+// partitionHostIDs sorts the host IDs and splits them into at most the number of partitions requested, returning the
+// first and last host ID of each partition. Each partition holds roughly the same number of hosts
+func partitionHostIDs(ids []int, partitions int) (ranges [][2]int) {
+	ranges = make([][2]int, 0)
+
+	if len(ids) > 0 && partitions > 0 {
+		sort.Ints(ids)
+
+		if partitions > len(ids) {
+			partitions = len(ids)
+		}
+
+		var size = len(ids) / partitions
+		var remainder = len(ids) % partitions
+
+		var start int
+		for partition := 0; partition < partitions; partition++ {
+			var end = start + size
+			if partition < remainder {
+				end++
+			}
+
+			ranges = append(ranges, [2]int{ids[start], ids[end-1]})
+			start = end
+		}
+	}
+
+	return ranges
+}
@@ -0,0 +1,207 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPartitionHostIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		ids        []int
+		partitions int
+		expected   [][2]int
+	}{
+		{"no hosts", nil, 3, [][2]int{}},
+		{"no partitions", []int{1, 2}, 0, [][2]int{}},
+		{"single partition", []int{3, 1, 2}, 1, [][2]int{{1, 3}}},
+		{"even split", []int{1, 2, 3, 4, 5, 6}, 3, [][2]int{{1, 2}, {3, 4}, {5, 6}}},
+		{"remainder spread over the first partitions", []int{1, 2, 3, 4, 5, 6, 7}, 3, [][2]int{{1, 3}, {4, 5}, {6, 7}}},
+		{"unsorted with gaps", []int{90, 10, 50, 30}, 2, [][2]int{{10, 30}, {50, 90}}},
+		{"more partitions than hosts", []int{5, 9}, 4, [][2]int{{5, 5}, {9, 9}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if ranges := partitionHostIDs(test.ids, test.partitions); fmt.Sprint(ranges) != fmt.Sprint(test.expected) {
+				t.Fatalf("expected ranges %v, got %v", test.expected, ranges)
+			}
+		})
+	}
+}
+
+func TestParseIDSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      []string
+		ranges   []string
+		expected []int
+		err      bool
+	}{
+		{"empty", nil, nil, []int{}, false},
+		{"IDs", []string{"1", " 7 "}, nil, []int{1, 7}, false},
+		{"ranges", nil, []string{"3-5", "9-9"}, []int{3, 4, 5, 9}, false},
+		{"IDs and ranges", []string{"1"}, []string{"10-12"}, []int{1, 10, 11, 12}, false},
+		{"invalid ID", []string{"one"}, nil, nil, true},
+		{"range without bounds", nil, []string{"5"}, nil, true},
+		{"invalid range bound", nil, []string{"5-x"}, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids, err := parseIDSet(test.ids, test.ranges)
+			if test.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if fmt.Sprint(ids) != fmt.Sprint(test.expected) {
+				t.Fatalf("expected IDs %v, got %v", test.expected, ids)
+			}
+		})
+	}
+}
+
+func TestHostIDs(t *testing.T) {
+	var query string
+	var form map[string][]string
+	var server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		query = request.URL.RawQuery
+		_ = request.ParseForm()
+		form = request.PostForm
+
+		_, _ = writer.Write([]byte("<HOST_LIST_OUTPUT><RESPONSE><ID_SET><ID>1</ID><ID_RANGE>4-6</ID_RANGE><ID>9</ID></ID_SET></RESPONSE></HOST_LIST_OUTPUT>"))
+	}))
+	defer server.Close()
+
+	var ips = make([]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		ips = append(ips, fmt.Sprintf("10.0.%d.%d", i/250, i%250))
+	}
+
+	ids, err := newTestSession(server, qsAssetHost).hostIDs(context.Background(), &DetectionQuery{HostFilter: HostFilter{IPs: ips}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(ids) != fmt.Sprint([]int{1, 9, 4, 5, 6}) {
+		t.Errorf("expected the IDs and ID ranges of the ID_SET, got %v", ids)
+	}
+
+	// the IP list can grow past the length allowed for a URI, so nothing is sent in the query string
+	if len(query) > 0 {
+		t.Errorf("expected the parameters in the body, got the query string [%s]", query)
+	}
+
+	if len(form["ips"]) != 1 || form["ips"][0] != strings.Join(ips, ",") || form["details"][0] != "None" {
+		t.Errorf("expected the IPs of the query in the body, got %v", form)
+	}
+}
+
+// partitionedDetections serves the host IDs through the Host List API and the detections of the hosts within the id_min
+// and id_max of each request through the Host List Detection API, one host per page. Requests with an id_min of failFrom
+// or above are rejected when failFrom is set
+func partitionedDetections(failFrom int, hostIDs ...int) http.Handler {
+	var mux = http.NewServeMux()
+
+	mux.HandleFunc(qsAssetHost, func(writer http.ResponseWriter, request *http.Request) {
+		var body strings.Builder
+		body.WriteString("<HOST_LIST_OUTPUT><RESPONSE><ID_SET>")
+		for _, hostID := range hostIDs {
+			fmt.Fprintf(&body, "<ID>%d</ID>", hostID)
+		}
+		body.WriteString("</ID_SET></RESPONSE></HOST_LIST_OUTPUT>")
+		_, _ = writer.Write([]byte(body.String()))
+	})
+
+	mux.HandleFunc(qsAssetVMHost, func(writer http.ResponseWriter, request *http.Request) {
+		_ = request.ParseForm()
+		var idMin, _ = strconv.Atoi(request.Form.Get("id_min"))
+		var idMax, _ = strconv.Atoi(request.Form.Get("id_max"))
+
+		if failFrom > 0 && idMin >= failFrom {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, _ = writer.Write([]byte(simpleReturnBody(qsInvalidParameterCode, "invalid id_min")))
+			return
+		}
+
+		var body strings.Builder
+		body.WriteString("<HOST_LIST_VM_DETECTION_OUTPUT><RESPONSE><DATETIME>2020-01-01T00:00:00Z</DATETIME><HOST_LIST>")
+
+		var next int
+		for _, hostID := range hostIDs {
+			if hostID >= idMin && (idMax == 0 || hostID <= idMax) {
+				if next == 0 {
+					fmt.Fprintf(&body, "<HOST><ID>%d</ID></HOST>", hostID)
+					next = -1
+				} else if next == -1 {
+					next = hostID
+				}
+			}
+		}
+		body.WriteString("</HOST_LIST>")
+
+		if next > 0 {
+			fmt.Fprintf(&body, "<WARNING><CODE>1980</CODE><TEXT>more</TEXT><URL>%s?action=list&amp;id_min=%d</URL></WARNING>", qsAssetVMHost, next)
+		}
+
+		body.WriteString("</RESPONSE></HOST_LIST_VM_DETECTION_OUTPUT>")
+		_, _ = writer.Write([]byte(body.String()))
+	})
+
+	return mux
+}
+
+func TestGetDetectionsContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		partitions int
+		failFrom   int
+		expected   []int
+		err        error
+	}{
+		{"single cursor", 0, 0, []int{1, 2, 3, 4, 5, 6}, nil},
+		{"partitioned", 3, 0, []int{1, 2, 3, 4, 5, 6}, nil},
+		{"paging error", 0, 4, []int{1, 2, 3}, ErrInvalidParameter},
+		{"partition paging error", 3, 6, []int{1, 2, 3, 4, 5}, ErrInvalidParameter},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var server = httptest.NewServer(partitionedDetections(test.failFrom, 1, 2, 3, 4, 5, 6))
+			defer server.Close()
+
+			var session = newTestSession(server, qsAssetHost, qsAssetVMHost)
+
+			hosts, cursors, err := session.GetDetectionsContext(context.Background(), &DetectionQuery{Partitions: test.partitions})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var hostIDs []int
+			for host := range hosts {
+				hostIDs = append(hostIDs, host.HostID)
+			}
+			sort.Ints(hostIDs)
+
+			if fmt.Sprint(hostIDs) != fmt.Sprint(test.expected) {
+				t.Errorf("expected hosts %v, got %v", test.expected, hostIDs)
+			}
+
+			if err = CursorsErr(cursors); test.err == nil && err != nil {
+				t.Fatalf("expected no error from the cursors, got [%v]", err)
+			} else if test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("expected the cursors to report [%v], got [%v]", test.err, err)
+			}
+		})
+	}
+}
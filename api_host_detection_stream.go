@@ -0,0 +1,98 @@
+package qualys
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nortonlifelock/log"
+)
+
+// streamHostDetections executes a request against the Host List Detection API and decodes the response as it is read
+// from the connection. Each host is passed to push as soon as its HOST element closes so that only a single host is held
+// in memory at a time regardless of the truncation limit. The WARNING returned by Qualys when there are more hosts to
+// load is returned so the caller can request the next page, along with the DATETIME at which Qualys generated the response
+func (session *Session) streamHostDetections(ctx context.Context, path string, fields map[string]string, push func(host QHost) (err error)) (warning *QWarning, hosts int, responded time.Time, err error) {
+	err = session.streamElements(ctx, path, fields, func(decoder *xml.Decoder, element *xml.StartElement) (err error) {
+		switch element.Name.Local {
+		case "HOST":
+			var host QHost
+			if err = decoder.DecodeElement(&host, element); err == nil {
+				hosts++
+
+				session.lstream.Send(log.Infof("Pushing Host [%v] with [%v] Detections to channel for processing", host.HostID, len(host.Detections)))
+				err = push(host)
+			}
+		case "WARNING":
+			warning = &QWarning{}
+			err = decoder.DecodeElement(warning, element)
+		case "DATETIME":
+			// the HOST elements are decoded whole, so only the DATETIME of the response itself is reached here
+			err = decoder.DecodeElement(&responded, element)
+		}
+
+		return err
+	})
+
+	return warning, hosts, responded, err
+}
+
+// streamElements posts the fields to the path as a form and walks the tokens of the response as it is read from the
+// connection, passing each start element to decode. The parameters are sent in the body of the POST as the IP and QID
+// lists can grow past the length allowed for a URI
+func (session *Session) streamElements(ctx context.Context, path string, fields map[string]string, decode func(decoder *xml.Decoder, element *xml.StartElement) (err error)) (err error) {
+	var form = url.Values{}
+	for key, value := range fields {
+		form.Set(key, value)
+	}
+
+	var request *http.Request
+	if request, err = http.NewRequestWithContext(ctx, http.MethodPost, path, strings.NewReader(form.Encode())); err == nil {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		err = session.makeRequest(request, func(response *http.Response) (err error) {
+			return decodeElements(request, response, decode)
+		})
+	}
+
+	return err
+}
+
+// decodeElements walks the tokens of a response, passing each start element to decode which either decodes the element
+// or skips it so its children are walked. A SIMPLE_RETURN in place of the expected output is returned as an APIError
+func decodeElements(request *http.Request, response *http.Response, decode func(decoder *xml.Decoder, element *xml.StartElement) (err error)) (err error) {
+	var decoder = xml.NewDecoder(response.Body)
+
+	for err == nil {
+		var token xml.Token
+		if token, err = decoder.Token(); err == nil {
+
+			if element, ok := token.(xml.StartElement); ok {
+				if element.Name.Local == "SIMPLE_RETURN" {
+					var ret simpleReturn
+					if err = decoder.DecodeElement(&ret, &element); err == nil && ret.Response.Code > 0 {
+						err = &APIError{
+							Endpoint:  request.URL.String(),
+							Status:    response.StatusCode,
+							Code:      ret.Response.Code,
+							Message:   ret.Response.Message,
+							RequestID: response.Header.Get(requestIDHeader),
+						}
+					}
+				} else {
+					err = decode(decoder, &element)
+				}
+			}
+		}
+	}
+
+	if err == io.EOF {
+		err = nil
+	}
+
+	return err
+}
@@ -0,0 +1,152 @@
+package qualys
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HostFilter holds the parameters that select hosts and the host information returned, which are understood by both the
+// Host List API and the Host List Detection API. It is embedded by HostListQuery and DetectionQuery
+type HostFilter struct {
+	// Hosts are selected by asset group, IP, host ID, network and operating system
+	AssetGroupIDs []string
+	IPs           []string
+	HostIDs       []int
+	IDMin         int
+	IDMax         int
+	NetworkIDs    []int
+
+	// OSPattern is a PCRE regular expression matched against the operating system of the host
+	OSPattern string
+
+	// Hosts can alternatively be selected by asset tags, either by tag name or tag ID. The selectors determine whether a host
+	// must hold any or all of the tags
+	TagSetInclude      []string
+	TagSetExclude      []string
+	TagIncludeSelector string
+	TagExcludeSelector string
+
+	// VMScanSince selects the hosts that have been scanned since the time
+	VMScanSince *time.Time
+
+	// ShowTags returns the asset tags of the hosts and ShowCloudTags the tags assigned to the instance by the cloud provider
+	ShowTags      bool
+	ShowCloudTags bool
+
+	// HostMetadata requests the cloud metadata of the hosts
+	HostMetadata string
+
+	// TruncationLimit is the number of hosts returned per page. Qualys defaults to 1000 hosts when it is not set and
+	// NoTruncationLimit returns every host in a single page
+	TruncationLimit int
+}
+
+// validate checks the filter for parameters that would be rejected by Qualys, the errors are prefixed with the name of
+// the query holding the filter
+func (filter *HostFilter) validate(name string) (err error) {
+	var usesTags = len(filter.TagSetInclude) > 0 || len(filter.TagSetExclude) > 0
+
+	switch {
+	case usesTags && (len(filter.AssetGroupIDs) > 0 || len(filter.IPs) > 0):
+		err = fmt.Errorf("%s cannot filter by asset tags along with asset groups or IPs", name)
+	case usesTags && tagSetBy(filter.TagSetInclude, filter.TagSetExclude) == "":
+		err = fmt.Errorf("%s cannot mix tag names and tag IDs [%s|%s]", name, strings.Join(filter.TagSetInclude, ","), strings.Join(filter.TagSetExclude, ","))
+	case !validSelector(filter.TagIncludeSelector) || !validSelector(filter.TagExcludeSelector):
+		err = fmt.Errorf("invalid tag selector [%s|%s], must be one of [%s,%s]", filter.TagIncludeSelector, filter.TagExcludeSelector, TagSelectorAny, TagSelectorAll)
+	case filter.IDMin < 0 || filter.IDMax < 0 || (filter.IDMax > 0 && filter.IDMin > filter.IDMax):
+		err = fmt.Errorf("invalid host ID range [%d-%d]", filter.IDMin, filter.IDMax)
+	case filter.TruncationLimit < NoTruncationLimit:
+		err = fmt.Errorf("invalid truncation limit [%d]", filter.TruncationLimit)
+	}
+
+	if err == nil {
+		switch strings.ToLower(filter.HostMetadata) {
+		case "", HostMetadataAll, HostMetadataEC2, HostMetadataAzure, HostMetadataGoogle:
+		default:
+			err = fmt.Errorf("invalid host metadata [%s], must be one of [%s,%s,%s,%s]", filter.HostMetadata, HostMetadataAll, HostMetadataEC2, HostMetadataAzure, HostMetadataGoogle)
+		}
+	}
+
+	return err
+}
+
+// setFields adds the parameters of the filter to the fields of an API call
+func (filter *HostFilter) setFields(fields map[string]string) {
+	setList(fields, "ag_ids", filter.AssetGroupIDs)
+	setList(fields, "ips", filter.IPs)
+	setList(fields, "ids", intArrayToStringArray(filter.HostIDs))
+	setList(fields, "network_ids", intArrayToStringArray(filter.NetworkIDs))
+	setInt(fields, "id_min", filter.IDMin)
+	setInt(fields, "id_max", filter.IDMax)
+
+	if len(filter.OSPattern) > 0 {
+		fields["os_pattern"] = filter.OSPattern
+	}
+
+	if len(filter.TagSetInclude) > 0 || len(filter.TagSetExclude) > 0 {
+		fields["use_tags"] = "1"
+		fields["tag_set_by"] = tagSetBy(filter.TagSetInclude, filter.TagSetExclude)
+		setList(fields, "tag_set_include", filter.TagSetInclude)
+		setList(fields, "tag_set_exclude", filter.TagSetExclude)
+
+		if len(filter.TagIncludeSelector) > 0 {
+			fields["tag_include_selector"] = strings.ToLower(filter.TagIncludeSelector)
+		}
+
+		if len(filter.TagExcludeSelector) > 0 {
+			fields["tag_exclude_selector"] = strings.ToLower(filter.TagExcludeSelector)
+		}
+	}
+
+	setDate(fields, "vm_scan_since", filter.VMScanSince)
+
+	if filter.ShowTags {
+		fields["show_tags"] = "1"
+	}
+
+	if filter.ShowCloudTags {
+		fields["show_cloud_tags"] = "1"
+	}
+
+	if len(filter.HostMetadata) > 0 {
+		fields["host_metadata"] = strings.ToLower(filter.HostMetadata)
+	}
+
+	if filter.TruncationLimit == NoTruncationLimit {
+		fields["truncation_limit"] = "0" // 0 means no limit
+	} else {
+		setInt(fields, "truncation_limit", filter.TruncationLimit)
+	}
+}
+
+// tagSetBy determines whether the tags are passed by ID or by name. Names are strings while IDs are integers, so the tags
+// are checked to see if they appear to be integers. An empty string is returned when names and IDs are mixed
+func tagSetBy(tagSets ...[]string) (setBy string) {
+	for _, tags := range tagSets {
+		for _, tag := range tags {
+			var current = "name"
+			if _, convertErr := strconv.Atoi(tag); convertErr == nil {
+				current = "id"
+			}
+
+			if len(setBy) == 0 {
+				setBy = current
+			} else if setBy != current {
+				return ""
+			}
+		}
+	}
+
+	return setBy
+}
+
+func validSelector(selector string) bool {
+	switch strings.ToLower(selector) {
+	case "", TagSelectorAny, TagSelectorAll:
+		return true
+	}
+
+	return false
+}
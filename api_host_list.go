@@ -0,0 +1,105 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nortonlifelock/log"
+)
+
+// Values of the HostListQuery Details which determine how much information is returned for each host
+const (
+	HostDetailsBasic    = "Basic"
+	HostDetailsBasicAGs = "Basic/AGs"
+	HostDetailsAll      = "All"
+	HostDetailsAllAGs   = "All/AGs"
+	HostDetailsNone     = "None"
+)
+
+// HostListQuery holds the parameters of a Host List API call. Fields left at their zero value are not sent to Qualys so
+// that the API defaults apply
+type HostListQuery struct {
+	// HostFilter selects the hosts that are listed
+	HostFilter
+
+	// Details determines how much information is returned for each host, All/AGs is used when it is not set
+	Details string
+
+	// NoVMScanSince selects the hosts that have not been scanned since the time
+	NoVMScanSince *time.Time
+
+	// ComplianceEnabled selects the hosts that have the Policy Compliance module enabled
+	ComplianceEnabled bool
+}
+
+// Validate checks the query for parameters that would be rejected by Qualys
+func (query *HostListQuery) Validate() (err error) {
+	if query == nil {
+		return fmt.Errorf("nil host list query")
+	}
+
+	if err = query.HostFilter.validate("host list query"); err == nil {
+		switch query.Details {
+		case "", HostDetailsBasic, HostDetailsBasicAGs, HostDetailsAll, HostDetailsAllAGs, HostDetailsNone:
+		default:
+			err = fmt.Errorf("invalid host details [%s]", query.Details)
+		}
+	}
+
+	return err
+}
+
+// fields validates the query and converts it to the parameters of the Host List API call
+func (query *HostListQuery) fields() (fields map[string]string, err error) {
+	if err = query.Validate(); err == nil {
+		fields = make(map[string]string)
+		fields["action"] = "list"
+
+		fields["details"] = HostDetailsAllAGs
+		if len(query.Details) > 0 {
+			fields["details"] = query.Details
+		}
+
+		query.HostFilter.setFields(fields)
+		setDate(fields, "no_vm_scan_since", query.NoVMScanSince)
+
+		if query.ComplianceEnabled {
+			fields["compliance_enabled"] = "1"
+		}
+	}
+
+	return fields, err
+}
+
+// ListHosts loads the hosts matched by the query from the Host List API, following the pages of the response until
+// every host has been loaded
+func (session *Session) ListHosts(query *HostListQuery) (hosts []HostListHost, err error) {
+	return session.ListHostsContext(session.ctx, query)
+}
+
+// ListHostsContext is ListHosts with a context that stops the paging of the hosts when cancelled
+func (session *Session) ListHostsContext(ctx context.Context, query *HostListQuery) (hosts []HostListHost, err error) {
+	hosts = make([]HostListHost, 0)
+
+	var baseFields map[string]string
+	if baseFields, err = query.fields(); err == nil {
+		var path = session.Config.Address() + qsAssetHost
+		var fields = baseFields
+
+		for len(path) > 0 && err == nil {
+			var output = &HostListOutput{}
+			if err = session.post(ctx, path, fields, output); err == nil {
+				hosts = append(hosts, output.Response.HostList.Host...)
+
+				path = ""
+				if output.Response.Warning != nil && len(output.Response.Warning.URL) > 0 {
+					session.lstream.Send(log.Infof("Loaded [%d] hosts from the Qualys Host List API, loading the next page", len(hosts)))
+					path, fields = nextPage(session.Config.Address()+qsAssetHost, baseFields, output.Response.Warning)
+				}
+			}
+		}
+	}
+
+	return hosts, err
+}
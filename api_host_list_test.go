@@ -0,0 +1,65 @@
+package qualys
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostListQueryFields(t *testing.T) {
+	var since = time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    *HostListQuery
+		expected map[string]string
+		err      string
+	}{
+		{"defaults", &HostListQuery{}, map[string]string{"action": "list", "details": HostDetailsAllAGs}, ""},
+		{
+			"filter and details",
+			&HostListQuery{
+				HostFilter:    HostFilter{TagSetInclude: []string{"1", "2"}, TagIncludeSelector: TagSelectorAny, TruncationLimit: NoTruncationLimit},
+				Details:       HostDetailsNone,
+				NoVMScanSince: &since,
+			},
+			map[string]string{
+				"action":               "list",
+				"details":              HostDetailsNone,
+				"use_tags":             "1",
+				"tag_set_by":           "id",
+				"tag_set_include":      "1,2",
+				"tag_include_selector": "any",
+				"no_vm_scan_since":     "2020-01-02T00:00:00Z",
+				"truncation_limit":     "0",
+			},
+			"",
+		},
+		{"invalid details", &HostListQuery{Details: "Some"}, nil, "invalid host details"},
+		{"invalid filter", &HostListQuery{HostFilter: HostFilter{TagSetInclude: []string{"1"}, IPs: []string{"10.0.0.1"}}}, nil, "host list query cannot filter by asset tags"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, err := test.query.fields()
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing [%s], got [%v]", test.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fields) != len(test.expected) {
+				t.Errorf("expected [%d] fields, got [%d] %v", len(test.expected), len(fields), fields)
+			}
+
+			for key, value := range test.expected {
+				if fields[key] != value {
+					t.Errorf("expected [%s] to be [%s], got [%s]", key, value, fields[key])
+				}
+			}
+		})
+	}
+}
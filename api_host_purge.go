@@ -0,0 +1,184 @@
+package qualys
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nortonlifelock/log"
+)
+
+// Data scopes of a host purge, which determine the modules the data of the hosts is purged from
+const (
+	PurgeScopeVM  = "vm"
+	PurgeScopePC  = "pc"
+	PurgeScopeAll = "vm,pc"
+)
+
+// minPurgeScanAge is how far in the past the scan dates of a purge must be when the hosts are selected by the scan dates
+// alone
+const minPurgeScanAge = 7 * 24 * time.Hour
+
+// PurgeQuery selects the hosts purged by PurgeHosts. The filters are combined, so a host must match every filter that is
+// set to be purged. At least one of the host IDs, IPs, asset groups or scan dates must be set. When the hosts are selected
+// by the scan dates alone, the dates must be at least a week in the past so that the hosts that are still being scanned
+// are never purged
+type PurgeQuery struct {
+	HostIDs       []int
+	IPs           []string
+	AssetGroupIDs []string
+	NetworkIDs    []int
+
+	// NoVMScanSince and NoComplianceScanSince select the hosts that have not been scanned since the time
+	NoVMScanSince         *time.Time
+	NoComplianceScanSince *time.Time
+
+	// DataScope is the module the data of the hosts is purged from, Qualys purges the VM data when it is not set
+	DataScope string
+
+	// DryRun lists the hosts that would be purged without purging them
+	DryRun bool
+}
+
+// batchReturn is the response of the Host Purge API, which lists the IDs of the purged hosts
+type batchReturn struct {
+	XMLName xml.Name `xml:"BATCH_RETURN"`
+	Batches []struct {
+		Code     int      `xml:"CODE"`
+		Text     string   `xml:"TEXT"`
+		IDs      []string `xml:"ID_SET>ID"`
+		IDRanges []string `xml:"ID_SET>ID_RANGE"`
+	} `xml:"RESPONSE>BATCH_LIST>BATCH"`
+}
+
+// Validate checks that the query selects a bounded set of hosts. A query that selects the hosts by the scan dates alone
+// must set dates that are at least a week in the past
+func (query *PurgeQuery) Validate() (err error) {
+	if query == nil {
+		return fmt.Errorf("nil purge query")
+	}
+
+	var byDate = len(query.HostIDs) == 0 && len(query.IPs) == 0 && len(query.AssetGroupIDs) == 0
+	var oldest = time.Now().Add(-minPurgeScanAge)
+
+	switch {
+	case byDate && query.NoVMScanSince == nil && query.NoComplianceScanSince == nil:
+		err = fmt.Errorf("a purge requires host IDs, IPs, asset groups or a scan date to select the hosts")
+	case byDate && query.NoVMScanSince != nil && query.NoVMScanSince.After(oldest):
+		err = fmt.Errorf("a purge selected by scan date alone requires the VM scan date [%s] to be at least [%s] in the past", query.NoVMScanSince.Format(time.RFC3339), minPurgeScanAge)
+	case byDate && query.NoComplianceScanSince != nil && query.NoComplianceScanSince.After(oldest):
+		err = fmt.Errorf("a purge selected by scan date alone requires the compliance scan date [%s] to be at least [%s] in the past", query.NoComplianceScanSince.Format(time.RFC3339), minPurgeScanAge)
+	case len(query.DataScope) > 0 && query.DataScope != PurgeScopeVM && query.DataScope != PurgeScopePC && query.DataScope != PurgeScopeAll:
+		err = fmt.Errorf("invalid purge data scope [%s], must be one of [%s|%s|%s]", query.DataScope, PurgeScopeVM, PurgeScopePC, PurgeScopeAll)
+	}
+
+	return err
+}
+
+// fields converts the query to the parameters that select the hosts, which are shared by the Host Purge and Host List APIs
+func (query *PurgeQuery) fields() (fields map[string]string) {
+	fields = make(map[string]string)
+	setList(fields, "ids", intArrayToStringArray(query.HostIDs))
+	setList(fields, "ips", query.IPs)
+	setList(fields, "ag_ids", query.AssetGroupIDs)
+	setList(fields, "network_ids", intArrayToStringArray(query.NetworkIDs))
+	setDate(fields, "no_vm_scan_since", query.NoVMScanSince)
+	setDate(fields, "no_compliance_scan_since", query.NoComplianceScanSince)
+
+	return fields
+}
+
+// PurgeCandidates lists the hosts the query would purge along with their details. Only the hosts with Policy Compliance
+// enabled hold the data of a purge scoped to compliance, so the hosts without it are left out of the list
+func (session *Session) PurgeCandidates(query *PurgeQuery) (hosts []HostListHost, err error) {
+	return session.PurgeCandidatesContext(session.ctx, query)
+}
+
+// PurgeCandidatesContext is PurgeCandidates with a context that aborts the API call when cancelled
+func (session *Session) PurgeCandidatesContext(ctx context.Context, query *PurgeQuery) (hosts []HostListHost, err error) {
+	if err = query.Validate(); err == nil {
+		hosts, err = session.ListHostsContext(ctx, &HostListQuery{
+			HostFilter: HostFilter{
+				HostIDs:       query.HostIDs,
+				IPs:           query.IPs,
+				AssetGroupIDs: query.AssetGroupIDs,
+				NetworkIDs:    query.NetworkIDs,
+			},
+			Details:           HostDetailsBasic,
+			NoVMScanSince:     query.NoVMScanSince,
+			ComplianceEnabled: query.DataScope == PurgeScopePC,
+		})
+
+		if err == nil && query.NoComplianceScanSince != nil {
+			// the Host List API does not filter by compliance scans, so the filter is applied to the listed hosts
+			var filtered = make([]HostListHost, 0, len(hosts))
+			for _, host := range hosts {
+				if host.LastComplianceScan == nil || host.LastComplianceScan.Before(*query.NoComplianceScanSince) {
+					filtered = append(filtered, host)
+				}
+			}
+			hosts = filtered
+		}
+	}
+
+	return hosts, err
+}
+
+// PurgeHosts purges the hosts selected by the query and returns the IDs of the purged hosts. When the query is a dry run
+// nothing is purged and the IDs of the hosts that would be purged are returned instead
+func (session *Session) PurgeHosts(query *PurgeQuery) (hostIDs []int, err error) {
+	return session.PurgeHostsContext(session.ctx, query)
+}
+
+// PurgeHostsContext is PurgeHosts with a context that aborts the API call when cancelled
+func (session *Session) PurgeHostsContext(ctx context.Context, query *PurgeQuery) (hostIDs []int, err error) {
+	hostIDs = make([]int, 0)
+
+	if err = query.Validate(); err == nil {
+		if query.DryRun {
+			var hosts []HostListHost
+			if hosts, err = session.PurgeCandidatesContext(ctx, query); err == nil {
+				for _, host := range hosts {
+					var hostID int
+					if hostID, err = strconv.Atoi(strings.TrimSpace(host.ID)); err == nil {
+						hostIDs = append(hostIDs, hostID)
+					} else {
+						err = fmt.Errorf("invalid host ID [%s] returned from Qualys - %s", host.ID, err.Error())
+						break
+					}
+				}
+
+				session.lstream.Send(log.Infof("Dry run of host purge selected [%d] hosts", len(hostIDs)))
+			}
+		} else {
+			var fields = query.fields()
+			fields["action"] = "purge"
+			if len(query.DataScope) > 0 {
+				fields["data_scope"] = query.DataScope
+			}
+
+			var output = &batchReturn{}
+			if err = session.post(ctx, session.Config.Address()+qsAssetHost, fields, output); err == nil {
+				for _, batch := range output.Batches {
+					if err == nil {
+						var ids []int
+						if ids, err = parseIDSet(batch.IDs, batch.IDRanges); err == nil {
+							hostIDs = append(hostIDs, ids...)
+						}
+					}
+				}
+
+				if err == nil {
+					session.lstream.Send(log.Infof("Purged [%d] hosts from Qualys", len(hostIDs)))
+				}
+			} else {
+				err = fmt.Errorf("error while purging hosts - %w", err)
+			}
+		}
+	}
+
+	return hostIDs, err
+}
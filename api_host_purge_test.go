@@ -0,0 +1,108 @@
+package qualys
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPurgeHosts(t *testing.T) {
+	var hostList = `<HOST_LIST_OUTPUT><RESPONSE><HOST_LIST><HOST><ID>1</ID></HOST><HOST><ID>2</ID></HOST></HOST_LIST></RESPONSE></HOST_LIST_OUTPUT>`
+	var batchReturn = `<BATCH_RETURN><RESPONSE><BATCH_LIST><BATCH><ID_SET><ID>1</ID><ID_RANGE>3-4</ID_RANGE></ID_SET></BATCH></BATCH_LIST></RESPONSE></BATCH_RETURN>`
+
+	tests := []struct {
+		name     string
+		query    *PurgeQuery
+		response string
+		hostIDs  []int
+		expected map[string]string
+	}{
+		{
+			"dry run lists the hosts",
+			&PurgeQuery{IPs: []string{"10.0.0.1"}, DryRun: true},
+			hostList,
+			[]int{1, 2},
+			map[string]string{"action": "list", "ips": "10.0.0.1", "compliance_enabled": ""},
+		},
+		{
+			"dry run of a compliance purge lists the compliance hosts",
+			&PurgeQuery{IPs: []string{"10.0.0.1"}, DataScope: PurgeScopePC, DryRun: true},
+			hostList,
+			[]int{1, 2},
+			map[string]string{"action": "list", "ips": "10.0.0.1", "compliance_enabled": "1", "data_scope": ""},
+		},
+		{
+			"purge",
+			&PurgeQuery{HostIDs: []int{1, 3, 4}, DataScope: PurgeScopeAll},
+			batchReturn,
+			[]int{1, 3, 4},
+			map[string]string{"action": "purge", "ids": "1,3,4", "data_scope": PurgeScopeAll},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var queries = make([]url.Values, 0)
+			var server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				queries = append(queries, request.URL.Query())
+				_, _ = writer.Write([]byte(test.response))
+			}))
+			defer server.Close()
+
+			hostIDs, err := newTestSession(server, qsAssetHost).PurgeHosts(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(hostIDs, test.hostIDs) {
+				t.Errorf("expected the hosts %v, got %v", test.hostIDs, hostIDs)
+			}
+
+			if len(queries) != 1 {
+				t.Fatalf("expected a single request, got %v", queries)
+			}
+
+			for key, value := range test.expected {
+				if queries[0].Get(key) != value {
+					t.Errorf("expected [%s] to be [%s], got [%s]", key, value, queries[0].Get(key))
+				}
+			}
+		})
+	}
+}
+
+func TestPurgeQueryValidate(t *testing.T) {
+	var recent = time.Now().Add(-time.Hour)
+	var old = time.Now().Add(-minPurgeScanAge - time.Hour)
+
+	tests := []struct {
+		name  string
+		query *PurgeQuery
+		valid bool
+	}{
+		{"nil", nil, false},
+		{"no filters", &PurgeQuery{}, false},
+		{"network only", &PurgeQuery{NetworkIDs: []int{1}}, false},
+		{"host IDs", &PurgeQuery{HostIDs: []int{1}}, true},
+		{"IPs with a recent scan date", &PurgeQuery{IPs: []string{"10.0.0.1"}, NoVMScanSince: &recent}, true},
+		{"asset groups", &PurgeQuery{AssetGroupIDs: []string{"1"}}, true},
+		{"old VM scan date alone", &PurgeQuery{NoVMScanSince: &old}, true},
+		{"old compliance scan date alone", &PurgeQuery{NoComplianceScanSince: &old, DataScope: PurgeScopePC}, true},
+		{"recent VM scan date alone", &PurgeQuery{NoVMScanSince: &recent}, false},
+		{"recent compliance scan date alone", &PurgeQuery{NoVMScanSince: &old, NoComplianceScanSince: &recent}, false},
+		{"invalid data scope", &PurgeQuery{HostIDs: []int{1}, DataScope: "was"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.query.Validate(); test.valid && err != nil {
+				t.Errorf("expected the query to be valid, got [%v]", err)
+			} else if !test.valid && err == nil {
+				t.Error("expected the query to be rejected")
+			}
+		})
+	}
+}
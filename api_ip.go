@@ -0,0 +1,269 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nortonlifelock/log"
+)
+
+// Tracking methods of the IPs added to the subscription
+const (
+	TrackingMethodIP      = "IP"
+	TrackingMethodDNS     = "DNS"
+	TrackingMethodNetBIOS = "NETBIOS"
+)
+
+// IPListQuery holds the parameters that filter the IPs returned by ListIPs. Every IP of the subscription is returned when
+// no parameter is set
+type IPListQuery struct {
+	IPs            []string
+	NetworkID      int
+	TrackingMethod string
+}
+
+// IPAddition holds the IPs to add to the subscription along with the attributes of the new hosts
+type IPAddition struct {
+	// IPs holds the IPs and IP ranges (e.g. 10.0.0.1-10.0.0.254) to add
+	IPs            []string
+	TrackingMethod string
+	NetworkID      int
+
+	// EnableVM and EnablePC add the IPs to the Vulnerability Management and Policy Compliance modules. At least one of
+	// them must be set
+	EnableVM bool
+	EnablePC bool
+
+	Owner   string
+	Comment string
+}
+
+// IPUpdate holds the changes made to the hosts of IPs by UpdateIPs. Fields left at their zero value are not changed
+type IPUpdate struct {
+	// IPs holds the IPs and IP ranges of the hosts to update
+	IPs            []string
+	NetworkID      int
+	TrackingMethod string
+	HostDNS        string
+	HostNetBIOS    string
+	Owner          string
+	Comment        string
+}
+
+// ListIPs returns the IPs and IP ranges of the subscription matched by the query, in the form Qualys lists them
+func (session *Session) ListIPs(query IPListQuery) (ips []string, err error) {
+	return session.ListIPsContext(session.ctx, query)
+}
+
+// ListIPsContext is ListIPs with a context that aborts the API call when cancelled
+func (session *Session) ListIPsContext(ctx context.Context, query IPListQuery) (ips []string, err error) {
+	var fields = make(map[string]string)
+	fields["action"] = "list"
+	setList(fields, "ips", query.IPs)
+	setInt(fields, "network_id", query.NetworkID)
+
+	if len(query.TrackingMethod) > 0 {
+		fields["tracking_method"] = query.TrackingMethod
+	}
+
+	var output = &ipListOutput{}
+	if err = session.post(ctx, session.Config.Address()+qsAssetIP, fields, output); err == nil {
+		ips = append(append(ips, output.IPs...), output.Ranges...)
+	} else {
+		session.lstream.Send(log.Errorf(err, "error while listing the IPs of the subscription"))
+	}
+
+	return ips, err
+}
+
+// AddIPs adds the IPs to the subscription so that they can be scanned
+func (session *Session) AddIPs(addition IPAddition) (err error) {
+	return session.AddIPsContext(session.ctx, addition)
+}
+
+// AddIPsContext is AddIPs with a context that aborts the API call when cancelled
+func (session *Session) AddIPsContext(ctx context.Context, addition IPAddition) (err error) {
+	if len(addition.IPs) > 0 && (addition.EnableVM || addition.EnablePC) {
+		var fields = make(map[string]string)
+		fields["action"] = "add"
+		setList(fields, "ips", addition.IPs)
+		setInt(fields, "network_id", addition.NetworkID)
+
+		if addition.EnableVM {
+			fields["enable_vm"] = "1"
+		}
+
+		if addition.EnablePC {
+			fields["enable_pc"] = "1"
+		}
+
+		if len(addition.TrackingMethod) > 0 {
+			fields["tracking_method"] = addition.TrackingMethod
+		}
+
+		if len(addition.Owner) > 0 {
+			fields["owner"] = addition.Owner
+		}
+
+		if len(addition.Comment) > 0 {
+			fields["comment"] = addition.Comment
+		}
+
+		if err = session.post(ctx, session.Config.Address()+qsAssetIP, fields, &simpleReturn{}); err != nil {
+			err = fmt.Errorf("error while adding IPs [%s] to the subscription - %w", strings.Join(addition.IPs, ","), err)
+		}
+	} else {
+		err = fmt.Errorf("IPs and at least one of the VM and PC modules are required to add IPs to the subscription")
+	}
+
+	return err
+}
+
+// UpdateIPs changes the attributes of the hosts of the IPs
+func (session *Session) UpdateIPs(update IPUpdate) (err error) {
+	return session.UpdateIPsContext(session.ctx, update)
+}
+
+// UpdateIPsContext is UpdateIPs with a context that aborts the API call when cancelled
+func (session *Session) UpdateIPsContext(ctx context.Context, update IPUpdate) (err error) {
+	if len(update.IPs) > 0 {
+		var fields = make(map[string]string)
+		fields["action"] = "update"
+		setList(fields, "ips", update.IPs)
+		setInt(fields, "network_id", update.NetworkID)
+
+		for key, value := range map[string]string{
+			"tracking_method": update.TrackingMethod,
+			"host_dns":        update.HostDNS,
+			"host_netbios":    update.HostNetBIOS,
+			"owner":           update.Owner,
+			"comment":         update.Comment,
+		} {
+			if len(value) > 0 {
+				fields[key] = value
+			}
+		}
+
+		if err = session.post(ctx, session.Config.Address()+qsAssetIP, fields, &simpleReturn{}); err != nil {
+			err = fmt.Errorf("error while updating IPs [%s] - %w", strings.Join(update.IPs, ","), err)
+		}
+	} else {
+		err = fmt.Errorf("IPs are required to update IPs")
+	}
+
+	return err
+}
+
+// ListExcludedIPs returns the IPs and IP ranges excluded from scanning. When IPs are passed only the exclusions of those
+// IPs are returned
+func (session *Session) ListExcludedIPs(ips []string, networkID int) (excluded []ExcludedIP, err error) {
+	return session.ListExcludedIPsContext(session.ctx, ips, networkID)
+}
+
+// ListExcludedIPsContext is ListExcludedIPs with a context that aborts the API call when cancelled
+func (session *Session) ListExcludedIPsContext(ctx context.Context, ips []string, networkID int) (excluded []ExcludedIP, err error) {
+	var fields = make(map[string]string)
+	fields["action"] = "list"
+	setList(fields, "ips", ips)
+	setInt(fields, "network_id", networkID)
+
+	var output = &excludedIPListOutput{}
+	if err = session.post(ctx, session.Config.Address()+qsAssetExcludedIP, fields, output); err == nil {
+		excluded = append(append(excluded, output.IPs...), output.Ranges...)
+	} else {
+		session.lstream.Send(log.Errorf(err, "error while listing the excluded IPs of the subscription"))
+	}
+
+	return excluded, err
+}
+
+// ExcludeIPs excludes the IPs from scanning. The exclusion is removed by Qualys after the number of days when expiryDays
+// is set, otherwise it lasts until it is removed. Qualys requires a comment for every exclusion
+func (session *Session) ExcludeIPs(ips []string, comment string, expiryDays int, networkID int) (err error) {
+	return session.ExcludeIPsContext(session.ctx, ips, comment, expiryDays, networkID)
+}
+
+// ExcludeIPsContext is ExcludeIPs with a context that aborts the API call when cancelled
+func (session *Session) ExcludeIPsContext(ctx context.Context, ips []string, comment string, expiryDays int, networkID int) (err error) {
+	if len(ips) > 0 && len(comment) > 0 {
+		var fields = make(map[string]string)
+		fields["action"] = "add"
+		fields["comment"] = comment
+		setList(fields, "ips", ips)
+		setInt(fields, "expiry_days", expiryDays)
+		setInt(fields, "network_id", networkID)
+
+		if err = session.post(ctx, session.Config.Address()+qsAssetExcludedIP, fields, &simpleReturn{}); err != nil {
+			err = fmt.Errorf("error while excluding IPs [%s] - %w", strings.Join(ips, ","), err)
+		}
+	} else {
+		err = fmt.Errorf("IPs and a comment are required to exclude IPs [%d|%s]", len(ips), comment)
+	}
+
+	return err
+}
+
+// RemoveExcludedIPs removes the exclusion of the IPs so that they are scanned again. Qualys requires a comment for every
+// removal
+func (session *Session) RemoveExcludedIPs(ips []string, comment string, networkID int) (err error) {
+	return session.RemoveExcludedIPsContext(session.ctx, ips, comment, networkID)
+}
+
+// RemoveExcludedIPsContext is RemoveExcludedIPs with a context that aborts the API call when cancelled
+func (session *Session) RemoveExcludedIPsContext(ctx context.Context, ips []string, comment string, networkID int) (err error) {
+	if len(ips) > 0 && len(comment) > 0 {
+		var fields = make(map[string]string)
+		fields["action"] = "remove"
+		fields["comment"] = comment
+		setList(fields, "ips", ips)
+		setInt(fields, "network_id", networkID)
+
+		if err = session.post(ctx, session.Config.Address()+qsAssetExcludedIP, fields, &simpleReturn{}); err != nil {
+			err = fmt.Errorf("error while removing the exclusion of IPs [%s] - %w", strings.Join(ips, ","), err)
+		}
+	} else {
+		err = fmt.Errorf("IPs and a comment are required to remove excluded IPs [%d|%s]", len(ips), comment)
+	}
+
+	return err
+}
+
+// IPsInSubscription splits the IPs into those that are already in the subscription and those that must be added before
+// they can be scanned. IP ranges are compared by the addresses they cover
+func (session *Session) IPsInSubscription(ips []string, networkID int) (known []string, unknown []string, err error) {
+	return session.IPsInSubscriptionContext(session.ctx, ips, networkID)
+}
+
+// IPsInSubscriptionContext is IPsInSubscription with a context that aborts the API call when cancelled
+func (session *Session) IPsInSubscriptionContext(ctx context.Context, ips []string, networkID int) (known []string, unknown []string, err error) {
+	var listed []string
+	if listed, err = session.ListIPsContext(ctx, IPListQuery{IPs: ips, NetworkID: networkID}); err == nil {
+		var wanted, subscribed []ipRange
+		var wantedOther, subscribedOther []string
+		if wanted, wantedOther, err = parseIPRanges(ips); err == nil {
+			if subscribed, subscribedOther, err = parseIPRanges(listed); err == nil {
+				var missing = subtractIPRanges(wanted, subscribed)
+				known = ipRangeStrings(subtractIPRanges(wanted, missing))
+				unknown = ipRangeStrings(missing)
+
+				var isSubscribed = make(map[string]bool)
+				for _, ip := range subscribedOther {
+					isSubscribed[ip] = true
+				}
+
+				for _, ip := range wantedOther {
+					if isSubscribed[ip] {
+						known = append(known, ip)
+					} else {
+						unknown = append(unknown, ip)
+					}
+				}
+			} else {
+				err = fmt.Errorf("error while parsing the IPs of the subscription - %s", err.Error())
+			}
+		}
+	}
+
+	return known, unknown, err
+}
@@ -1,37 +1,46 @@
 package qualys
 
 import (
+	"context"
 	"fmt"
 	"github.com/nortonlifelock/log"
+	"sort"
+	"strings"
 	"time"
 )
 
-// LoadVulnerabilities downloads the ENTIRE qualys knowledge base on vulnerabilities in a single API call. There is currently
-// no Qualys support for paging on this API call, so this method can be quite expensive (> 10 minutes)
+// LoadVulnerabilities downloads the ENTIRE qualys knowledge base on vulnerabilities. The download is split into chunks of
+// QID ranges by DownloadVulnerabilities, so a failed request only repeats its own chunk instead of the whole download
 func (session *Session) LoadVulnerabilities(since *time.Time) (output *QKnowledgeBaseVulnOutput, err error) {
-	output = &QKnowledgeBaseVulnOutput{}
-
-	// Set the status of the KB load as started
-	session.lstream.Send(log.Info("Starting load of vulnerabilities from knowledge base"))
+	return session.LoadVulnerabilitiesContext(session.ctx, since)
+}
 
-	var fields = make(map[string]string)
-	fields["action"] = "list"
-	fields["details"] = "All" // Pull ALL details for the vulnerabilities
-	if since != nil {
-		// there is also published_after field, but it matches the last_modified_after field if the vulnerability has been created but not updated
-		fields["last_modified_after"] = since.Format("2006-01-02")
-	}
+// LoadVulnerabilitiesContext is LoadVulnerabilities with a context that aborts the API calls when cancelled
+func (session *Session) LoadVulnerabilitiesContext(ctx context.Context, since *time.Time) (output *QKnowledgeBaseVulnOutput, err error) {
+	output = &QKnowledgeBaseVulnOutput{}
 
-	// Execute the post call against the Qualys API
-	if err = session.post(session.Config.Address()+qsVulnerabilities, fields, &output); err != nil {
+	if err = session.DownloadVulnerabilities(ctx, KBDownload{Since: since}, func(vuln QVulnerability) (err error) {
+		output.Vulnerabilities = append(output.Vulnerabilities, vuln)
+		return nil
+	}); err != nil {
 		session.lstream.Send(log.Errorf(err, "Vulnerability Information failed to load [%s]", err.Error()))
 	}
 
+	// the chunks complete in any order, so the vulnerabilities are sorted by QID as they were when loaded in a single call
+	sort.Slice(output.Vulnerabilities, func(i, j int) bool {
+		return output.Vulnerabilities[i].QualysID < output.Vulnerabilities[j].QualysID
+	})
+
 	return output, err
 }
 
 // LoadVulnerability loads a single vulnerability from the Qualys knowledge base
 func (session *Session) LoadVulnerability(id string) (vuln *QVulnerability, err error) {
+	return session.LoadVulnerabilityContext(session.ctx, id)
+}
+
+// LoadVulnerabilityContext is LoadVulnerability with a context that aborts the API calls when cancelled
+func (session *Session) LoadVulnerabilityContext(ctx context.Context, id string) (vuln *QVulnerability, err error) {
 	var output = &QKnowledgeBaseVulnOutput{}
 
 	var fields = make(map[string]string)
@@ -40,7 +49,7 @@ func (session *Session) LoadVulnerability(id string) (vuln *QVulnerability, err
 	fields["ids"] = id
 
 	// Execute the post call against the Qualys API
-	if err = session.post(session.Config.Address()+qsVulnerabilities, fields, &output); err == nil {
+	if err = session.post(ctx, session.Config.Address()+qsVulnerabilities, fields, &output); err == nil {
 		if len(output.Vulnerabilities) == 1 {
 			vuln = &output.Vulnerabilities[0]
 		} else {
@@ -52,3 +61,51 @@ func (session *Session) LoadVulnerability(id string) (vuln *QVulnerability, err
 
 	return vuln, err
 }
+
+// LoadVulnerabilitiesByID loads the vulnerabilities of the QIDs from the Qualys knowledge base in a single request. QIDs
+// that are not in the knowledge base are left out of the returned vulnerabilities
+func (session *Session) LoadVulnerabilitiesByID(ids []int) (vulns []QVulnerability, err error) {
+	return session.LoadVulnerabilitiesByIDContext(session.ctx, ids)
+}
+
+// LoadVulnerabilitiesByIDContext is LoadVulnerabilitiesByID with a context that aborts the API calls when cancelled
+func (session *Session) LoadVulnerabilitiesByIDContext(ctx context.Context, ids []int) (vulns []QVulnerability, err error) {
+	vulns = make([]QVulnerability, 0, len(ids))
+
+	if len(ids) > 0 {
+		if err = session.DownloadVulnerabilities(ctx, KBDownload{IDs: ids}, func(vuln QVulnerability) (err error) {
+			vulns = append(vulns, vuln)
+			return nil
+		}); err != nil {
+			session.lstream.Send(log.Errorf(err, "Vulnerability Information failed to load for [%d] QIDs", len(ids)))
+		}
+	}
+
+	return vulns, err
+}
+
+// LoadVulnerabilitiesByCVE loads the vulnerabilities that reference the CVEs from the Qualys knowledge base
+func (session *Session) LoadVulnerabilitiesByCVE(cves []string) (vulns []QVulnerability, err error) {
+	return session.LoadVulnerabilitiesByCVEContext(session.ctx, cves)
+}
+
+// LoadVulnerabilitiesByCVEContext is LoadVulnerabilitiesByCVE with a context that aborts the API calls when cancelled
+func (session *Session) LoadVulnerabilitiesByCVEContext(ctx context.Context, cves []string) (vulns []QVulnerability, err error) {
+	vulns = make([]QVulnerability, 0)
+
+	if len(cves) > 0 {
+		var normalized = make([]string, 0, len(cves))
+		for _, cve := range cves {
+			normalized = append(normalized, normalizeCVE(cve))
+		}
+
+		if err = session.DownloadVulnerabilities(ctx, KBDownload{CVEs: normalized}, func(vuln QVulnerability) (err error) {
+			vulns = append(vulns, vuln)
+			return nil
+		}); err != nil {
+			session.lstream.Send(log.Errorf(err, "Vulnerability Information failed to load for CVEs [%s]", strings.Join(normalized, ",")))
+		}
+	}
+
+	return vulns, err
+}
@@ -0,0 +1,257 @@
+package qualys
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nortonlifelock/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultKBChunkSize is the number of QIDs covered by each chunk of a knowledge base download
+	defaultKBChunkSize = 50000
+
+	// defaultKBMaxQID is where the chunks of a knowledge base download stop being bounded. The final chunk loads every QID
+	// above it so that QIDs issued beyond it are never missed
+	defaultKBMaxQID = 1000000
+
+	// defaultKBChunkRetries is the number of times a failed chunk is retried before the download fails
+	defaultKBChunkRetries = 3
+)
+
+// KBDownload holds the parameters of a chunked knowledge base download. Fields left at their zero value use the defaults
+type KBDownload struct {
+	// Since only loads the vulnerabilities modified after the time
+	Since *time.Time
+
+	// IDs only loads the vulnerabilities with the QIDs, in place of the QID ranges
+	IDs []int
+
+	// CVEs only loads the vulnerabilities that reference the CVEs, in place of the QID ranges
+	CVEs []string
+
+	// ChunkSize is the number of QIDs covered by each chunk
+	ChunkSize int
+
+	// MaxQID is the last QID covered by a bounded chunk, every QID above it is loaded by a final unbounded chunk
+	MaxQID int
+
+	// Retries is the number of times a failed chunk is retried before the download fails
+	Retries int
+}
+
+// kbChunk is a range of QIDs loaded by a single request. A high of 0 leaves the range unbounded
+type kbChunk struct {
+	low  int
+	high int
+}
+
+// chunks splits the QID space of the download into ranges
+func (download KBDownload) chunks() (chunks []kbChunk) {
+	var size = download.ChunkSize
+	if size <= 0 {
+		size = defaultKBChunkSize
+	}
+
+	var max = download.MaxQID
+	if max <= 0 {
+		max = defaultKBMaxQID
+	}
+
+	for low := 1; low <= max; low += size {
+		var high = low + size - 1
+		if high > max {
+			high = max
+		}
+
+		chunks = append(chunks, kbChunk{low: low, high: high})
+	}
+
+	return append(chunks, kbChunk{low: max + 1})
+}
+
+// fields returns the parameters shared by every chunk of the download
+func (download KBDownload) fields() (fields map[string]string) {
+	fields = make(map[string]string)
+	fields["action"] = "list"
+	fields["details"] = "All" // Pull ALL details for the vulnerabilities
+	if download.Since != nil {
+		// there is also published_after field, but it matches the last_modified_after field if the vulnerability has been created but not updated
+		fields["last_modified_after"] = download.Since.Format("2006-01-02")
+	}
+
+	return fields
+}
+
+// DownloadVulnerabilities loads the knowledge base in chunks of QID ranges which are requested concurrently, with no more
+// chunks loading at once than the concurrency limit Qualys reported for the subscription. Each chunk is decoded as it is
+// read and every vulnerability is passed to push, which is never called concurrently. A chunk that fails is retried from
+// the QID after the last vulnerability it pushed, so a transient failure only repeats the remainder of that chunk
+func (session *Session) DownloadVulnerabilities(ctx context.Context, download KBDownload, push func(vuln QVulnerability) (err error)) (err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var retries = download.Retries
+	if retries <= 0 {
+		retries = defaultKBChunkRetries
+	}
+
+	var parallel = session.concurrencyLimit
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var pushLock sync.Mutex
+	var serialPush = func(vuln QVulnerability) error {
+		pushLock.Lock()
+		defer pushLock.Unlock()
+		return push(vuln)
+	}
+
+	var baseFields = download.fields()
+	var requests = make([]map[string]string, 0)
+	if len(download.IDs) > 0 || len(download.CVEs) > 0 {
+		var fields = copyFields(baseFields)
+		setList(fields, "ids", intArrayToStringArray(download.IDs))
+		setList(fields, "cve_id", download.CVEs)
+		requests = append(requests, fields)
+	} else {
+		for _, chunk := range download.chunks() {
+			var fields = copyFields(baseFields)
+			fields["id_min"] = strconv.Itoa(chunk.low)
+			if chunk.high > 0 {
+				fields["id_max"] = strconv.Itoa(chunk.high)
+			}
+			requests = append(requests, fields)
+		}
+	}
+
+	session.lstream.Send(log.Infof("Starting load of vulnerabilities from knowledge base in [%d] chunks", len(requests)))
+
+	var errLock sync.Mutex
+	var permits = make(chan bool, parallel)
+	var wg = &sync.WaitGroup{}
+
+	for _, fields := range requests {
+		select {
+		case <-ctx.Done():
+		case permits <- true:
+			wg.Add(1)
+			go func(fields map[string]string) {
+				defer handleRoutinePanic(session.lstream)
+				defer wg.Done()
+				defer func() { <-permits }()
+
+				if chunkErr := session.downloadKBChunk(ctx, fields, retries, serialPush); chunkErr != nil {
+					errLock.Lock()
+					if err == nil {
+						err = chunkErr
+					}
+					errLock.Unlock()
+
+					// the download has failed, so the remaining chunks are abandoned
+					cancel()
+				}
+			}(fields)
+		}
+	}
+
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	return err
+}
+
+// downloadKBChunk loads a single chunk, following any WARNING returned by Qualys, and retries the chunk from the QID after
+// the last vulnerability pushed when a request fails
+func (session *Session) downloadKBChunk(ctx context.Context, baseFields map[string]string, retries int, push func(vuln QVulnerability) (err error)) (err error) {
+	var path = session.Config.Address() + qsVulnerabilities
+	var fields = copyFields(baseFields)
+	var lastQID int
+
+	for attempt := 0; len(path) > 0; {
+		var warning *QWarning
+		if warning, err = session.streamVulnerabilities(ctx, path, fields, func(vuln QVulnerability) (err error) {
+			if err = push(vuln); err == nil {
+				lastQID = vuln.QualysID
+			}
+			return err
+		}); err == nil {
+			path = ""
+			if warning != nil && len(warning.URL) > 0 {
+				path, fields = nextPage(session.Config.Address()+qsVulnerabilities, baseFields, warning)
+			}
+		} else if attempt < retries && ctx.Err() == nil && retryableChunkError(err) {
+			attempt++
+
+			var wait = session.retry.backoff(attempt-1, Rates{})
+			session.lstream.Send(log.Warningf(err, "Knowledge base chunk [%s-%s] failed, retrying in %s (attempt %d of %d)", baseFields["id_min"], baseFields["id_max"], wait.Round(time.Second), attempt, retries))
+
+			var timer = time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+
+			// the chunk is resumed after the last vulnerability that was pushed as Qualys returns the QIDs in order
+			path = session.Config.Address() + qsVulnerabilities
+			fields = copyFields(baseFields)
+			if lastQID > 0 {
+				fields["id_min"] = strconv.Itoa(lastQID + 1)
+			}
+		} else {
+			err = fmt.Errorf("error while loading knowledge base chunk [%s-%s] - %w", baseFields["id_min"], baseFields["id_max"], err)
+			break
+		}
+	}
+
+	return err
+}
+
+// retryableChunkError determines whether a failed chunk should be retried. Errors caused by the request itself would fail
+// again, and rate limited requests have already been retried by makeRequest
+func retryableChunkError(err error) bool {
+	return !errors.Is(err, ErrAuthentication) && !errors.Is(err, ErrInvalidParameter) && !errors.Is(err, ErrRateLimited) && !errors.Is(err, ErrConcurrencyLimited)
+}
+
+// streamVulnerabilities executes a request against the knowledge base API and decodes the response as it is read from the
+// connection, passing each vulnerability to push as soon as its VULN element closes. The WARNING returned by Qualys when
+// there are more vulnerabilities to load is returned so the caller can request the next page
+func (session *Session) streamVulnerabilities(ctx context.Context, path string, fields map[string]string, push func(vuln QVulnerability) (err error)) (warning *QWarning, err error) {
+	err = session.streamElements(ctx, path, fields, func(decoder *xml.Decoder, element *xml.StartElement) (err error) {
+		switch element.Name.Local {
+		case "VULN":
+			var vuln QVulnerability
+			if err = decoder.DecodeElement(&vuln, element); err == nil {
+				err = push(vuln)
+			}
+		case "WARNING":
+			warning = &QWarning{}
+			err = decoder.DecodeElement(warning, element)
+		}
+
+		return err
+	})
+
+	return warning, err
+}
+
+// copyFields returns a copy of the parameters of a request
+func copyFields(fields map[string]string) (copied map[string]string) {
+	copied = make(map[string]string, len(fields))
+	for key, value := range fields {
+		copied[key] = value
+	}
+
+	return copied
+}
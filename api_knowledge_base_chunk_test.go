@@ -0,0 +1,188 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// vulnerabilityBody builds a knowledge base response holding the QIDs. A truncated body ends in the middle of a VULN
+// element, as it would when the connection drops while the response is read
+func vulnerabilityBody(truncated bool, qids ...int) string {
+	var body strings.Builder
+	body.WriteString("<KNOWLEDGE_BASE_VULN_LIST_OUTPUT><RESPONSE><VULN_LIST>")
+	for _, qid := range qids {
+		body.WriteString(fmt.Sprintf("<VULN><QID>%d</QID></VULN>", qid))
+	}
+
+	if truncated {
+		body.WriteString("<VULN><QI")
+	} else {
+		body.WriteString("</VULN_LIST></RESPONSE></KNOWLEDGE_BASE_VULN_LIST_OUTPUT>")
+	}
+
+	return body.String()
+}
+
+// chunkServer returns the bodies in order and records the QID range of every request it receives. The last body is
+// repeated once the bodies run out
+type chunkServer struct {
+	bodies []string
+
+	lock   sync.Mutex
+	ranges []string
+}
+
+func (server *chunkServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	_ = request.ParseForm()
+
+	server.lock.Lock()
+	var index = len(server.ranges)
+	server.ranges = append(server.ranges, request.PostForm.Get("id_min")+"-"+request.PostForm.Get("id_max"))
+	server.lock.Unlock()
+
+	if index >= len(server.bodies) {
+		index = len(server.bodies) - 1
+	}
+
+	_, _ = writer.Write([]byte(server.bodies[index]))
+}
+
+func (server *chunkServer) requested() []string {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	return append([]string(nil), server.ranges...)
+}
+
+func TestDownloadKBChunk(t *testing.T) {
+	tests := []struct {
+		name   string
+		bodies []string
+		qids   []int
+		ranges []string
+		err    error
+	}{
+		{
+			"single page",
+			[]string{vulnerabilityBody(false, 1, 2, 3)},
+			[]int{1, 2, 3},
+			[]string{"1-10"},
+			nil,
+		},
+		{
+			"resumed after the last pushed QID",
+			[]string{vulnerabilityBody(true, 1, 2), vulnerabilityBody(true, 4), vulnerabilityBody(false, 7, 9)},
+			[]int{1, 2, 4, 7, 9},
+			[]string{"1-10", "3-10", "5-10"},
+			nil,
+		},
+		{
+			"retried from the start when nothing was pushed",
+			[]string{vulnerabilityBody(true), vulnerabilityBody(false, 1)},
+			[]int{1},
+			[]string{"1-10", "1-10"},
+			nil,
+		},
+		{
+			"retries exhausted",
+			[]string{vulnerabilityBody(true, 1), vulnerabilityBody(true)},
+			[]int{1},
+			[]string{"1-10", "2-10", "2-10"},
+			fmt.Errorf("XML syntax error"),
+		},
+		{
+			"rejected parameters not retried",
+			[]string{simpleReturnBody(qsInvalidParameterValueCode, "invalid id_min")},
+			[]int{},
+			[]string{"1-10"},
+			ErrInvalidParameter,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var handler = &chunkServer{bodies: test.bodies}
+			var server = httptest.NewServer(handler)
+			defer server.Close()
+
+			var qids = make([]int, 0)
+			var err = newTestSession(server, qsVulnerabilities).downloadKBChunk(context.Background(), map[string]string{"id_min": "1", "id_max": "10"}, 2, func(vuln QVulnerability) error {
+				qids = append(qids, vuln.QualysID)
+				return nil
+			})
+
+			if test.err == nil && err != nil {
+				t.Fatalf("expected no error, got [%v]", err)
+			} else if test.err != nil && (err == nil || !(errors.Is(err, test.err) || strings.Contains(err.Error(), test.err.Error()))) {
+				t.Fatalf("expected the error [%v], got [%v]", test.err, err)
+			}
+
+			if !reflect.DeepEqual(qids, test.qids) {
+				t.Errorf("expected the QIDs %v to be pushed, got %v", test.qids, qids)
+			}
+
+			if ranges := handler.requested(); !reflect.DeepEqual(ranges, test.ranges) {
+				t.Errorf("expected the QID ranges %v to be requested, got %v", test.ranges, ranges)
+			}
+		})
+	}
+}
+
+func TestKBDownloadChunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		download KBDownload
+		chunks   []kbChunk
+	}{
+		{"even split", KBDownload{ChunkSize: 5, MaxQID: 10}, []kbChunk{{1, 5}, {6, 10}, {11, 0}}},
+		{"last chunk shortened", KBDownload{ChunkSize: 4, MaxQID: 10}, []kbChunk{{1, 4}, {5, 8}, {9, 10}, {11, 0}}},
+		{"defaults", KBDownload{}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var chunks = test.download.chunks()
+			if test.chunks == nil {
+				if len(chunks) != defaultKBMaxQID/defaultKBChunkSize+1 || chunks[len(chunks)-1] != (kbChunk{low: defaultKBMaxQID + 1}) {
+					t.Errorf("expected the default chunks, got [%d] chunks ending with %v", len(chunks), chunks[len(chunks)-1])
+				}
+			} else if !reflect.DeepEqual(chunks, test.chunks) {
+				t.Errorf("expected %v, got %v", test.chunks, chunks)
+			}
+		})
+	}
+}
+
+func TestDownloadVulnerabilities(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_ = request.ParseForm()
+
+		// every chunk holds the QID at the start of its range
+		var qid int
+		_, _ = fmt.Sscanf(request.PostForm.Get("id_min"), "%d", &qid)
+		_, _ = writer.Write([]byte(vulnerabilityBody(false, qid)))
+	}))
+	defer server.Close()
+
+	var qids = make(map[int]bool)
+	var err = newTestSession(server, qsVulnerabilities).DownloadVulnerabilities(context.Background(), KBDownload{ChunkSize: 3, MaxQID: 9}, func(vuln QVulnerability) error {
+		qids[vuln.QualysID] = true
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := map[int]bool{1: true, 4: true, 7: true, 10: true}; !reflect.DeepEqual(qids, expected) {
+		t.Errorf("expected a vulnerability from every chunk %v, got %v", expected, qids)
+	}
+}
@@ -1,6 +1,7 @@
 package qualys
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"net/http"
@@ -12,6 +13,11 @@ import (
 // CreateOptionProfile takes an option profile object outlining the configurable aspects of a scan, and creates that
 // in Qualys, and returns its ID
 func (session *Session) CreateOptionProfile(optionProfile *OptionProfiles) (optionProfileID string, err error) {
+	return session.CreateOptionProfileContext(session.ctx, optionProfile)
+}
+
+// CreateOptionProfileContext is CreateOptionProfile with a context that aborts the API calls when cancelled
+func (session *Session) CreateOptionProfileContext(ctx context.Context, optionProfile *OptionProfiles) (optionProfileID string, err error) {
 	var fields = make(map[string]string)
 	fields["action"] = "import"
 
@@ -44,7 +50,7 @@ func (session *Session) CreateOptionProfile(optionProfile *OptionProfiles) (opti
 	if err == nil {
 		var resp = &simpleReturn{}
 		var bodyString = string(body)
-		err = session.httpCall(http.MethodPost, session.Config.Address()+qsOptionProfile, fields, &bodyString, resp)
+		err = session.httpCall(ctx, http.MethodPost, session.Config.Address()+qsOptionProfile, fields, &bodyString, resp)
 		if err == nil {
 			for _, item := range resp.Response.Items {
 				if len(item.Key) > 0 {
@@ -64,17 +70,27 @@ func (session *Session) CreateOptionProfile(optionProfile *OptionProfiles) (opti
 
 // GetOptionProfile returns the option profile in Qualys corresponding to the ID in the argument
 func (session *Session) GetOptionProfile(optionProfileID int) (optionProfiles *OptionProfiles, err error) {
+	return session.GetOptionProfileContext(session.ctx, optionProfileID)
+}
+
+// GetOptionProfileContext is GetOptionProfile with a context that aborts the API calls when cancelled
+func (session *Session) GetOptionProfileContext(ctx context.Context, optionProfileID int) (optionProfiles *OptionProfiles, err error) {
 	var fields = make(map[string]string)
 	fields["action"] = "export"
 	fields["option_profile_id"] = strconv.Itoa(optionProfileID)
 	optionProfiles = &OptionProfiles{}
-	err = session.httpCall(http.MethodGet, session.Config.Address()+qsOptionProfile, fields, nil, &optionProfiles)
+	err = session.httpCall(ctx, http.MethodGet, session.Config.Address()+qsOptionProfile, fields, nil, &optionProfiles)
 
 	return optionProfiles, err
 }
 
 // CreateSearchList creates a search list in Qualys which specifies the vulnerabilities for Qualys to scan
 func (session *Session) CreateSearchList(qIDs []string, searchListFormatString string) (searchListID string, searchListTitle string, err error) {
+	return session.CreateSearchListContext(session.ctx, qIDs, searchListFormatString)
+}
+
+// CreateSearchListContext is CreateSearchList with a context that aborts the API calls when cancelled
+func (session *Session) CreateSearchListContext(ctx context.Context, qIDs []string, searchListFormatString string) (searchListID string, searchListTitle string, err error) {
 	const idKey = "id"
 
 	var fields = make(map[string]string)
@@ -88,7 +104,7 @@ func (session *Session) CreateSearchList(qIDs []string, searchListFormatString s
 	fields["qids"] = strings.Join(qIDs, ",")
 	var response = &simpleReturn{}
 
-	err = session.post(session.Config.Address()+qsSearchList, fields, response)
+	err = session.post(ctx, session.Config.Address()+qsSearchList, fields, response)
 	if err == nil {
 		for _, item := range response.Response.Items {
 			if strings.ToLower(item.Key) == idKey {
@@ -104,24 +120,39 @@ func (session *Session) CreateSearchList(qIDs []string, searchListFormatString s
 // DeleteSearchList calls the Qualys endpoint to delete a search list (which specifies the vulnerabilities to
 // be scanned by Qualys)
 func (session *Session) DeleteSearchList(searchListID string) (err error) {
+	return session.DeleteSearchListContext(session.ctx, searchListID)
+}
+
+// DeleteSearchListContext is DeleteSearchList with a context that aborts the API calls when cancelled
+func (session *Session) DeleteSearchListContext(ctx context.Context, searchListID string) (err error) {
 	var fields = make(map[string]string)
 	fields["action"] = "delete"
 	fields["id"] = searchListID
-	err = session.post(session.Config.Address()+qsSearchList, fields, nil)
+	err = session.post(ctx, session.Config.Address()+qsSearchList, fields, nil)
 	return err
 }
 
 // DeleteOptionProfile calls the Qualys endpoint to delete an option profile
 func (session *Session) DeleteOptionProfile(optionProfileID string) (err error) {
+	return session.DeleteOptionProfileContext(session.ctx, optionProfileID)
+}
+
+// DeleteOptionProfileContext is DeleteOptionProfile with a context that aborts the API calls when cancelled
+func (session *Session) DeleteOptionProfileContext(ctx context.Context, optionProfileID string) (err error) {
 	var fields = make(map[string]string)
 	fields["action"] = "delete"
 	fields["id"] = optionProfileID
-	err = session.post(session.Config.Address()+qsOptionProfileDelete, fields, nil)
+	err = session.post(ctx, session.Config.Address()+qsOptionProfileDelete, fields, nil)
 	return err
 }
 
 // GatherDeadHostsFoundSince returns a list of hosts that were found dead by a scan that started since a certain date
 func (session *Session) GatherDeadHostsFoundSince(since time.Time) (output *ScanSummaryOutput, err error) {
+	return session.GatherDeadHostsFoundSinceContext(session.ctx, since)
+}
+
+// GatherDeadHostsFoundSinceContext is GatherDeadHostsFoundSince with a context that aborts the API calls when cancelled
+func (session *Session) GatherDeadHostsFoundSinceContext(ctx context.Context, since time.Time) (output *ScanSummaryOutput, err error) {
 	if !since.IsZero() {
 		output = &ScanSummaryOutput{}
 
@@ -140,7 +171,7 @@ func (session *Session) GatherDeadHostsFoundSince(since time.Time) (output *Scan
 		fields["include_duplicate"] = "0"
 		fields["include_aborted"] = "0"
 
-		err = session.httpCall(http.MethodGet, session.Config.Address()+qsHostStatusFromScan, fields, nil, output)
+		err = session.httpCall(ctx, http.MethodGet, session.Config.Address()+qsHostStatusFromScan, fields, nil, output)
 	} else {
 		err = fmt.Errorf("invalid since date")
 	}
@@ -0,0 +1,391 @@
+package qualys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Operators of the criteria of a QPS REST API search
+const (
+	QPSEquals    = "EQUALS"
+	QPSNotEquals = "NOT EQUALS"
+	QPSGreater   = "GREATER"
+	QPSLesser    = "LESSER"
+	QPSIn        = "IN"
+	QPSContains  = "CONTAINS"
+)
+
+// QPSEncoding determines whether the body of a QPS REST API call is encoded as XML or JSON
+type QPSEncoding int
+
+const (
+	// QPSXML encodes the ServiceRequest and decodes the ServiceResponse as XML
+	QPSXML QPSEncoding = iota
+
+	// QPSJSON encodes the ServiceRequest and decodes the ServiceResponse as JSON
+	QPSJSON
+)
+
+// contentType returns the media type of the encoding
+func (encoding QPSEncoding) contentType() string {
+	if encoding == QPSJSON {
+		return "application/json"
+	}
+
+	return "application/xml"
+}
+
+// SearchCriteria is a single criteria of the filters of a QPS REST API search
+type SearchCriteria struct {
+	Text     string `xml:",chardata" json:"value"`
+	Field    string `xml:"field,attr" json:"field"`
+	Operator string `xml:"operator,attr" json:"operator"`
+}
+
+// NewCriteria builds the criteria of a search. Multiple values are joined with commas, which is how the IN operator
+// expects its values
+func NewCriteria(field string, operator string, values ...string) SearchCriteria {
+	return SearchCriteria{
+		Field:    field,
+		Operator: operator,
+		Text:     strings.Join(values, ","),
+	}
+}
+
+// CriteriaEquals matches the records where the field is equal to the value
+func CriteriaEquals(field string, value string) SearchCriteria {
+	return NewCriteria(field, QPSEquals, value)
+}
+
+// CriteriaIn matches the records where the field is equal to any of the values
+func CriteriaIn(field string, values ...string) SearchCriteria {
+	return NewCriteria(field, QPSIn, values...)
+}
+
+// CriteriaContains matches the records where the field contains the value
+func CriteriaContains(field string, value string) SearchCriteria {
+	return NewCriteria(field, QPSContains, value)
+}
+
+// CriteriaGreater matches the records where the field is greater than the value
+func CriteriaGreater(field string, value string) SearchCriteria {
+	return NewCriteria(field, QPSGreater, value)
+}
+
+// CriteriaLesser matches the records where the field is less than the value
+func CriteriaLesser(field string, value string) SearchCriteria {
+	return NewCriteria(field, QPSLesser, value)
+}
+
+// QPSPreferences are the preferences of a QPS REST API search which control the records returned in each page
+type QPSPreferences struct {
+	// LimitResults is the number of records returned per page, Qualys defaults to 100
+	LimitResults int `xml:"limitResults,omitempty" json:"limitResults,omitempty"`
+
+	// StartFromOffset is the 1-based position of the first record returned
+	StartFromOffset int `xml:"startFromOffset,omitempty" json:"startFromOffset,omitempty"`
+
+	// StartFromID is the ID of the first record returned
+	StartFromID string `xml:"startFromId,omitempty" json:"startFromId,omitempty"`
+
+	// Verbose returns every field of the records instead of a summary
+	Verbose bool `xml:"verbose,omitempty" json:"verbose,omitempty"`
+}
+
+// QPSFilters holds the criteria of a QPS REST API search, which are combined with AND
+type QPSFilters struct {
+	Criteria []SearchCriteria `xml:"Criteria" json:"Criteria"`
+}
+
+// QPSRequest is the ServiceRequest body of a QPS REST API call. Data holds the object being created or updated and must
+// encode to the element expected by the API, e.g. a struct with a HostAsset field
+type QPSRequest struct {
+	XMLName     xml.Name        `xml:"ServiceRequest" json:"-"`
+	Preferences *QPSPreferences `xml:"preferences,omitempty" json:"preferences,omitempty"`
+	Filters     *QPSFilters     `xml:"filters,omitempty" json:"filters,omitempty"`
+	Data        interface{}     `xml:"data,omitempty" json:"data,omitempty"`
+}
+
+// NewQPSRequest creates a search request for the criteria
+func NewQPSRequest(criteria ...SearchCriteria) (request *QPSRequest) {
+	request = &QPSRequest{
+		Preferences: &QPSPreferences{},
+	}
+
+	if len(criteria) > 0 {
+		request.Filters = &QPSFilters{
+			Criteria: criteria,
+		}
+	}
+
+	return request
+}
+
+// encode serializes the request in the encoding. JSON requests are wrapped in a ServiceRequest object
+func (request *QPSRequest) encode(encoding QPSEncoding) (body []byte, err error) {
+	if encoding == QPSJSON {
+		body, err = json.Marshal(struct {
+			ServiceRequest *QPSRequest `json:"ServiceRequest"`
+		}{request})
+	} else {
+		body, err = xml.Marshal(request)
+	}
+
+	return body, err
+}
+
+// copy returns a copy of the request whose preferences and criteria can be changed without affecting the original
+func (request *QPSRequest) copy() (copied *QPSRequest) {
+	copied = &QPSRequest{
+		Data: request.Data,
+	}
+
+	if request.Preferences != nil {
+		var preferences = *request.Preferences
+		copied.Preferences = &preferences
+	}
+
+	if request.Filters != nil {
+		copied.Filters = &QPSFilters{
+			Criteria: append([]SearchCriteria{}, request.Filters.Criteria...),
+		}
+	}
+
+	return copied
+}
+
+// QPSResponse describes the page of records returned by a QPS REST API call
+type QPSResponse struct {
+	ResponseCode   string
+	Count          int
+	HasMoreRecords bool
+	LastID         string
+}
+
+// qpsValue accepts both JSON strings and numbers, as the QPS REST API is inconsistent in how it encodes counts and IDs
+type qpsValue string
+
+// UnmarshalJSON stores the raw text of a number or the content of a string
+func (value *qpsValue) UnmarshalJSON(data []byte) (err error) {
+	var text string
+	if err = json.Unmarshal(data, &text); err != nil {
+		text, err = strings.TrimSpace(string(data)), nil
+	}
+
+	*value = qpsValue(text)
+	return err
+}
+
+// qpsXMLEnvelope is the XML ServiceResponse. The content of the data element is kept raw to be decoded into the object of
+// the caller
+type qpsXMLEnvelope struct {
+	XMLName        xml.Name `xml:"ServiceResponse"`
+	ResponseCode   string   `xml:"responseCode"`
+	Count          string   `xml:"count"`
+	HasMoreRecords string   `xml:"hasMoreRecords"`
+	LastID         string   `xml:"lastId"`
+	Data           struct {
+		Inner []byte `xml:",innerxml"`
+	} `xml:"data"`
+}
+
+// qpsJSONEnvelope is the JSON ServiceResponse. The data array is kept raw to be decoded into the object of the caller
+type qpsJSONEnvelope struct {
+	ServiceResponse struct {
+		ResponseCode   string          `json:"responseCode"`
+		Count          qpsValue        `json:"count"`
+		HasMoreRecords qpsValue        `json:"hasMoreRecords"`
+		LastID         qpsValue        `json:"lastId"`
+		Data           json.RawMessage `json:"data"`
+	} `json:"ServiceResponse"`
+}
+
+// QPSCall executes a call against the URL of a QPS REST API, such as the Config address followed by the path. The data of
+// the ServiceResponse is decoded into data, which for XML must be a struct holding the elements found within the data
+// element, e.g. a struct with a HostAsset slice, and for JSON must accept the data array. A responseCode other than
+// SUCCESS is returned as an APIError
+func (session *Session) QPSCall(ctx context.Context, method string, path string, request *QPSRequest, encoding QPSEncoding, data interface{}) (response QPSResponse, err error) {
+	var body []byte
+	if request != nil {
+		if body, err = request.encode(encoding); err != nil {
+			err = fmt.Errorf("error while encoding the QPS request for [%s] - %s", path, err.Error())
+		}
+	}
+
+	var httpRequest *http.Request
+	if err == nil {
+		httpRequest, err = http.NewRequestWithContext(ctx, method, path, bytes.NewReader(body))
+	}
+
+	if err == nil {
+		httpRequest.Header.Set("Content-Type", encoding.contentType())
+		httpRequest.Header.Set("Accept", encoding.contentType())
+
+		err = session.makeRequest(httpRequest, func(httpResponse *http.Response) (err error) {
+			defer httpResponse.Body.Close()
+
+			var raw []byte
+			if raw, err = ioutil.ReadAll(httpResponse.Body); err == nil {
+				if encoding == QPSJSON {
+					response, err = decodeQPSJSON(httpRequest, httpResponse, raw, data)
+				} else {
+					response, err = decodeQPSXML(httpRequest, httpResponse, raw, data)
+				}
+			}
+
+			return err
+		})
+	}
+
+	return response, err
+}
+
+// decodeQPSXML decodes an XML ServiceResponse, decoding the content of the data element into data
+func decodeQPSXML(request *http.Request, response *http.Response, raw []byte, data interface{}) (page QPSResponse, err error) {
+	var envelope qpsXMLEnvelope
+	if err = xml.Unmarshal(raw, &envelope); err == nil {
+		page = QPSResponse{
+			ResponseCode:   envelope.ResponseCode,
+			HasMoreRecords: strings.ToLower(envelope.HasMoreRecords) == "true",
+			LastID:         strings.TrimSpace(envelope.LastID),
+		}
+		page.Count, _ = strconv.Atoi(strings.TrimSpace(envelope.Count))
+
+		if err = serviceResponseError(request, response, raw); err == nil && data != nil {
+			// the content of the data element is wrapped again as the decoder requires a single root element
+			var inner = append(append([]byte("<data>"), envelope.Data.Inner...), []byte("</data>")...)
+			if err = xml.Unmarshal(inner, data); err != nil {
+				err = fmt.Errorf("error while decoding the data of the QPS response from [%s] - %s", request.URL.Path, err.Error())
+			}
+		}
+	} else {
+		err = newAPIError(request, response, raw)
+	}
+
+	return page, err
+}
+
+// decodeQPSJSON decodes a JSON ServiceResponse, decoding the data array into data
+func decodeQPSJSON(request *http.Request, response *http.Response, raw []byte, data interface{}) (page QPSResponse, err error) {
+	var envelope qpsJSONEnvelope
+	if err = json.Unmarshal(raw, &envelope); err == nil {
+		var service = envelope.ServiceResponse
+		page = QPSResponse{
+			ResponseCode:   service.ResponseCode,
+			HasMoreRecords: strings.ToLower(string(service.HasMoreRecords)) == "true",
+			LastID:         string(service.LastID),
+		}
+		page.Count, _ = strconv.Atoi(string(service.Count))
+
+		if len(service.ResponseCode) > 0 && strings.ToUpper(service.ResponseCode) != qpsSuccess {
+			err = newAPIError(request, response, raw)
+		} else if data != nil && len(service.Data) > 0 {
+			if err = json.Unmarshal(service.Data, data); err != nil {
+				err = fmt.Errorf("error while decoding the data of the QPS response from [%s] - %s", request.URL.Path, err.Error())
+			}
+		}
+	} else {
+		err = newAPIError(request, response, raw)
+	}
+
+	return page, err
+}
+
+// QPSPager pages through the records of a QPS REST API search. By default the pages are requested by offset, PageByID
+// switches to requesting the records with an ID greater than the last ID of the previous page, which does not skip or
+// repeat records when records are added or removed during the search
+//
+// The data passed to Next should be empty as decoding appends to the slices it holds
+//
+//	var pager = session.NewQPSPager(url, request, qualys.QPSXML)
+//	for page := (Page{}); pager.Next(ctx, &page); page = (Page{}) {
+//		...
+//	}
+//	err = pager.Err()
+type QPSPager struct {
+	session  *Session
+	path     string
+	request  *QPSRequest
+	encoding QPSEncoding
+
+	idField string
+	lastID  string
+	offset  int
+
+	done bool
+	err  error
+}
+
+// NewQPSPager creates a pager for the search request against the URL of a QPS REST API
+func (session *Session) NewQPSPager(path string, request *QPSRequest, encoding QPSEncoding) (pager *QPSPager) {
+	if request == nil {
+		request = NewQPSRequest()
+	}
+
+	pager = &QPSPager{
+		session:  session,
+		path:     path,
+		request:  request.copy(),
+		encoding: encoding,
+	}
+
+	if pager.request.Preferences == nil {
+		pager.request.Preferences = &QPSPreferences{}
+	}
+
+	pager.offset = pager.request.Preferences.StartFromOffset
+	if pager.offset < 1 {
+		pager.offset = 1
+	}
+
+	return pager
+}
+
+// PageByID pages through the records by adding a criteria on the ID field for each page instead of using an offset
+func (pager *QPSPager) PageByID(field string) *QPSPager {
+	pager.idField = field
+	return pager
+}
+
+// Next loads the next page of the search into data, returning false once every page has been loaded or an error occurred
+func (pager *QPSPager) Next(ctx context.Context, data interface{}) (loaded bool) {
+	if !pager.done && pager.err == nil {
+		var request = pager.request.copy()
+
+		if len(pager.idField) > 0 {
+			request.Preferences.StartFromOffset = 0
+			if len(pager.lastID) > 0 {
+				if request.Filters == nil {
+					request.Filters = &QPSFilters{}
+				}
+				request.Filters.Criteria = append(request.Filters.Criteria, CriteriaGreater(pager.idField, pager.lastID))
+			}
+		} else {
+			request.Preferences.StartFromOffset = pager.offset
+		}
+
+		var page QPSResponse
+		if page, pager.err = pager.session.QPSCall(ctx, http.MethodPost, pager.path, request, pager.encoding, data); pager.err == nil {
+			loaded = true
+			pager.offset += page.Count
+
+			// a page that doesn't move the search forward ends it, as requesting it again would loop forever
+			pager.done = !page.HasMoreRecords || page.Count == 0 || (len(pager.idField) > 0 && (len(page.LastID) == 0 || page.LastID == pager.lastID))
+			pager.lastID = page.LastID
+		}
+	}
+
+	return loaded
+}
+
+// Err returns the error that stopped the pager, or nil when every page was loaded
+func (pager *QPSPager) Err() error {
+	return pager.err
+}
@@ -0,0 +1,445 @@
+package qualys
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestQPSRequestEncode(t *testing.T) {
+	var search = NewQPSRequest(CriteriaEquals("name", "Production"), CriteriaIn("id", "1", "2"))
+	search.Preferences.LimitResults = 10
+	search.Preferences.Verbose = true
+
+	var asset struct {
+		HostAsset struct {
+			Name string `xml:"name" json:"name"`
+		} `xml:"HostAsset" json:"HostAsset"`
+	}
+	asset.HostAsset.Name = "web"
+
+	tests := []struct {
+		name     string
+		request  *QPSRequest
+		encoding QPSEncoding
+		expected string
+	}{
+		{
+			"search XML",
+			search,
+			QPSXML,
+			`<ServiceRequest><preferences><limitResults>10</limitResults><verbose>true</verbose></preferences><filters><Criteria field="name" operator="EQUALS">Production</Criteria><Criteria field="id" operator="IN">1,2</Criteria></filters></ServiceRequest>`,
+		},
+		{
+			"search JSON",
+			search,
+			QPSJSON,
+			`{"ServiceRequest":{"preferences":{"limitResults":10,"verbose":true},"filters":{"Criteria":[{"value":"Production","field":"name","operator":"EQUALS"},{"value":"1,2","field":"id","operator":"IN"}]}}}`,
+		},
+		{"no criteria XML", NewQPSRequest(), QPSXML, `<ServiceRequest><preferences></preferences></ServiceRequest>`},
+		{"no criteria JSON", NewQPSRequest(), QPSJSON, `{"ServiceRequest":{"preferences":{}}}`},
+		{"data XML", &QPSRequest{Data: asset}, QPSXML, `<ServiceRequest><data><HostAsset><name>web</name></HostAsset></data></ServiceRequest>`},
+		{"data JSON", &QPSRequest{Data: asset}, QPSJSON, `{"ServiceRequest":{"data":{"HostAsset":{"name":"web"}}}}`},
+		{
+			"offset and ID preferences",
+			&QPSRequest{Preferences: &QPSPreferences{StartFromOffset: 101, StartFromID: "42"}},
+			QPSXML,
+			`<ServiceRequest><preferences><startFromOffset>101</startFromOffset><startFromId>42</startFromId></preferences></ServiceRequest>`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, err := test.request.encode(test.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != test.expected {
+				t.Errorf("expected [%s], got [%s]", test.expected, body)
+			}
+		})
+	}
+}
+
+func TestQPSCriteria(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria SearchCriteria
+		expected SearchCriteria
+	}{
+		{"equals", CriteriaEquals("name", "web"), SearchCriteria{Field: "name", Operator: QPSEquals, Text: "web"}},
+		{"in", CriteriaIn("id", "1", "2", "3"), SearchCriteria{Field: "id", Operator: QPSIn, Text: "1,2,3"}},
+		{"contains", CriteriaContains("name", "web"), SearchCriteria{Field: "name", Operator: QPSContains, Text: "web"}},
+		{"greater", CriteriaGreater("id", "5"), SearchCriteria{Field: "id", Operator: QPSGreater, Text: "5"}},
+		{"lesser", CriteriaLesser("id", "5"), SearchCriteria{Field: "id", Operator: QPSLesser, Text: "5"}},
+		{"not equals", NewCriteria("name", QPSNotEquals, "web"), SearchCriteria{Field: "name", Operator: QPSNotEquals, Text: "web"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.criteria != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, test.criteria)
+			}
+		})
+	}
+}
+
+func TestQPSRequestCopy(t *testing.T) {
+	var request = NewQPSRequest(CriteriaEquals("name", "web"))
+	var copied = request.copy()
+
+	copied.Preferences.LimitResults = 5
+	copied.Filters.Criteria = append(copied.Filters.Criteria, CriteriaGreater("id", "1"))
+	copied.Filters.Criteria[0].Text = "db"
+
+	if request.Preferences.LimitResults != 0 || len(request.Filters.Criteria) != 1 || request.Filters.Criteria[0].Text != "web" {
+		t.Errorf("expected the original request to be unchanged, got %+v %+v", request.Preferences, request.Filters)
+	}
+}
+
+// qpsAssets is the data of a ServiceResponse holding host assets
+type qpsAssets struct {
+	Assets []struct {
+		ID   int    `xml:"id" json:"id"`
+		Name string `xml:"name" json:"name"`
+	} `xml:"HostAsset"`
+}
+
+// qpsJSONAssets is the data array of a JSON ServiceResponse holding host assets
+type qpsJSONAssets []struct {
+	HostAsset struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"HostAsset"`
+}
+
+func TestDecodeQPS(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding QPSEncoding
+		body     string
+		page     QPSResponse
+		names    []string
+		code     string
+		err      error
+	}{
+		{
+			"XML",
+			QPSXML,
+			`<ServiceResponse><responseCode>SUCCESS</responseCode><count>2</count><hasMoreRecords>true</hasMoreRecords><lastId>8</lastId><data><HostAsset><id>7</id><name>web</name></HostAsset><HostAsset><id>8</id><name>db</name></HostAsset></data></ServiceResponse>`,
+			QPSResponse{ResponseCode: "SUCCESS", Count: 2, HasMoreRecords: true, LastID: "8"},
+			[]string{"web", "db"},
+			"", nil,
+		},
+		{
+			"XML without data",
+			QPSXML,
+			`<ServiceResponse><responseCode>SUCCESS</responseCode><count>0</count><hasMoreRecords>false</hasMoreRecords></ServiceResponse>`,
+			QPSResponse{ResponseCode: "SUCCESS"},
+			nil,
+			"", nil,
+		},
+		{
+			"XML failure",
+			QPSXML,
+			`<ServiceResponse><responseCode>NOT_FOUND</responseCode><responseErrorDetails><errorMessage>no such asset</errorMessage></responseErrorDetails></ServiceResponse>`,
+			QPSResponse{ResponseCode: "NOT_FOUND"},
+			nil,
+			qpsNotFound, ErrNotFound,
+		},
+		{
+			"JSON with string values",
+			QPSJSON,
+			`{"ServiceResponse":{"responseCode":"SUCCESS","count":"1","hasMoreRecords":"false","lastId":"7","data":[{"HostAsset":{"id":7,"name":"web"}}]}}`,
+			QPSResponse{ResponseCode: "SUCCESS", Count: 1, LastID: "7"},
+			[]string{"web"},
+			"", nil,
+		},
+		{
+			"JSON with number values",
+			QPSJSON,
+			`{"ServiceResponse":{"responseCode":"SUCCESS","count":2,"hasMoreRecords":true,"lastId":8,"data":[{"HostAsset":{"id":7,"name":"web"}},{"HostAsset":{"id":8,"name":"db"}}]}}`,
+			QPSResponse{ResponseCode: "SUCCESS", Count: 2, HasMoreRecords: true, LastID: "8"},
+			[]string{"web", "db"},
+			"", nil,
+		},
+		{
+			"JSON failure",
+			QPSJSON,
+			`{"ServiceResponse":{"responseCode":"INVALID_REQUEST","responseErrorDetails":{"errorMessage":"bad criteria"}}}`,
+			QPSResponse{ResponseCode: "INVALID_REQUEST"},
+			nil,
+			qpsInvalidRequest, ErrInvalidParameter,
+		},
+		{
+			"JSON insufficient privileges",
+			QPSJSON,
+			`{"ServiceResponse":{"responseCode":"INSUFFICIENT_PRIVILEGES"}}`,
+			QPSResponse{ResponseCode: "INSUFFICIENT_PRIVILEGES"},
+			nil,
+			qpsInsufficientPrivileges, ErrAuthentication,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var request = httptest.NewRequest(http.MethodPost, "https://qualysapi.qualys.com/qps/rest/2.0/search/am/hostasset", nil)
+			var response = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+
+			var page QPSResponse
+			var names []string
+			var err error
+			if test.encoding == QPSJSON {
+				var data qpsJSONAssets
+				if page, err = decodeQPSJSON(request, response, []byte(test.body), &data); err == nil {
+					for _, asset := range data {
+						names = append(names, asset.HostAsset.Name)
+					}
+				}
+			} else {
+				var data qpsAssets
+				if page, err = decodeQPSXML(request, response, []byte(test.body), &data); err == nil {
+					for _, asset := range data.Assets {
+						names = append(names, asset.Name)
+					}
+				}
+			}
+
+			if test.err == nil && err != nil {
+				t.Fatal(err)
+			} else if test.err != nil {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.ResponseCode != test.code || !errors.Is(err, test.err) {
+					t.Fatalf("expected an APIError with the responseCode [%s] matching [%v], got [%v]", test.code, test.err, err)
+				}
+			}
+
+			if page != test.page {
+				t.Errorf("expected the page %+v, got %+v", test.page, page)
+			}
+
+			if !reflect.DeepEqual(names, test.names) {
+				t.Errorf("expected the assets %v, got %v", test.names, names)
+			}
+		})
+	}
+}
+
+func TestDecodeQPSInvalidBody(t *testing.T) {
+	var request = httptest.NewRequest(http.MethodPost, "https://qualysapi.qualys.com/qps/rest/2.0/search/am/hostasset", nil)
+	var response = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+
+	if _, err := decodeQPSXML(request, response, []byte("not xml"), &qpsAssets{}); err == nil {
+		t.Error("expected an XML body that doesn't parse to fail")
+	}
+
+	if _, err := decodeQPSJSON(request, response, []byte("not json"), &qpsJSONAssets{}); err == nil {
+		t.Error("expected a JSON body that doesn't parse to fail")
+	}
+}
+
+const qpsSearchPath = "/qps/rest/2.0/search/am/hostasset"
+
+// qpsSearchServer serves the host assets with the IDs 1 through records in pages of pageSize, by offset or by the id
+// criteria of the request, and records the request of each page. The page starting at failAt is rejected when it is set
+type qpsSearchServer struct {
+	records  int
+	pageSize int
+	failAt   int
+
+	lock     sync.Mutex
+	requests []qpsSearchRequest
+}
+
+// qpsSearchRequest is the part of a ServiceRequest read by the search server
+type qpsSearchRequest struct {
+	Offset   int              `xml:"preferences>startFromOffset"`
+	Criteria []SearchCriteria `xml:"filters>Criteria"`
+}
+
+func (server *qpsSearchServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var search qpsSearchRequest
+	data, _ := ioutil.ReadAll(request.Body)
+	_ = xml.Unmarshal(data, &search)
+
+	server.lock.Lock()
+	server.requests = append(server.requests, search)
+	server.lock.Unlock()
+
+	var first = 1
+	if search.Offset > 0 {
+		first = search.Offset
+	}
+
+	for _, criteria := range search.Criteria {
+		if criteria.Field == "id" && criteria.Operator == QPSGreater {
+			first, _ = strconv.Atoi(criteria.Text)
+			first++
+		}
+	}
+
+	if server.failAt > 0 && first >= server.failAt {
+		_, _ = writer.Write([]byte("<ServiceResponse><responseCode>INVALID_REQUEST</responseCode></ServiceResponse>"))
+		return
+	}
+
+	var body strings.Builder
+	var last int
+	for id := first; id <= server.records && id < first+server.pageSize; id++ {
+		fmt.Fprintf(&body, "<HostAsset><id>%d</id><name>asset %d</name></HostAsset>", id, id)
+		last = id
+	}
+
+	var count = 0
+	if last > 0 {
+		count = last - first + 1
+	}
+
+	_, _ = fmt.Fprintf(writer, "<ServiceResponse><responseCode>SUCCESS</responseCode><count>%d</count><hasMoreRecords>%t</hasMoreRecords><lastId>%d</lastId><data>%s</data></ServiceResponse>",
+		count, last < server.records && last > 0, last, body.String())
+}
+
+func (server *qpsSearchServer) received() []qpsSearchRequest {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	return append([]qpsSearchRequest(nil), server.requests...)
+}
+
+func TestQPSPager(t *testing.T) {
+	tests := []struct {
+		name    string
+		byID    bool
+		failAt  int
+		ids     []int
+		offsets []int
+		lastIDs []string
+		err     error
+	}{
+		{"by offset", false, 0, []int{1, 2, 3, 4, 5}, []int{1, 3, 5}, []string{"", "", ""}, nil},
+		{"by ID", true, 0, []int{1, 2, 3, 4, 5}, []int{0, 0, 0}, []string{"", "2", "4"}, nil},
+		{"stopped on error", false, 3, []int{1, 2}, []int{1, 3}, []string{"", ""}, ErrInvalidParameter},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var handler = &qpsSearchServer{records: 5, pageSize: 2, failAt: test.failAt}
+			var server = httptest.NewServer(handler)
+			defer server.Close()
+
+			var pager = newTestSession(server, qpsSearchPath).NewQPSPager(server.URL+qpsSearchPath, NewQPSRequest(), QPSXML)
+			if test.byID {
+				pager.PageByID("id")
+			}
+
+			var ids []int
+			for page := (qpsAssets{}); pager.Next(context.Background(), &page); page = (qpsAssets{}) {
+				for _, asset := range page.Assets {
+					ids = append(ids, asset.ID)
+				}
+			}
+
+			if test.err == nil && pager.Err() != nil {
+				t.Fatal(pager.Err())
+			} else if test.err != nil && !errors.Is(pager.Err(), test.err) {
+				t.Fatalf("expected the pager to stop with [%v], got [%v]", test.err, pager.Err())
+			}
+
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Errorf("expected the assets %v, got %v", test.ids, ids)
+			}
+
+			var offsets []int
+			var lastIDs []string
+			for _, request := range handler.received() {
+				offsets = append(offsets, request.Offset)
+
+				var lastID string
+				for _, criteria := range request.Criteria {
+					if criteria.Operator == QPSGreater {
+						lastID = criteria.Text
+					}
+				}
+				lastIDs = append(lastIDs, lastID)
+			}
+
+			if !reflect.DeepEqual(offsets, test.offsets) || !reflect.DeepEqual(lastIDs, test.lastIDs) {
+				t.Errorf("expected the offsets %v and last IDs %v to be requested, got %v and %v", test.offsets, test.lastIDs, offsets, lastIDs)
+			}
+
+			// a stopped pager doesn't request any more pages
+			if pager.Next(context.Background(), &qpsAssets{}) || len(handler.received()) != len(test.offsets) {
+				t.Errorf("expected the pager to stay stopped")
+			}
+		})
+	}
+}
+
+func TestQPSPagerCancelled(t *testing.T) {
+	var handler = &qpsSearchServer{records: 5, pageSize: 2}
+	var server = httptest.NewServer(handler)
+	defer server.Close()
+
+	var pager = newTestSession(server, qpsSearchPath).NewQPSPager(server.URL+qpsSearchPath, NewQPSRequest(), QPSXML)
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	if !pager.Next(ctx, &qpsAssets{}) {
+		t.Fatalf("expected the first page to load, got [%v]", pager.Err())
+	}
+
+	cancel()
+	if pager.Next(ctx, &qpsAssets{}) {
+		t.Fatal("expected the pager to stop once its context is cancelled")
+	}
+
+	if !errors.Is(pager.Err(), context.Canceled) {
+		t.Errorf("expected the pager to report the cancellation, got [%v]", pager.Err())
+	}
+
+	if requests := handler.received(); len(requests) != 1 {
+		t.Errorf("expected no request once the context was cancelled, got [%d] requests", len(requests))
+	}
+}
+
+func TestQPSCallJSON(t *testing.T) {
+	var contentType, accept string
+	var sent map[string]interface{}
+	var server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		contentType, accept = request.Header.Get("Content-Type"), request.Header.Get("Accept")
+		data, _ := ioutil.ReadAll(request.Body)
+		_ = json.Unmarshal(data, &sent)
+
+		_, _ = writer.Write([]byte(`{"ServiceResponse":{"responseCode":"SUCCESS","count":1,"hasMoreRecords":false,"data":[{"HostAsset":{"id":7,"name":"web"}}]}}`))
+	}))
+	defer server.Close()
+
+	var data qpsJSONAssets
+	page, err := newTestSession(server, qpsSearchPath).QPSCall(context.Background(), http.MethodPost, server.URL+qpsSearchPath, NewQPSRequest(CriteriaEquals("name", "web")), QPSJSON, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contentType != "application/json" || accept != "application/json" {
+		t.Errorf("expected the JSON media type, got [%s] [%s]", contentType, accept)
+	}
+
+	if _, ok := sent["ServiceRequest"]; !ok {
+		t.Errorf("expected the request to be wrapped in a ServiceRequest, got %v", sent)
+	}
+
+	if page.Count != 1 || len(data) != 1 || data[0].HostAsset.ID != 7 {
+		t.Errorf("expected the asset of the response, got %+v %+v", page, data)
+	}
+}
@@ -1,16 +1,18 @@
 package qualys
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/nortonlifelock/domain"
 	"github.com/nortonlifelock/log"
 	"github.com/pkg/errors"
 )
@@ -47,75 +49,219 @@ type Rates struct {
 	CRunning int
 }
 
+// RateLimitError is returned once a request has been rejected by the Qualys rate or concurrency limits more times than the
+// retry budget of the session allows. It matches ErrRateLimited, or ErrConcurrencyLimited when the concurrency limit was
+// hit, through errors.Is and unwraps to the APIError of the last rejected response
+type RateLimitError struct {
+	APIError
+
+	// Rates holds the rate limit headers of the last rejected response
+	Rates Rates
+
+	// Attempts is the number of times the request was sent before giving up
+	Attempts int
+}
+
+func (err *RateLimitError) Error() string {
+	return fmt.Sprintf("%s rate limit exceeded after %d attempts", err.APIError.Error(), err.Attempts)
+}
+
+// Is matches ErrConcurrencyLimited when the request was rejected by the concurrency limit and ErrRateLimited otherwise
+func (err *RateLimitError) Is(target error) bool {
+	if err.concurrencyLimited() {
+		return target == ErrConcurrencyLimited
+	}
+
+	return target == ErrRateLimited
+}
+
+// Unwrap returns the APIError of the last rejected response
+func (err *RateLimitError) Unwrap() error {
+	return &err.APIError
+}
+
+// concurrencyLimited determines whether the request was rejected due to the number of API calls running concurrently
+// rather than the number of API calls made within the rate limit window
+func (err *RateLimitError) concurrencyLimited() bool {
+	return err.Code == qsConcurrencyLimitCode || err.Code == qsRequestLockedCode || (err.Rates.CLimit > 0 && err.Rates.CRunning >= err.Rates.CLimit)
+}
+
+// newRateLimitError returns a RateLimitError when the response shows that the request was rejected by the Qualys rate or
+// concurrency limits, otherwise a nil error is returned
+func newRateLimitError(apiErr *APIError, rates Rates) (err *RateLimitError) {
+	if apiErr.Status == http.StatusConflict || apiErr.Status == http.StatusTooManyRequests {
+		var limited = apiErr.Status == http.StatusTooManyRequests || rates.RLToWaitSec > 0
+		switch apiErr.Code {
+		case qsRateLimitCode, qsConcurrencyLimitCode, qsRequestLockedCode:
+			limited = true
+		}
+
+		if limited {
+			err = &RateLimitError{
+				APIError: *apiErr,
+				Rates:    rates,
+			}
+		}
+	}
+
+	return err
+}
+
+// backoff calculates how long to wait before retrying a request that was rejected by the rate limits. The delay grows
+// exponentially with each attempt and is jittered so concurrent requests don't retry in lockstep. The wait advertised by
+// Qualys in the response headers is always honored
+func (policy retryPolicy) backoff(attempt int, rates Rates) (delay time.Duration) {
+	delay = policy.maxDelay
+	if attempt < 32 {
+		if exponential := policy.baseDelay << uint(attempt); exponential > 0 && exponential < policy.maxDelay {
+			delay = exponential
+		}
+	}
+
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	if serverWait := time.Duration(rates.RLToWaitSec) * time.Second; serverWait > delay {
+		delay = serverWait
+	}
+
+	return delay
+}
+
 // makeRequest creates a http request to the Qualys API while also taking into account the rate limiting implementation
-// by Qualys so that the requesting methods don't crash immediately
+// by Qualys so that the requesting methods don't crash immediately. Requests rejected by the rate or concurrency limits
+// are retried after backing off until the retry budget of the session is spent. The context of the request is checked
+// before each attempt so a cancelled caller stops the loop instead of continuing to hit the API
 func (session *Session) makeRequest(request *http.Request, action func(response *http.Response) (err error)) (err error) {
 
 	var status int
-	var timeout int
+	var attempt int
+	var reauthenticated bool
 
 	// Loop over the request until the status is 200 or there is an error returned from the loop
 	for status != 200 && err == nil {
 
-		// Ensure the timeout hasn't been met before making the request, otherwise break the loop
-		if timeout < 500 {
+		if err = request.Context().Err(); err != nil {
+			break
+		}
+
+		// Set the authentication information and required Qualys Headers
+		var cookie *http.Cookie
+		if cookie, err = session.authorize(request); err == nil {
+			request.Header.Set("X-Requested-With", "Aegis")
+			if len(request.Header.Get("Content-Type")) == 0 {
+				request.Header.Set("Content-Type", "application/xml")
+			}
 
-			var authInfo domain.BasicAuth
-			if err = json.Unmarshal([]byte(session.Config.AuthInfo()), &authInfo); err == nil {
-				// Set the basic auth information and required Qualys Headers
-				request.SetBasicAuth(authInfo.Username, authInfo.Password)
-				request.Header.Add("X-Requested-With", "Aegis")
-				request.Header.Add("Content-Type", "application/xml")
+			// Execute the HTTP request against the Qualys API
+			// the funnel reports non-2xx responses as errors, but still returns the response so that the status can be handled here
+			var response *http.Response
+			if response, err = session.getFunnelForEndpoint(request.URL.Host + request.URL.Path).Do(request); response != nil {
+				err = nil
+			}
 
-				// Execute the HTTP request against the Qualys API
-				var response *http.Response
-				if response, err = session.getFunnelForEndpoint(request.URL.Path).Do(request); err == nil {
+			if cookie != nil && !reauthenticated && sessionExpired(response) {
+				// The Qualys session has expired, so we log in again and retry the request with the new cookie
+				reauthenticated = true
+				_ = response.Body.Close()
+				session.auth.expire(cookie)
+				err = rewindRequestBody(request)
+			} else if err == nil {
 
-					// Ensure that the response from the API was not NIL
-					if response != nil {
+				// Ensure that the response from the API was not NIL
+				if response != nil {
 
-						// Pull the response headers and validate that the rate limits have not been exceeded
-						var rates Rates
-						if status, rates, err = session.pullResponseHeaders(response); err == nil {
-							err = session.processResponse(status, action, response, request, rates, timeout)
-						} else {
-							err = errors.Errorf("Error occurred when pulling rate limit information from Qualys response header [Endpoint: %s | Error: %s]", request.URL, err.Error())
+					// Pull the response headers and validate that the rate limits have not been exceeded
+					var rates Rates
+					if status, rates, err = session.pullResponseHeaders(response); err == nil {
+						err = session.processResponse(status, action, response, request, rates)
+
+						if limitErr, ok := err.(*RateLimitError); ok {
+							attempt++
+							limitErr.Attempts = attempt
+
+							if attempt <= session.retry.attempts {
+								var wait = session.retry.backoff(attempt-1, rates)
+								session.lstream.Send(log.Warningf(limitErr, "Qualys limit hit for API [%s], retrying in %s (attempt %d of %d)", request.URL.Path, wait.Round(time.Second), attempt, session.retry.attempts))
+
+								var timer = time.NewTimer(wait)
+								select {
+								case <-request.Context().Done():
+									timer.Stop()
+									err = request.Context().Err()
+								case <-timer.C:
+									err = rewindRequestBody(request)
+								}
+							}
 						}
 					} else {
-						err = errors.Errorf("No Response from Qualys API [Endpoint: %s]", request.URL)
+						err = errors.Errorf("Error occurred when pulling rate limit information from Qualys response header [Endpoint: %s | Error: %s]", request.URL, err.Error())
 					}
 				} else {
-					err = errors.Errorf("Error when making API Request to Qualys [Endpoint: %s | Error: %s]", request.URL, err.Error())
+					err = errors.Errorf("No Response from Qualys API [Endpoint: %s]", request.URL)
 				}
 			} else {
-				err = fmt.Errorf("error while parsing authentication information - %s", err.Error())
+				err = errors.Errorf("Error when making API Request to Qualys [Endpoint: %s | Error: %s]", request.URL, err.Error())
 			}
-
 		} else {
-			session.lstream.Send(log.Warningf(err, "Qualys Timeout Reached for API [%s]", request.URL))
-			break
+			err = fmt.Errorf("error while authenticating against Qualys - %w", err)
 		}
 	}
 
 	return err
 }
 
-func (session *Session) processResponse(status int, action func(response *http.Response) (err error), response *http.Response, request *http.Request, rates Rates, timeout int) (err error) {
+// sessionExpired determines whether Qualys rejected the session cookie of the request, either through a 401 or through
+// the session expired and session not found codes of the SIMPLE_RETURN. The body of the response is buffered so that it
+// can still be read by the caller
+func sessionExpired(response *http.Response) (expired bool) {
+	if response != nil && (response.StatusCode < 200 || response.StatusCode > 299) {
+		expired = response.StatusCode == http.StatusUnauthorized
+
+		if !expired {
+			data, _ := ioutil.ReadAll(response.Body)
+			_ = response.Body.Close()
+			response.Body = &rewindingBody{Reader: bytes.NewReader(data)}
+
+			var retResponse simpleReturn
+			if xml.Unmarshal(data, &retResponse) == nil {
+				expired = retResponse.Response.Code == qsSessionExpiredCode || retResponse.Response.Code == qsSessionNotFoundCode
+			}
+		}
+	}
+
+	return expired
+}
+
+// rewindRequestBody resets the body of a request that has already been sent so that it can be sent again
+func rewindRequestBody(request *http.Request) (err error) {
+	if request.GetBody != nil {
+		request.Body, err = request.GetBody()
+	}
+
+	return err
+}
+
+func (session *Session) processResponse(status int, action func(response *http.Response) (err error), response *http.Response, request *http.Request, rates Rates) (err error) {
 	// If the status is 200 then the API request went through correctly
 	switch status {
 	case 200:
 		// If the action function literal was passed to the makeRequest method then execute the action method
 		if action != nil {
 			err = action(response)
-			_ = response.Body.Close()
 		}
-	default: // A non-rate limiting error was returned from the API, handle it appropriately
+		_ = response.Body.Close()
+	default:
 		var data []byte
 		defer response.Body.Close()
-		data, err = ioutil.ReadAll(response.Body)
+		data, _ = ioutil.ReadAll(response.Body)
 
-		// Error code that we're not monitoring
-		err = errors.Errorf("Qualys API [%s] Error Returned [Status: %v | Error: %s]", request.URL, status, string(data))
+		var apiErr = newAPIError(request, response, data)
+		if limitErr := newRateLimitError(apiErr, rates); limitErr != nil {
+			err = limitErr
+		} else {
+			// A non-rate limiting error was returned from the API
+			err = apiErr
+		}
 	}
 	return err
 }
@@ -170,6 +316,14 @@ func (session *Session) pullResponseHeaders(response *http.Response) (status int
 				}
 			}
 		}
+
+		// a Retry-After given in seconds is honored as the wait before the next call when it is longer than the wait
+		// advertised through the rate limit headers
+		if retryAfter := response.Header.Get("Retry-After"); err == nil && len(retryAfter) > 0 {
+			if wait, convErr := strconv.Atoi(strings.TrimSpace(retryAfter)); convErr == nil && wait > rates.RLToWaitSec {
+				rates.RLToWaitSec = wait
+			}
+		}
 	}
 
 	return status, rates, err
@@ -180,13 +334,13 @@ func (session *Session) pullResponseHeaders(response *http.Response) (status int
 //----------------------------------------------------------------
 
 // Execute a POST call against the Qualys API
-func (session *Session) post(path string, fields map[string]string, obj interface{}) (err error) {
-	err = session.httpCall(http.MethodPost, path, fields, nil, obj)
+func (session *Session) post(ctx context.Context, path string, fields map[string]string, obj interface{}) (err error) {
+	err = session.httpCall(ctx, http.MethodPost, path, fields, nil, obj)
 	return err
 }
 
 // Execute a POST call against the Qualys API that contains a binary
-func (session *Session) httpCall(method string, path string, fields map[string]string, in *string, obj interface{}) (err error) {
+func (session *Session) httpCall(ctx context.Context, method string, path string, fields map[string]string, in *string, obj interface{}) (err error) {
 
 	var qstring string
 	if qstring, err = mapToQueryString(path, fields); err == nil {
@@ -199,7 +353,7 @@ func (session *Session) httpCall(method string, path string, fields map[string]s
 		}
 
 		var request *http.Request
-		if request, err = http.NewRequest(method, fmt.Sprintf("%s%s", path, qstring), reader); err == nil {
+		if request, err = http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", path, qstring), reader); err == nil {
 
 			err = session.makeRequest(request, func(response *http.Response) (err error) {
 				if response != nil {
@@ -207,25 +361,27 @@ func (session *Session) httpCall(method string, path string, fields map[string]s
 
 					var data []byte
 					if data, err = ioutil.ReadAll(response.Body); err == nil {
-						//fmt.Println(string(data))
 						if retResponse, ok := obj.(*simpleReturn); ok {
 
 							if err = xml.Unmarshal(data, &retResponse); err == nil {
 
 								if retResponse.Response.Code > 0 {
-									err = fmt.Errorf("error While Accessing Qualys. Error [%v]: %s | URL [%s] | DATA [%s]", retResponse.Response.Code, retResponse.Response.Message, path, string(data))
+									err = newAPIError(request, response, data)
 								}
 							}
 						} else {
 
-							if err = xml.Unmarshal(data, obj); err != nil {
+							if err = xml.Unmarshal(data, obj); err == nil {
+								// QPS REST API calls return a 200 along with the failure in the responseCode of the ServiceResponse
+								err = serviceResponseError(request, response, data)
+							} else {
 								var e1 = err
 
 								var retResponse simpleReturn
 								if err = xml.Unmarshal(data, &retResponse); err == nil {
 
 									if retResponse.Response.Code > 0 {
-										err = fmt.Errorf("error While Accessing Qualys: [%s] Error [%v]: %s |  URL [%s] | DATA [%s]", e1, retResponse.Response.Code, retResponse.Response.Message, path, string(data))
+										err = newAPIError(request, response, data)
 									}
 								} else {
 									err = fmt.Errorf("error While Accessing Qualys: [%s] Error attempting to unmarshal simple return. URL [%s] | DATA [%s]", e1, path, string(data))
@@ -0,0 +1,252 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// testResponse is a response returned by a test server
+type testResponse struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+// testServer returns the responses in order and records the body of every request it receives. The last response is
+// repeated once the responses run out
+type testServer struct {
+	responses []testResponse
+
+	lock   sync.Mutex
+	bodies []string
+}
+
+func (server *testServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	data, _ := ioutil.ReadAll(request.Body)
+
+	server.lock.Lock()
+	var index = len(server.bodies)
+	server.bodies = append(server.bodies, string(data))
+	server.lock.Unlock()
+
+	if index >= len(server.responses) {
+		index = len(server.responses) - 1
+	}
+
+	var response = server.responses[index]
+	for key, value := range response.headers {
+		writer.Header().Set(key, value)
+	}
+
+	writer.WriteHeader(response.status)
+	_, _ = writer.Write([]byte(response.body))
+}
+
+func (server *testServer) requests() []string {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	return append([]string(nil), server.bodies...)
+}
+
+func simpleReturnBody(code int, text string) string {
+	return fmt.Sprintf("<SIMPLE_RETURN><RESPONSE><DATETIME>2020-01-01T00:00:00Z</DATETIME><CODE>%d</CODE><TEXT>%s</TEXT></RESPONSE></SIMPLE_RETURN>", code, text)
+}
+
+func TestMakeRequest(t *testing.T) {
+	var ok = testResponse{status: http.StatusOK, body: "<OK/>"}
+	var rateLimited = testResponse{status: http.StatusConflict, body: simpleReturnBody(qsRateLimitCode, "rate limit exceeded")}
+	var concurrencyLimited = testResponse{
+		status:  http.StatusConflict,
+		headers: map[string]string{"X-Concurrency-Limit-Limit": "2", "X-Concurrency-Limit-Running": "2"},
+		body:    simpleReturnBody(qsConcurrencyLimitCode, "concurrency limit exceeded"),
+	}
+
+	tests := []struct {
+		name      string
+		responses []testResponse
+		attempts  int
+		requests  int
+		err       error
+	}{
+		{"success", []testResponse{ok}, 3, 1, nil},
+		{"rate limit retried", []testResponse{rateLimited, ok}, 3, 2, nil},
+		{"concurrency limit retried", []testResponse{concurrencyLimited, concurrencyLimited, ok}, 3, 3, nil},
+		{"request locked retried", []testResponse{{status: http.StatusConflict, body: simpleReturnBody(qsRequestLockedCode, "locked")}, ok}, 3, 2, nil},
+		{"429 retried", []testResponse{{status: http.StatusTooManyRequests}, ok}, 3, 2, nil},
+		{"rate limit budget exhausted", []testResponse{rateLimited}, 2, 3, ErrRateLimited},
+		{"concurrency limit budget exhausted", []testResponse{concurrencyLimited}, 2, 3, ErrConcurrencyLimited},
+		{"retries disabled", []testResponse{rateLimited}, 0, 1, ErrRateLimited},
+		{"409 without a limit not retried", []testResponse{{status: http.StatusConflict, body: simpleReturnBody(999, "conflict")}}, 3, 1, nil},
+		{"invalid parameter not retried", []testResponse{{status: http.StatusBadRequest, body: simpleReturnBody(qsInvalidParameterCode, "bad parameter")}}, 3, 1, ErrInvalidParameter},
+		{"unauthorized not retried", []testResponse{{status: http.StatusUnauthorized, body: simpleReturnBody(qsBadLoginCode, "bad login")}}, 3, 1, ErrAuthentication},
+		{"not found not retried", []testResponse{{status: http.StatusNotFound}}, 3, 1, ErrNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var handler = &testServer{responses: test.responses}
+			var server = httptest.NewServer(handler)
+			defer server.Close()
+
+			var session = newTestSession(server, qsVulnerabilities)
+			session.retry.attempts = test.attempts
+
+			request, err := http.NewRequest(http.MethodPost, server.URL+qsVulnerabilities, strings.NewReader("action=list"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = session.makeRequest(request, nil)
+
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected an error matching [%v], got [%v]", test.err, err)
+				}
+			} else if test.responses[len(test.responses)-1].status == http.StatusOK && err != nil {
+				t.Fatalf("expected no error, got [%v]", err)
+			} else if test.responses[len(test.responses)-1].status != http.StatusOK && err == nil {
+				t.Fatal("expected an error")
+			}
+
+			var limitErr *RateLimitError
+			if errors.As(err, &limitErr) && limitErr.Attempts != test.requests {
+				t.Errorf("expected the error to report [%d] attempts, got [%d]", test.requests, limitErr.Attempts)
+			}
+
+			var bodies = handler.requests()
+			if len(bodies) != test.requests {
+				t.Fatalf("expected [%d] requests, got [%d]", test.requests, len(bodies))
+			}
+
+			for index, body := range bodies {
+				if body != "action=list" {
+					t.Errorf("request [%d] was sent with the body [%s] after the body was rewound", index, body)
+				}
+			}
+		})
+	}
+}
+
+func TestMakeRequestHonorsServerWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{"Retry-After", map[string]string{"Retry-After": "1"}},
+		{"X-RateLimit-ToWait-Sec", map[string]string{
+			"X-RateLimit-Limit":      "300",
+			"X-RateLimit-Window-Sec": "3600",
+			"X-RateLimit-Remaining":  "0",
+			"X-RateLimit-ToWait-Sec": "1",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var handler = &testServer{responses: []testResponse{
+				{status: http.StatusTooManyRequests, headers: test.headers},
+				{status: http.StatusOK},
+			}}
+			var server = httptest.NewServer(handler)
+			defer server.Close()
+
+			var session = newTestSession(server, qsVulnerabilities)
+
+			request, err := http.NewRequest(http.MethodGet, server.URL+qsVulnerabilities, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var start = time.Now()
+			if err = session.makeRequest(request, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if elapsed := time.Since(start); elapsed < time.Second {
+				t.Errorf("expected the retry to wait for the server advertised second, waited %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestMakeRequestCancelledDuringBackoff(t *testing.T) {
+	var handler = &testServer{responses: []testResponse{{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "60"}}}}
+	var server = httptest.NewServer(handler)
+	defer server.Close()
+
+	var session = newTestSession(server, qsVulnerabilities)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+qsVulnerabilities, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = session.makeRequest(request, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline of the context, got [%v]", err)
+	}
+
+	if requests := len(handler.requests()); requests != 1 {
+		t.Errorf("expected [1] request, got [%d]", requests)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	var policy = retryPolicy{attempts: 5, baseDelay: time.Second, maxDelay: 10 * time.Second}
+
+	tests := []struct {
+		name    string
+		attempt int
+		rates   Rates
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"first attempt", 0, Rates{}, 500 * time.Millisecond, time.Second},
+		{"exponential", 2, Rates{}, 2 * time.Second, 4 * time.Second},
+		{"capped", 10, Rates{}, 5 * time.Second, 10 * time.Second},
+		{"shift overflow capped", 64, Rates{}, 5 * time.Second, 10 * time.Second},
+		{"server wait honored", 0, Rates{RLToWaitSec: 30}, 30 * time.Second, 30 * time.Second},
+		{"shorter server wait ignored", 3, Rates{RLToWaitSec: 1}, 4 * time.Second, 8 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if delay := policy.backoff(test.attempt, test.rates); delay < test.min || delay > test.max {
+					t.Fatalf("expected a delay between %s and %s, got %s", test.min, test.max, delay)
+				}
+			}
+		})
+	}
+}
+
+func TestRewindRequestBody(t *testing.T) {
+	request, err := http.NewRequest(http.MethodPost, "https://qualysapi.qualys.com"+qsVulnerabilities, strings.NewReader("action=list"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = ioutil.ReadAll(request.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = rewindRequestBody(request); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := ioutil.ReadAll(request.Body); string(data) != "action=list" {
+		t.Errorf("expected the rewound body to hold [action=list], got [%s]", string(data))
+	}
+}
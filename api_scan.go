@@ -1,6 +1,7 @@
 package qualys
 
 import (
+	"context"
 	"fmt"
 	"github.com/nortonlifelock/log"
 	"net/http"
@@ -10,6 +11,11 @@ import (
 
 // GetScanList loads the list of scans from Qualys
 func (session *Session) GetScanList() (output QScanListOutput, err error) {
+	return session.GetScanListContext(session.ctx)
+}
+
+// GetScanListContext is GetScanList with a context that aborts the API calls when cancelled
+func (session *Session) GetScanListContext(ctx context.Context) (output QScanListOutput, err error) {
 	output = QScanListOutput{}
 
 	var fields = make(map[string]string)
@@ -20,13 +26,18 @@ func (session *Session) GetScanList() (output QScanListOutput, err error) {
 	// launched_after_datetime, launched_before_datetime,
 	// scan_type=certview, client_id and client_name (only for Consultant type subscriptions)
 
-	err = session.post(session.Config.Address()+qsVMScan, fields, &output)
+	err = session.post(ctx, session.Config.Address()+qsVMScan, fields, &output)
 
 	return output, err
 }
 
 // GetScanByReference queries the Qualys API and recovers information for the scan corresponding to the scanReference argument
 func (session *Session) GetScanByReference(scanReference string) (scan ScanQualys, err error) {
+	return session.GetScanByReferenceContext(session.ctx, scanReference)
+}
+
+// GetScanByReferenceContext is GetScanByReference with a context that aborts the API calls when cancelled
+func (session *Session) GetScanByReferenceContext(ctx context.Context, scanReference string) (scan ScanQualys, err error) {
 	var output = QScanListOutput{}
 	var fields = make(map[string]string)
 	fields["action"] = "list"
@@ -36,7 +47,7 @@ func (session *Session) GetScanByReference(scanReference string) (scan ScanQualy
 	// launched_after_datetime, launched_before_datetime,
 	// scan_type=certview, client_id and client_name (only for Consultant type subscriptions)
 
-	if err = session.post(session.Config.Address()+qsVMScan, fields, &output); err == nil {
+	if err = session.post(ctx, session.Config.Address()+qsVMScan, fields, &output); err == nil {
 		if len(output.Response.Scans) == 1 {
 			scan = output.Response.Scans[0]
 		} else {
@@ -48,6 +59,11 @@ func (session *Session) GetScanByReference(scanReference string) (scan ScanQualy
 }
 
 func (session *Session) CreateEC2Scan(scanTitle string, optionProfileID string, instanceIDs []string, ec2Region string, connectorName string, scannerName string) (scanID int, scanRef string, err error) {
+	return session.CreateEC2ScanContext(session.ctx, scanTitle, optionProfileID, instanceIDs, ec2Region, connectorName, scannerName)
+}
+
+// CreateEC2ScanContext is CreateEC2Scan with a context that aborts the API calls when cancelled
+func (session *Session) CreateEC2ScanContext(ctx context.Context, scanTitle string, optionProfileID string, instanceIDs []string, ec2Region string, connectorName string, scannerName string) (scanID int, scanRef string, err error) {
 	var fields = make(map[string]string)
 	fields["action"] = "launch"
 	fields["scan_title"] = scanTitle
@@ -58,7 +74,7 @@ func (session *Session) CreateEC2Scan(scanTitle string, optionProfileID string,
 	fields["iscanner_name"] = scannerName
 
 	var ret = &simpleReturn{}
-	if err = session.post(session.Config.Address()+qsVMScan, fields, ret); err == nil {
+	if err = session.post(ctx, session.Config.Address()+qsVMScan, fields, ret); err == nil {
 
 		// Determine if there were items returned in the response
 		if len(ret.Response.Items) > 0 {
@@ -79,7 +95,7 @@ func (session *Session) CreateEC2Scan(scanTitle string, optionProfileID string,
 			err = fmt.Errorf("invalid item list returned from create search list in Qualys")
 		}
 	} else {
-		err = fmt.Errorf("error when executing call to Qualys to initialize scan | %s", err.Error())
+		err = fmt.Errorf("error when executing call to Qualys to initialize scan | %w", err)
 	}
 
 	return scanID, scanRef, err
@@ -87,6 +103,11 @@ func (session *Session) CreateEC2Scan(scanTitle string, optionProfileID string,
 
 // CreateScan executes the API call to Qualys to create the scan with all of the information required by the endpoint
 func (session *Session) CreateScan(scanTitle string, optionProfileID string, appliances []string, networkID int, ips []string, external bool) (scanID int, scanRef string, err error) {
+	return session.CreateScanContext(session.ctx, scanTitle, optionProfileID, appliances, networkID, ips, external)
+}
+
+// CreateScanContext is CreateScan with a context that aborts the API calls when cancelled
+func (session *Session) CreateScanContext(ctx context.Context, scanTitle string, optionProfileID string, appliances []string, networkID int, ips []string, external bool) (scanID int, scanRef string, err error) {
 	// TODO: Move this
 	const externalScanner = "External"
 
@@ -114,7 +135,7 @@ func (session *Session) CreateScan(scanTitle string, optionProfileID string, app
 		fields["ip"] = strings.Join(ips, ",") // concat the ips together in a comma separated list for the API
 		// Execute the post call to the API to create the scan
 		var ret = &simpleReturn{}
-		if err = session.post(session.Config.Address()+qsVMScan, fields, ret); err == nil {
+		if err = session.post(ctx, session.Config.Address()+qsVMScan, fields, ret); err == nil {
 
 			// Determine if there were items returned in the response
 			if len(ret.Response.Items) > 0 {
@@ -135,7 +156,7 @@ func (session *Session) CreateScan(scanTitle string, optionProfileID string, app
 				err = fmt.Errorf("invalid item list returned from create search list in Qualys")
 			}
 		} else {
-			err = fmt.Errorf("error when executing call to Qualys to initialize scan | %s", err.Error())
+			err = fmt.Errorf("error when executing call to Qualys to initialize scan | %w", err)
 		}
 	}
 
@@ -143,11 +164,16 @@ func (session *Session) CreateScan(scanTitle string, optionProfileID string, app
 }
 
 func (session *Session) GetAssetTagTargetOfScheduledScan(scheduleTitle string) (tagSetTarget string, err error) {
+	return session.GetAssetTagTargetOfScheduledScanContext(session.ctx, scheduleTitle)
+}
+
+// GetAssetTagTargetOfScheduledScanContext is GetAssetTagTargetOfScheduledScan with a context that aborts the API calls when cancelled
+func (session *Session) GetAssetTagTargetOfScheduledScanContext(ctx context.Context, scheduleTitle string) (tagSetTarget string, err error) {
 	var output = ScheduleScanListOutput{}
 	var fields = make(map[string]string)
 	fields["action"] = "list"
 
-	if err = session.httpCall(http.MethodGet, session.Config.Address()+qsScheduledScan, fields, nil, &output); err == nil {
+	if err = session.httpCall(ctx, http.MethodGet, session.Config.Address()+qsScheduledScan, fields, nil, &output); err == nil {
 		for _, scheduledScan := range output.Response.ScheduleScanList.Scan {
 			if scheduledScan.Title == scheduleTitle {
 				tagSetTarget = scheduledScan.AssetTags.TagSetInclude
@@ -163,13 +189,18 @@ func (session *Session) GetAssetTagTargetOfScheduledScan(scheduleTitle string) (
 }
 
 func (session *Session) GetScheduledScan(scanTitle string) (scan *ScanQualys, err error) {
+	return session.GetScheduledScanContext(session.ctx, scanTitle)
+}
+
+// GetScheduledScanContext is GetScheduledScan with a context that aborts the API calls when cancelled
+func (session *Session) GetScheduledScanContext(ctx context.Context, scanTitle string) (scan *ScanQualys, err error) {
 	var output = QScanListOutput{}
 	var fields = make(map[string]string)
 	fields["action"] = "list"
 	fields["type"] = "Scheduled"
 	fields["state"] = "Running,Paused,Queued,Loading"
 
-	if err = session.post(session.Config.Address()+qsVMScan, fields, &output); err == nil {
+	if err = session.post(ctx, session.Config.Address()+qsVMScan, fields, &output); err == nil {
 
 		var found bool
 		for index, scheduledScan := range output.Response.Scans {
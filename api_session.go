@@ -1,21 +1,27 @@
 package qualys
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/nortonlifelock/domain"
 	"github.com/nortonlifelock/funnel"
 	"github.com/nortonlifelock/log"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"sync"
 	"time"
 )
 
-// each endpoint has a separate rate limit, so we create a funnel for each endpoint
-var (
-	funnelMap  = make(map[string]funnel.Client)
-	funnelLock = &sync.Mutex{}
+const (
+	defaultRetryAttempts = 5
+	defaultRetryDelay    = 5 * time.Second
+	defaultRetryMaxDelay = 5 * time.Minute
+
+	// logoutTimeout limits how long Close waits on Qualys to end the session
+	logoutTimeout = 30 * time.Second
 )
 
 type logger interface {
@@ -29,59 +35,176 @@ type Session struct {
 	// Source configuration which holds the authentication information for the Qualys API
 	Config domain.SourceConfig
 
+	// auth holds the credentials parsed from the source config as well as the Qualys session cookie when session
+	// authentication is selected through the "auth_method" JSON key of the AuthInfo
+	auth *authenticator
+
+	// ctx is the default context used by the API methods that are not passed a context by the caller
 	ctx              context.Context
 	concurrencyLimit int
 	rateLimit        time.Duration
 
+	// retry controls how requests rejected by the Qualys rate and concurrency limits are retried
+	retry retryPolicy
+
+	// client executes the requests of the funnels, its transport can be replaced through WithRoundTripper
+	client *http.Client
+
+	// each endpoint has a separate rate limit, so we create a funnel for each endpoint. The funnels are owned by the session
+	// and are only stopped by cancelling funnelCtx when the session is closed, cancelling ctx does not stop them
+	funnels      map[string]funnel.Client
+	funnelLock   sync.Mutex
+	funnelCtx    context.Context
+	funnelCancel context.CancelFunc
+
 	// Web Application Scanning API uses a different base URL from the Qualys VM API
 	// we can gather the second URL from the AuthInfo column for the Qualys connection in the SourceConfig table
 	// the url JSON key is "host_web_app"
 	webAppBaseURL string
 }
 
-// NewQualysAPISession initializes the Qualys session object but currently all authentication is done with basic auth rather
-// than session management which will be changing in a future release
-func NewQualysAPISession(ctx context.Context, lstream logger, config domain.SourceConfig) (session *Session, err error) {
+// retryPolicy holds the budget for retrying requests that were rejected by the Qualys rate or concurrency limits
+type retryPolicy struct {
+	attempts  int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// Option configures optional behavior of a Session when passed to NewQualysAPISession
+type Option func(session *Session)
+
+// WithRetryBudget sets how many times a request rejected by the Qualys rate or concurrency limits (409/429) is retried
+// before a RateLimitError is returned. The delay between attempts starts at baseDelay and doubles with each attempt up to
+// maxDelay, unless Qualys asks for a longer wait through the X-RateLimit-ToWait-Sec header. An attempts value of 0
+// disables retrying
+func WithRetryBudget(attempts int, baseDelay time.Duration, maxDelay time.Duration) Option {
+	return func(session *Session) {
+		if attempts >= 0 {
+			session.retry.attempts = attempts
+		}
+
+		if baseDelay > 0 {
+			session.retry.baseDelay = baseDelay
+		}
+
+		if maxDelay >= session.retry.baseDelay {
+			session.retry.maxDelay = maxDelay
+		}
+	}
+}
+
+// WithRoundTripper sets the transport used for the requests made against Qualys, e.g. to route the requests through a
+// proxy or to present a client certificate. http.DefaultTransport is used when no transport is provided
+func WithRoundTripper(transport http.RoundTripper) Option {
+	return func(session *Session) {
+		if transport != nil {
+			session.client.Transport = transport
+		}
+	}
+}
+
+// WithTimeout sets a time limit for each request made against Qualys, including reading the response body. No time
+// limit is set by default as the detection downloads can take a long time
+func WithTimeout(timeout time.Duration) Option {
+	return func(session *Session) {
+		if timeout > 0 {
+			session.client.Timeout = timeout
+		}
+	}
+}
+
+// NewQualysAPISession initializes the Qualys session object. Requests are authenticated with basic auth unless the AuthInfo
+// of the source config sets "auth_method" to "session", in which case a Qualys session is opened with the credentials and
+// the session cookie is used for the v2 API. Logout should be called to end the Qualys session once the Session is done
+// ctx is used by the API methods that do not accept a context of their own, the ...Context variants of those methods
+// should be preferred so that cancellation can be controlled per call. Close should be called once the session is no longer
+// needed to stop the funnels that rate limit the requests of the session
+func NewQualysAPISession(ctx context.Context, lstream logger, config domain.SourceConfig, opts ...Option) (session *Session, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	session = &Session{
 		lstream:       lstream,
 		Config:        config,
 		webAppBaseURL: getWebAppURLIfPresent(config),
 		ctx:           ctx,
+		retry: retryPolicy{
+			attempts:  defaultRetryAttempts,
+			baseDelay: defaultRetryDelay,
+			maxDelay:  defaultRetryMaxDelay,
+		},
+		client:  &http.Client{},
+		funnels: make(map[string]funnel.Client),
 	}
+	// the funnels live as long as the session rather than the context of the caller, so they are only stopped by Close
+	session.funnelCtx, session.funnelCancel = context.WithCancel(context.Background())
 
-	var rates Rates
-	if rates, err = session.pullRateInfoForInitialization(); err == nil {
-
-		// RLLimit is requests allowed within a time period
-		// RLWindowSec is the time period which the requests are limited
-		// The ratio between of the two is the frequency one is able to make API calls for any particular endpoint
-		var reqsPerSecond float32
-		if rates.RLLimit > 0 {
-			reqsPerSecond = float32(rates.RLWindowSec) / float32(rates.RLLimit)
+	for _, opt := range opts {
+		if opt != nil {
+			opt(session)
 		}
+	}
 
-		if reqsPerSecond < 1 {
-			reqsPerSecond = 1
-		}
+	if session.auth, err = newAuthenticator(config); err == nil {
+		var rates Rates
+		if rates, err = session.pullRateInfoForInitialization(); err == nil {
 
-		session.rateLimit = time.Duration(reqsPerSecond)
+			// RLLimit is requests allowed within a time period
+			// RLWindowSec is the time period which the requests are limited
+			// The ratio between of the two is the frequency one is able to make API calls for any particular endpoint
+			var reqsPerSecond float32
+			if rates.RLLimit > 0 {
+				reqsPerSecond = float32(rates.RLWindowSec) / float32(rates.RLLimit)
+			}
 
-		session.concurrencyLimit = rates.CLimit
-		if session.concurrencyLimit < 1 {
-			session.concurrencyLimit = 1
+			if reqsPerSecond < 1 {
+				reqsPerSecond = 1
+			}
+
+			session.rateLimit = time.Duration(reqsPerSecond)
+
+			session.concurrencyLimit = rates.CLimit
+			if session.concurrencyLimit < 1 {
+				session.concurrencyLimit = 1
+			}
 		}
 	}
 
+	if err != nil {
+		_ = session.Close()
+	}
+
 	return session, err
 }
 
+// Close ends the Qualys session when session authentication is used and stops the funnels of the session. The session
+// can not be used to make further requests once it is closed. The logout is not tied to the context of the session, as
+// that context is usually cancelled by the time the session is closed
+func (session *Session) Close() (err error) {
+	if session.auth != nil {
+		var ctx, cancel = context.WithTimeout(context.Background(), logoutTimeout)
+		err = session.LogoutContext(ctx)
+		cancel()
+	}
+
+	session.funnelLock.Lock()
+	defer session.funnelLock.Unlock()
+
+	session.funnelCancel()
+	session.funnels = make(map[string]funnel.Client)
+
+	return err
+}
+
+// getFunnelForEndpoint returns the funnel of the session that rate limits the requests made against the endpoint, the
+// funnel is created on the first request made against the endpoint
 func (session *Session) getFunnelForEndpoint(endpoint string) (client funnel.Client) {
-	funnelLock.Lock()
-	defer funnelLock.Unlock()
+	session.funnelLock.Lock()
+	defer session.funnelLock.Unlock()
 
-	mapKey := fmt.Sprintf("%s;%s", session.Config.Address(), endpoint)
-	if funnelMap[mapKey] != nil {
-		client = funnelMap[mapKey]
+	if session.funnels[endpoint] != nil {
+		client = session.funnels[endpoint]
 	} else {
 		if session.concurrencyLimit < 1 {
 			session.concurrencyLimit = 1
@@ -92,11 +215,11 @@ func (session *Session) getFunnelForEndpoint(endpoint string) (client funnel.Cli
 		}
 
 		var err error
-		if client, err = funnel.New(context.Background(), &http.Client{}, session.lstream, session.rateLimit*time.Second, 1, session.concurrencyLimit); err == nil && client != nil {
-			funnelMap[mapKey] = client
+		if client, err = funnel.New(session.funnelCtx, &rewindingClient{client: session.client}, session.lstream, session.rateLimit*time.Second, 1, session.concurrencyLimit); err == nil && client != nil {
+			session.funnels[endpoint] = client
 		} else {
 			session.lstream.Send(log.Error("error while creating a Qualys funnel, defaulting to http client", err))
-			client = &http.Client{}
+			client = session.client
 		}
 	}
 
@@ -106,7 +229,7 @@ func (session *Session) getFunnelForEndpoint(endpoint string) (client funnel.Cli
 func (session *Session) pullRateInfoForInitialization() (rates Rates, err error) {
 	var req *http.Request
 	// we make a request to a random authenticated endpoint to get the rate limit information in the response headers
-	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("%s?action=list", session.Config.Address()+qsAppliance), nil)
+	req, err = http.NewRequestWithContext(session.ctx, http.MethodGet, fmt.Sprintf("%s?action=list", session.Config.Address()+qsAppliance), nil)
 	if err == nil {
 		err = session.makeRequest(req, func(response *http.Response) (err error) {
 			defer response.Body.Close()
@@ -135,3 +258,33 @@ func getWebAppURLIfPresent(sourceConfig domain.SourceConfig) (url string) {
 	url = parse.URL
 	return url
 }
+
+// rewindingClient buffers the body of non-2xx responses before they are handed to the funnel. The funnel drains the body
+// of any response it considers a failure, so the buffered body is rewound when closed which allows makeRequest to still
+// read the status and the error returned by Qualys
+type rewindingClient struct {
+	client *http.Client
+}
+
+// Do executes the request and buffers the body of the response when the status is not 2xx
+func (rc *rewindingClient) Do(request *http.Request) (response *http.Response, err error) {
+	if response, err = rc.client.Do(request); err == nil && response != nil && (response.StatusCode < 200 || response.StatusCode > 299) {
+		var data []byte
+		data, err = ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+		response.Body = &rewindingBody{Reader: bytes.NewReader(data)}
+	}
+
+	return response, err
+}
+
+// rewindingBody is a response body that returns to the start of the buffered data when closed
+type rewindingBody struct {
+	*bytes.Reader
+}
+
+// Close rewinds the body so that it can be read again
+func (body *rewindingBody) Close() (err error) {
+	_, err = body.Seek(0, io.SeekStart)
+	return err
+}
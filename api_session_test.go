@@ -0,0 +1,89 @@
+package qualys
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nortonlifelock/funnel"
+	"github.com/nortonlifelock/log"
+)
+
+// testConfig is the source config of a session created against a test server
+type testConfig struct {
+	address  string
+	authInfo string
+}
+
+func (config testConfig) Address() string           { return config.address }
+func (config testConfig) AuthInfo() string          { return config.authInfo }
+func (config testConfig) DBCreatedDate() time.Time  { return time.Time{} }
+func (config testConfig) DBUpdatedDate() *time.Time { return nil }
+func (config testConfig) ID() string                { return "" }
+func (config testConfig) OrganizationID() string    { return "" }
+func (config testConfig) Payload() *string          { return nil }
+func (config testConfig) Port() string              { return "" }
+func (config testConfig) Source() string            { return "Qualys" }
+func (config testConfig) SourceID() string          { return "" }
+
+// testLogger discards the logs of a session created against a test server
+type testLogger struct{}
+
+func (testLogger) Send(log.Log) {}
+
+// newTestSession returns a session that sends its requests to the test server using basic auth. The requests made
+// against the paths skip the rate limiting funnels so that the tests don't wait on the funnel delay
+func newTestSession(server *httptest.Server, paths ...string) (session *Session) {
+	var ctx, cancel = context.WithCancel(context.Background())
+
+	session = &Session{
+		lstream:          testLogger{},
+		Config:           testConfig{address: server.URL},
+		auth:             &authenticator{method: BasicAuthMethod},
+		ctx:              ctx,
+		concurrencyLimit: 2,
+		retry: retryPolicy{
+			attempts:  3,
+			baseDelay: time.Millisecond,
+			maxDelay:  5 * time.Millisecond,
+		},
+		client:       server.Client(),
+		funnels:      make(map[string]funnel.Client),
+		funnelCtx:    ctx,
+		funnelCancel: cancel,
+	}
+
+	var address, _ = url.Parse(server.URL)
+	for _, path := range paths {
+		session.funnels[address.Host+path] = &rewindingClient{client: session.client}
+	}
+
+	return session
+}
+
+func TestSessionFunnelsOutliveCallerContext(t *testing.T) {
+	var server = httptest.NewServer(&testServer{responses: []testResponse{{status: http.StatusOK}}})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session, err := NewQualysAPISession(ctx, testLogger{}, testConfig{address: server.URL, authInfo: `{"username":"user","password":"pass"}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if session.funnelCtx.Err() != nil {
+		t.Fatal("expected the funnels to keep running once the context of the caller is cancelled")
+	}
+
+	if err = session.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if session.funnelCtx.Err() == nil {
+		t.Fatal("expected the funnels to be stopped once the session is closed")
+	}
+}
@@ -0,0 +1,372 @@
+package qualys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nortonlifelock/log"
+)
+
+// maxTagDepth bounds the depth of the tag hierarchy loaded by GetTagHierarchy
+const maxTagDepth = 32
+
+// SearchTags loads the asset tags matched by the criteria, following the pages of the response until every tag has been
+// loaded. Every tag is loaded when no criteria are passed
+func (session *Session) SearchTags(criteria ...SearchCriteria) (tags []Tag, err error) {
+	return session.SearchTagsContext(session.ctx, criteria...)
+}
+
+// SearchTagsContext is SearchTags with a context that stops the paging of the tags when cancelled
+func (session *Session) SearchTagsContext(ctx context.Context, criteria ...SearchCriteria) (tags []Tag, err error) {
+	tags = make([]Tag, 0)
+
+	var pager = session.NewQPSPager(session.Config.Address()+qpsTagSearch, NewQPSRequest(criteria...), QPSXML).PageByID("id")
+	for {
+		var page tagPage
+		if !pager.Next(ctx, &page) {
+			break
+		}
+
+		tags = append(tags, page.Tag...)
+	}
+
+	if err = pager.Err(); err != nil {
+		session.lstream.Send(log.Errorf(err, "error while searching tags in Qualys"))
+	}
+
+	return tags, err
+}
+
+// GetTag loads a single asset tag by its ID
+func (session *Session) GetTag(tagID int) (tag *Tag, err error) {
+	return session.GetTagContext(session.ctx, tagID)
+}
+
+// GetTagContext is GetTag with a context that aborts the API call when cancelled
+func (session *Session) GetTagContext(ctx context.Context, tagID int) (tag *Tag, err error) {
+	var tags []Tag
+	if tags, err = session.SearchTagsContext(ctx, CriteriaEquals("id", strconv.Itoa(tagID))); err == nil {
+		if len(tags) > 0 {
+			tag = &tags[0]
+		} else {
+			err = fmt.Errorf("tag [%d] was not found in Qualys - %w", tagID, ErrNotFound)
+		}
+	}
+
+	return tag, err
+}
+
+// GetTagByName loads a single asset tag by its name. Tag names are matched exactly, including their case
+func (session *Session) GetTagByName(name string) (tag *Tag, err error) {
+	return session.GetTagByNameContext(session.ctx, name)
+}
+
+// GetTagByNameContext is GetTagByName with a context that aborts the API call when cancelled
+func (session *Session) GetTagByNameContext(ctx context.Context, name string) (tag *Tag, err error) {
+	var tags []Tag
+	if tags, err = session.SearchTagsContext(ctx, CriteriaEquals("name", name)); err == nil {
+		for index := range tags {
+			if tags[index].Name == name {
+				tag = &tags[index]
+				break
+			}
+		}
+
+		if tag == nil {
+			err = fmt.Errorf("tag [%s] was not found in Qualys - %w", name, ErrNotFound)
+		}
+	}
+
+	return tag, err
+}
+
+// ResolveTagIDs returns the IDs of the tags with the names. An error matching ErrNotFound is returned along with the IDs
+// that were resolved when any of the names does not belong to a tag
+func (session *Session) ResolveTagIDs(names ...string) (ids map[string]int, err error) {
+	return session.ResolveTagIDsContext(session.ctx, names...)
+}
+
+// ResolveTagIDsContext is ResolveTagIDs with a context that aborts the API call when cancelled
+func (session *Session) ResolveTagIDsContext(ctx context.Context, names ...string) (ids map[string]int, err error) {
+	ids = make(map[string]int)
+
+	if len(names) > 0 {
+		var tags []Tag
+		if tags, err = session.SearchTagsContext(ctx, CriteriaIn("name", names...)); err == nil {
+			ids, err = tagIDsByName(tags, names)
+		}
+	}
+
+	return ids, err
+}
+
+// tagIDsByName maps the names to the IDs of the tags with the exact same name. An error matching ErrNotFound lists the
+// names without a tag
+func tagIDsByName(tags []Tag, names []string) (ids map[string]int, err error) {
+	ids = make(map[string]int)
+
+	var wanted = make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	for _, tag := range tags {
+		// the search may return tags whose name only differs in case, which are left out as names are matched exactly
+		if wanted[tag.Name] {
+			ids[tag.Name] = tag.ID
+		}
+	}
+
+	var missing = make([]string, 0)
+	for _, name := range names {
+		if _, ok := ids[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		err = fmt.Errorf("tags [%s] were not found in Qualys - %w", strings.Join(missing, ","), ErrNotFound)
+	}
+
+	return ids, err
+}
+
+// ResolveTagNames returns the names of the tags with the IDs. An error matching ErrNotFound is returned along with the
+// names that were resolved when any of the IDs does not belong to a tag
+func (session *Session) ResolveTagNames(ids ...int) (names map[int]string, err error) {
+	return session.ResolveTagNamesContext(session.ctx, ids...)
+}
+
+// ResolveTagNamesContext is ResolveTagNames with a context that aborts the API call when cancelled
+func (session *Session) ResolveTagNamesContext(ctx context.Context, ids ...int) (names map[int]string, err error) {
+	names = make(map[int]string)
+
+	if len(ids) > 0 {
+		var tags []Tag
+		if tags, err = session.SearchTagsContext(ctx, CriteriaIn("id", intArrayToStringArray(ids)...)); err == nil {
+			names, err = tagNamesByID(tags, ids)
+		}
+	}
+
+	return names, err
+}
+
+// tagNamesByID maps the IDs to the names of the tags. An error matching ErrNotFound lists the IDs without a tag
+func tagNamesByID(tags []Tag, ids []int) (names map[int]string, err error) {
+	names = make(map[int]string)
+
+	var wanted = make(map[int]bool)
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	for _, tag := range tags {
+		if wanted[tag.ID] {
+			names[tag.ID] = tag.Name
+		}
+	}
+
+	var missing = make([]string, 0)
+	for _, id := range ids {
+		if _, ok := names[id]; !ok {
+			missing = append(missing, strconv.Itoa(id))
+		}
+	}
+
+	if len(missing) > 0 {
+		err = fmt.Errorf("tags [%s] were not found in Qualys - %w", strings.Join(missing, ","), ErrNotFound)
+	}
+
+	return names, err
+}
+
+// CreateTag creates an asset tag, along with the children of the tag when their names are set, and returns the tag as it
+// was created by Qualys
+func (session *Session) CreateTag(tag Tag) (created *Tag, err error) {
+	return session.CreateTagContext(session.ctx, tag)
+}
+
+// CreateTagContext is CreateTag with a context that aborts the API call when cancelled
+func (session *Session) CreateTagContext(ctx context.Context, tag Tag) (created *Tag, err error) {
+	if len(tag.Name) > 0 {
+		var children = make([]TagSimple, 0, len(tag.Children))
+		for _, child := range tag.Children {
+			children = append(children, TagSimple{Name: child.Name})
+		}
+
+		var data struct {
+			Tag tagBody `xml:"Tag"`
+		}
+
+		data.Tag = tagBody{
+			Name:             tag.Name,
+			ParentTagID:      tag.ParentTagID,
+			Color:            tag.Color,
+			RuleType:         tag.RuleType,
+			RuleText:         tag.RuleText,
+			CriticalityScore: tag.CriticalityScore,
+			Children:         newTagChanges(children, nil, nil),
+		}
+
+		var page tagPage
+		if _, err = session.QPSCall(ctx, http.MethodPost, session.Config.Address()+qpsTagCreate, &QPSRequest{Data: data}, QPSXML, &page); err == nil {
+			if len(page.Tag) > 0 {
+				created = &page.Tag[0]
+			} else {
+				err = fmt.Errorf("tag [%s] was not returned from Qualys after it was created", tag.Name)
+			}
+		}
+	} else {
+		err = fmt.Errorf("cannot create a tag without a name")
+	}
+
+	return created, err
+}
+
+// UpdateTag applies the update to the asset tag
+func (session *Session) UpdateTag(tagID int, update TagUpdate) (err error) {
+	return session.UpdateTagContext(session.ctx, tagID, update)
+}
+
+// UpdateTagContext is UpdateTag with a context that aborts the API call when cancelled
+func (session *Session) UpdateTagContext(ctx context.Context, tagID int, update TagUpdate) (err error) {
+	var data struct {
+		Tag tagBody `xml:"Tag"`
+	}
+
+	data.Tag = tagBody{
+		Name:             update.Name,
+		ParentTagID:      update.ParentTagID,
+		Color:            update.Color,
+		RuleType:         update.RuleType,
+		RuleText:         update.RuleText,
+		CriticalityScore: update.CriticalityScore,
+		Children:         newTagChanges(nil, update.AddChildren, update.RemoveChildren),
+	}
+
+	var path = strings.Replace(session.Config.Address()+qpsTagUpdate, "<id>", strconv.Itoa(tagID), 1)
+	_, err = session.QPSCall(ctx, http.MethodPost, path, &QPSRequest{Data: data}, QPSXML, nil)
+
+	return err
+}
+
+// DeleteTag deletes the asset tag. Qualys deletes the children of the tag along with it
+func (session *Session) DeleteTag(tagID int) (err error) {
+	return session.DeleteTagContext(session.ctx, tagID)
+}
+
+// DeleteTagContext is DeleteTag with a context that aborts the API call when cancelled
+func (session *Session) DeleteTagContext(ctx context.Context, tagID int) (err error) {
+	var path = strings.Replace(session.Config.Address()+qpsTagDelete, "<id>", strconv.Itoa(tagID), 1)
+	_, err = session.QPSCall(ctx, http.MethodPost, path, nil, QPSXML, nil)
+
+	return err
+}
+
+// AddTagsToAssets assigns the tags to the host assets with the Asset Management IDs
+func (session *Session) AddTagsToAssets(assetIDs []int, tagIDs []int) (err error) {
+	return session.AddTagsToAssetsContext(session.ctx, assetIDs, tagIDs)
+}
+
+// AddTagsToAssetsContext is AddTagsToAssets with a context that aborts the API call when cancelled
+func (session *Session) AddTagsToAssetsContext(ctx context.Context, assetIDs []int, tagIDs []int) (err error) {
+	return session.updateAssetTags(ctx, assetIDs, tagIDs, true)
+}
+
+// RemoveTagsFromAssets removes the tags from the host assets with the Asset Management IDs
+func (session *Session) RemoveTagsFromAssets(assetIDs []int, tagIDs []int) (err error) {
+	return session.RemoveTagsFromAssetsContext(session.ctx, assetIDs, tagIDs)
+}
+
+// RemoveTagsFromAssetsContext is RemoveTagsFromAssets with a context that aborts the API call when cancelled
+func (session *Session) RemoveTagsFromAssetsContext(ctx context.Context, assetIDs []int, tagIDs []int) (err error) {
+	return session.updateAssetTags(ctx, assetIDs, tagIDs, false)
+}
+
+// updateAssetTags adds or removes the tags on the host assets in a single call
+func (session *Session) updateAssetTags(ctx context.Context, assetIDs []int, tagIDs []int, add bool) (err error) {
+	if len(assetIDs) > 0 && len(tagIDs) > 0 {
+		var tags = make([]TagSimple, 0, len(tagIDs))
+		for _, tagID := range tagIDs {
+			tags = append(tags, TagSimple{ID: tagID})
+		}
+
+		var data hostAssetTagUpdate
+		if add {
+			data.HostAsset.Tags = newTagChanges(nil, tags, nil)
+		} else {
+			data.HostAsset.Tags = newTagChanges(nil, nil, tags)
+		}
+
+		var request = NewQPSRequest(CriteriaIn("id", intArrayToStringArray(assetIDs)...))
+		request.Preferences = nil
+		request.Data = data
+
+		_, err = session.QPSCall(ctx, http.MethodPost, session.Config.Address()+qpsHostAssetUpdate, request, QPSXML, nil)
+	} else {
+		err = fmt.Errorf("both asset IDs and tag IDs are required to update the tags of assets [%d|%d]", len(assetIDs), len(tagIDs))
+	}
+
+	return err
+}
+
+// GetTagHierarchy loads the tag along with every tag beneath it. The children of each level are loaded with a single
+// search on their parent tag IDs
+func (session *Session) GetTagHierarchy(tagID int) (root *TagNode, err error) {
+	return session.GetTagHierarchyContext(session.ctx, tagID)
+}
+
+// GetTagHierarchyContext is GetTagHierarchy with a context that aborts the API call when cancelled
+func (session *Session) GetTagHierarchyContext(ctx context.Context, tagID int) (root *TagNode, err error) {
+	var tag *Tag
+	if tag, err = session.GetTagContext(ctx, tagID); err == nil {
+		root = &TagNode{Tag: *tag}
+
+		var seen = map[int]bool{root.ID: true}
+		var level = []*TagNode{root}
+		for depth := 0; len(level) > 0 && err == nil; depth++ {
+			if depth >= maxTagDepth {
+				err = fmt.Errorf("tag hierarchy of [%d] is deeper than [%d] levels", tagID, maxTagDepth)
+				break
+			}
+
+			var parentIDs = make([]int, 0, len(level))
+			for _, node := range level {
+				parentIDs = append(parentIDs, node.ID)
+			}
+
+			var children []Tag
+			if children, err = session.SearchTagsContext(ctx, CriteriaIn("parent", intArrayToStringArray(parentIDs)...)); err == nil {
+				level = addTagLevel(level, children, seen)
+			}
+		}
+	}
+
+	return root, err
+}
+
+// addTagLevel attaches the children to their parents within the level and returns the nodes of the children, which form
+// the next level of the hierarchy. Children whose parent isn't within the level are skipped, as are the tags already seen
+// in the hierarchy so that a parent loop ends the walk instead of repeating it
+func addTagLevel(level []*TagNode, children []Tag, seen map[int]bool) (next []*TagNode) {
+	var parents = make(map[int]*TagNode)
+	for _, node := range level {
+		parents[node.ID] = node
+	}
+
+	next = make([]*TagNode, 0, len(children))
+	for _, child := range children {
+		if parent := parents[child.ParentTagID]; parent != nil && !seen[child.ID] {
+			seen[child.ID] = true
+
+			var node = &TagNode{Tag: child}
+			parent.Nodes = append(parent.Nodes, node)
+			next = append(next, node)
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,306 @@
+package qualys
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+var testTags = []Tag{
+	{ID: 1, Name: "Production"},
+	{ID: 2, Name: "production"},
+	{ID: 3, Name: "Web", ParentTagID: 1},
+}
+
+func TestTagIDsByName(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		expected map[string]int
+		err      error
+	}{
+		{"exact names", []string{"Production", "Web"}, map[string]int{"Production": 1, "Web": 3}, nil},
+		{"case is kept apart", []string{"production"}, map[string]int{"production": 2}, nil},
+		{"missing name", []string{"Web", "Database"}, map[string]int{"Web": 3}, ErrNotFound},
+		{"no names", nil, map[string]int{}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids, err := tagIDsByName(testTags, test.names)
+			if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+				t.Fatalf("expected the error [%v], got [%v]", test.err, err)
+			}
+
+			if test.err != nil && !strings.Contains(err.Error(), "Database") {
+				t.Errorf("expected the error to list the missing names, got [%v]", err)
+			}
+
+			if !reflect.DeepEqual(ids, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, ids)
+			}
+		})
+	}
+}
+
+func TestTagNamesByID(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      []int
+		expected map[int]string
+		err      error
+	}{
+		{"IDs", []int{1, 3}, map[int]string{1: "Production", 3: "Web"}, nil},
+		{"missing ID", []int{3, 42}, map[int]string{3: "Web"}, ErrNotFound},
+		{"no IDs", nil, map[int]string{}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names, err := tagNamesByID(testTags, test.ids)
+			if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+				t.Fatalf("expected the error [%v], got [%v]", test.err, err)
+			}
+
+			if test.err != nil && !strings.Contains(err.Error(), "42") {
+				t.Errorf("expected the error to list the missing IDs, got [%v]", err)
+			}
+
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+// hierarchy flattens the hierarchy beneath the node into the IDs of the tags prefixed by their depth
+func hierarchy(node *TagNode) (flat []string) {
+	node.Walk(func(node *TagNode, depth int) {
+		flat = append(flat, fmt.Sprintf("%s%d", strings.Repeat("-", depth), node.ID))
+	})
+
+	return flat
+}
+
+func TestAddTagLevel(t *testing.T) {
+	var root = &TagNode{Tag: Tag{ID: 1}}
+	var seen = map[int]bool{1: true}
+
+	var level = addTagLevel([]*TagNode{root}, []Tag{
+		{ID: 2, ParentTagID: 1},
+		{ID: 3, ParentTagID: 1},
+		{ID: 9, ParentTagID: 99}, // the parent isn't part of the level
+	}, seen)
+
+	if len(level) != 2 || level[0].ID != 2 || level[1].ID != 3 {
+		t.Fatalf("expected the children of the root to form the next level, got %v", hierarchy(root))
+	}
+
+	level = addTagLevel(level, []Tag{
+		{ID: 4, ParentTagID: 3},
+		{ID: 1, ParentTagID: 2}, // the root is a child of its own child
+		{ID: 4, ParentTagID: 2}, // a tag is only added beneath one parent
+	}, seen)
+
+	if len(level) != 1 || level[0].ID != 4 {
+		t.Fatalf("expected only the new tag to form the next level, got %v", hierarchy(root))
+	}
+
+	if expected := []string{"1", "-2", "-3", "--4"}; !reflect.DeepEqual(hierarchy(root), expected) {
+		t.Errorf("expected the hierarchy %v, got %v", expected, hierarchy(root))
+	}
+}
+
+// tagServer plays the tag API of Qualys. The searches on the id and parent fields are answered from the tags, and the path
+// and body of every other call are recorded
+type tagServer struct {
+	tags []Tag
+
+	lock  sync.Mutex
+	calls []string
+}
+
+func (server *tagServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	data, _ := ioutil.ReadAll(request.Body)
+
+	if request.URL.Path != qpsTagSearch {
+		server.lock.Lock()
+		server.calls = append(server.calls, request.URL.Path+" "+string(data))
+		server.lock.Unlock()
+
+		_, _ = writer.Write([]byte("<ServiceResponse><responseCode>SUCCESS</responseCode><count>1</count><data><Tag><id>50</id><name>created</name></Tag></data></ServiceResponse>"))
+		return
+	}
+
+	var search qpsSearchRequest
+	_ = xml.Unmarshal(data, &search)
+
+	var matched = make([]Tag, 0)
+	for _, criteria := range search.Criteria {
+		var values = make(map[int]bool)
+		for _, value := range strings.Split(criteria.Text, ",") {
+			id, _ := strconv.Atoi(value)
+			values[id] = true
+		}
+
+		for _, tag := range server.tags {
+			if (criteria.Field == "id" && values[tag.ID]) || (criteria.Field == "parent" && values[tag.ParentTagID]) {
+				matched = append(matched, tag)
+			}
+		}
+	}
+
+	var body []byte
+	body, _ = xml.Marshal(tagPage{Tag: matched})
+	var inner = strings.TrimSuffix(strings.TrimPrefix(string(body), "<tagPage>"), "</tagPage>")
+	_, _ = fmt.Fprintf(writer, "<ServiceResponse><responseCode>SUCCESS</responseCode><count>%d</count><hasMoreRecords>false</hasMoreRecords><data>%s</data></ServiceResponse>", len(matched), inner)
+}
+
+func (server *tagServer) received() []string {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	return append([]string(nil), server.calls...)
+}
+
+func TestGetTagHierarchy(t *testing.T) {
+	var server = httptest.NewServer(&tagServer{tags: []Tag{
+		{ID: 1, Name: "root", ParentTagID: 2},
+		{ID: 2, Name: "child", ParentTagID: 1},
+		{ID: 3, Name: "child", ParentTagID: 1},
+		{ID: 4, Name: "grandchild", ParentTagID: 3},
+		{ID: 5, Name: "orphan", ParentTagID: 99},
+	}})
+	defer server.Close()
+
+	root, err := newTestSession(server, qpsTagSearch).GetTagHierarchyContext(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the root is also a child of its child, which ends the walk rather than repeating the hierarchy
+	if expected := []string{"1", "-2", "-3", "--4"}; !reflect.DeepEqual(hierarchy(root), expected) {
+		t.Errorf("expected the hierarchy %v, got %v", expected, hierarchy(root))
+	}
+
+	if _, err = newTestSession(server, qpsTagSearch).GetTagHierarchyContext(context.Background(), 42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a missing tag to match ErrNotFound, got [%v]", err)
+	}
+}
+
+func TestTagCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     func(session *Session) error
+		expected string
+	}{
+		{
+			"create",
+			func(session *Session) error {
+				created, err := session.CreateTagContext(context.Background(), Tag{
+					ID:       7,
+					Name:     "Production",
+					Color:    "#FF0000",
+					RuleType: TagRuleOSRegex,
+					RuleText: "Windows",
+					Children: []TagSimple{{ID: 8, Name: "Web"}},
+				})
+				if err == nil && (created == nil || created.ID != 50) {
+					err = fmt.Errorf("expected the created tag to be returned, got [%v]", created)
+				}
+
+				return err
+			},
+			qpsTagCreate + " <ServiceRequest><data><Tag><name>Production</name><color>#FF0000</color><ruleType>OS_REGEX</ruleType><ruleText>Windows</ruleText><children><set><TagSimple><name>Web</name></TagSimple></set></children></Tag></data></ServiceRequest>",
+		},
+		{
+			"update",
+			func(session *Session) error {
+				return session.UpdateTagContext(context.Background(), 7, TagUpdate{
+					Name:           "Prod",
+					AddChildren:    []TagSimple{{Name: "Database"}},
+					RemoveChildren: []TagSimple{{ID: 8}},
+				})
+			},
+			"/qps/rest/2.0/update/am/tag/7 <ServiceRequest><data><Tag><name>Prod</name><children><add><TagSimple><name>Database</name></TagSimple></add><remove><TagSimple><id>8</id></TagSimple></remove></children></Tag></data></ServiceRequest>",
+		},
+		{
+			"update without changes to the children",
+			func(session *Session) error {
+				return session.UpdateTagContext(context.Background(), 7, TagUpdate{Color: "#00FF00"})
+			},
+			"/qps/rest/2.0/update/am/tag/7 <ServiceRequest><data><Tag><color>#00FF00</color></Tag></data></ServiceRequest>",
+		},
+		{
+			"delete",
+			func(session *Session) error {
+				return session.DeleteTagContext(context.Background(), 7)
+			},
+			"/qps/rest/2.0/delete/am/tag/7 ",
+		},
+		{
+			"add to assets",
+			func(session *Session) error {
+				return session.AddTagsToAssetsContext(context.Background(), []int{10, 11}, []int{7})
+			},
+			qpsHostAssetUpdate + ` <ServiceRequest><filters><Criteria field="id" operator="IN">10,11</Criteria></filters><data><HostAsset><tags><add><TagSimple><id>7</id></TagSimple></add></tags></HostAsset></data></ServiceRequest>`,
+		},
+		{
+			"remove from assets",
+			func(session *Session) error {
+				return session.RemoveTagsFromAssetsContext(context.Background(), []int{10}, []int{7, 8})
+			},
+			qpsHostAssetUpdate + ` <ServiceRequest><filters><Criteria field="id" operator="IN">10</Criteria></filters><data><HostAsset><tags><remove><TagSimple><id>7</id></TagSimple><TagSimple><id>8</id></TagSimple></remove></tags></HostAsset></data></ServiceRequest>`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var handler = &tagServer{}
+			var server = httptest.NewServer(handler)
+			defer server.Close()
+
+			var session = newTestSession(server, qpsTagCreate, qpsHostAssetUpdate, "/qps/rest/2.0/update/am/tag/7", "/qps/rest/2.0/delete/am/tag/7")
+			if err := test.call(session); err != nil {
+				t.Fatal(err)
+			}
+
+			if calls := handler.received(); len(calls) != 1 || calls[0] != test.expected {
+				t.Errorf("expected the call\n%s\ngot\n%v", test.expected, calls)
+			}
+		})
+	}
+}
+
+func TestTagCallsRejected(t *testing.T) {
+	var handler = &tagServer{}
+	var server = httptest.NewServer(handler)
+	defer server.Close()
+
+	var session = newTestSession(server)
+	if _, err := session.CreateTagContext(context.Background(), Tag{}); err == nil {
+		t.Error("expected a tag without a name to be rejected")
+	}
+
+	if err := session.AddTagsToAssetsContext(context.Background(), nil, []int{7}); err == nil {
+		t.Error("expected tagging without assets to be rejected")
+	}
+
+	if err := session.RemoveTagsFromAssetsContext(context.Background(), []int{10}, nil); err == nil {
+		t.Error("expected untagging without tags to be rejected")
+	}
+
+	if calls := handler.received(); len(calls) != 0 {
+		t.Errorf("expected nothing to be sent to Qualys, got %v", calls)
+	}
+}
@@ -1,6 +1,7 @@
 package qualys
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/nortonlifelock/log"
@@ -19,9 +20,14 @@ const (
 
 // findingUID in the form aaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaa
 func (session *Session) CreateRetestForWebAppVulnerabilityFinding(findingUID string) (count string, err error) {
+	return session.CreateRetestForWebAppVulnerabilityFindingContext(session.ctx, findingUID)
+}
+
+// CreateRetestForWebAppVulnerabilityFindingContext is CreateRetestForWebAppVulnerabilityFinding with a context that aborts the API calls when cancelled
+func (session *Session) CreateRetestForWebAppVulnerabilityFindingContext(ctx context.Context, findingUID string) (count string, err error) {
 	url := strings.Replace(session.webAppBaseURL+postRetestFinding, "<uid>", findingUID, 1)
 	resp := &webAppRetestFindingResponse{}
-	if err = session.httpCall(http.MethodPost, url, make(map[string]string), nil, resp); err == nil {
+	if err = session.httpCall(ctx, http.MethodPost, url, make(map[string]string), nil, resp); err == nil {
 		count = resp.Count
 	} else {
 		session.lstream.Send(log.Errorf(err, "err while calling api [%s]", url))
@@ -31,6 +37,11 @@ func (session *Session) CreateRetestForWebAppVulnerabilityFinding(findingUID str
 }
 
 func (session *Session) CreateWebAppVulnerabilityScan(webAppID string, webAppOptionProfileID string, scannerType string, scannerName string) (scanID string, title string, err error) {
+	return session.CreateWebAppVulnerabilityScanContext(session.ctx, webAppID, webAppOptionProfileID, scannerType, scannerName)
+}
+
+// CreateWebAppVulnerabilityScanContext is CreateWebAppVulnerabilityScan with a context that aborts the API calls when cancelled
+func (session *Session) CreateWebAppVulnerabilityScanContext(ctx context.Context, webAppID string, webAppOptionProfileID string, scannerType string, scannerName string) (scanID string, title string, err error) {
 	if len(webAppID) > 0 && len(webAppOptionProfileID) > 0 && len(scannerType) > 0 { // scanner name can be empty for externel scans
 		reqBody := &createWebAppScanRequest{}
 
@@ -54,7 +65,7 @@ func (session *Session) CreateWebAppVulnerabilityScan(webAppID string, webAppOpt
 
 			resp := &webAppScanResponse{}
 
-			if err = session.httpCall(http.MethodPost, session.webAppBaseURL+postLaunchScan, make(map[string]string), &reqBodyString, resp); err == nil {
+			if err = session.httpCall(ctx, http.MethodPost, session.webAppBaseURL+postLaunchScan, make(map[string]string), &reqBodyString, resp); err == nil {
 
 				if len(resp.Data.WasScan.ID) > 0 {
 					scanID = resp.Data.WasScan.ID
@@ -76,11 +87,16 @@ func (session *Session) CreateWebAppVulnerabilityScan(webAppID string, webAppOpt
 }
 
 func (session *Session) GetWebAppScanStatus(scanID string) (status string, err error) {
+	return session.GetWebAppScanStatusContext(session.ctx, scanID)
+}
+
+// GetWebAppScanStatusContext is GetWebAppScanStatus with a context that aborts the API calls when cancelled
+func (session *Session) GetWebAppScanStatusContext(ctx context.Context, scanID string) (status string, err error) {
 	url := strings.Replace(session.webAppBaseURL+getScanStatus, "<id>", scanID, 1)
 
 	resp := &webAppScanResponse{}
 
-	if err = session.httpCall(http.MethodGet, url, make(map[string]string), nil, resp); err == nil {
+	if err = session.httpCall(ctx, http.MethodGet, url, make(map[string]string), nil, resp); err == nil {
 		if len(resp.Data.WasScan.Status) > 0 {
 			status = resp.Data.WasScan.Status
 		} else {
@@ -94,64 +110,44 @@ func (session *Session) GetWebAppScanStatus(scanID string) (status string, err e
 }
 
 func (session *Session) GetVulnerabilitiesForSite(siteID string) (findings []*WebAppFinding, err error) {
-	var hasMoreRecords = true
-	var lastID = "0"
-	findings = make([]*WebAppFinding, 0)
+	return session.GetVulnerabilitiesForSiteContext(session.ctx, siteID)
+}
 
-	for hasMoreRecords {
-		reqBody := &WebAppFindingsRequest{}
+// GetVulnerabilitiesForSiteContext is GetVulnerabilitiesForSite with a context that aborts the API calls when cancelled
+func (session *Session) GetVulnerabilitiesForSiteContext(ctx context.Context, siteID string) (findings []*WebAppFinding, err error) {
+	findings = make([]*WebAppFinding, 0)
 
-		webCriteria := SearchCriteria{
-			Field:    "webApp.id",
-			Operator: "EQUALS",
-			Text:     siteID,
-		}
+	var request = NewQPSRequest(CriteriaEquals("webApp.id", siteID))
+	request.Preferences.Verbose = true
 
-		pagingCriteria := SearchCriteria{
-			Field:    "id",
-			Operator: "GREATER",
-			Text:     lastID,
+	var pager = session.NewQPSPager(session.webAppBaseURL+postGetSiteFindings, request, QPSXML).PageByID("id")
+	for {
+		var page webAppFindingsPage
+		if !pager.Next(ctx, &page) {
+			break
 		}
 
-		reqBody.Filters.Criteria = []SearchCriteria{webCriteria, pagingCriteria}
-
-		reqBody.Preferences.Verbose = "true"
-
-		resp := &webAppFindingsResponse{}
-
-		var reqBodyByte []byte
-		if reqBodyByte, err = xml.Marshal(reqBody); err == nil {
-			reqBodyString := string(reqBodyByte)
-
-			if err = session.httpCall(http.MethodPost, session.webAppBaseURL+postGetSiteFindings, make(map[string]string), &reqBodyString, resp); err == nil {
-				if len(resp.Data.Finding) > 0 {
-					findings = append(findings, resp.Data.Finding...)
+		findings = append(findings, page.Finding...)
+	}
 
-					hasMoreRecords = strings.ToLower(resp.HasMoreRecords) == "true"
-					lastID = resp.LastID
-				} else {
-					session.lstream.Send(log.Errorf(err, "could not find status from [%s]", postGetSiteFindings))
-					break
-				}
-			} else {
-				session.lstream.Send(log.Errorf(err, "err while calling api [%s]", postGetSiteFindings))
-				break
-			}
-		} else {
-			session.lstream.Send(log.Errorf(err, "error while marshalling GetVulnerabilitiesForSite body"))
-			break
-		}
+	if err = pager.Err(); err != nil {
+		session.lstream.Send(log.Errorf(err, "err while calling api [%s]", postGetSiteFindings))
 	}
 
 	return findings, err
 }
 
 func (session *Session) GetWebApplicationInfo(webAppID string) (defaultScannerName, defaultScannerType string, err error) {
+	return session.GetWebApplicationInfoContext(session.ctx, webAppID)
+}
+
+// GetWebApplicationInfoContext is GetWebApplicationInfo with a context that aborts the API calls when cancelled
+func (session *Session) GetWebApplicationInfoContext(ctx context.Context, webAppID string) (defaultScannerName, defaultScannerType string, err error) {
 	url := strings.Replace(session.webAppBaseURL+getWebAppInfo, "<id>", webAppID, 1)
 
 	resp := &getWebAppResponse{}
 
-	if err = session.httpCall(http.MethodGet, url, make(map[string]string), nil, resp); err == nil {
+	if err = session.httpCall(ctx, http.MethodGet, url, make(map[string]string), nil, resp); err == nil {
 		defaultScannerType = resp.Data.WebApp.DefaultScanner.Type
 		defaultScannerName = resp.Data.WebApp.DefaultScanner.FriendlyName
 	} else {
@@ -224,25 +220,9 @@ type WebAppFindingsRequest struct {
 	} `xml:"filters"`
 }
 
-type SearchCriteria struct {
-	Text     string `xml:",chardata"`
-	Field    string `xml:"field,attr"`
-	Operator string `xml:"operator,attr"`
-}
-
-type webAppFindingsResponse struct {
-	XMLName                   xml.Name `xml:"ServiceResponse"`
-	Text                      string   `xml:",chardata"`
-	Xsi                       string   `xml:"xsi,attr"`
-	NoNamespaceSchemaLocation string   `xml:"noNamespaceSchemaLocation,attr"`
-	ResponseCode              string   `xml:"responseCode"`
-	Count                     string   `xml:"count"`
-	HasMoreRecords            string   `xml:"hasMoreRecords"`
-	LastID                    string   `xml:"lastId"`
-	Data                      struct {
-		Text    string           `xml:",chardata"`
-		Finding []*WebAppFinding `xml:"Finding"`
-	} `xml:"data"`
+// webAppFindingsPage holds the findings within the data of a page of the WAS finding search
+type webAppFindingsPage struct {
+	Finding []*WebAppFinding `xml:"Finding"`
 }
 
 type WebAppFinding struct {
@@ -2,6 +2,7 @@ package connector
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/nortonlifelock/domain"
 	"github.com/nortonlifelock/log"
@@ -178,13 +179,13 @@ func (session *QsSession) isIPInRange(ip string, ipFrom string, ipTo string) boo
 
 // getAppliancesForAssetGroups loads asset groups from the Qualys API and using that information gets all scan
 // appliances from them after filtering them against the systems configured asset groups
-func (session *QsSession) getAssetGroups(assetGroups []int) (groups []*qualys.QSAssetGroup, err error) {
+func (session *QsSession) getAssetGroups(ctx context.Context, assetGroups []int) (groups []*qualys.QSAssetGroup, err error) {
 	if session.assetGroupCache == nil {
 		session.lstream.Send(log.Info("Loading asset groups with Engines from Qualys"))
 
 		// Load the asset groups from Qualys
 		var ags *qualys.QSAGListOutput
-		if ags, err = session.apiSession.LoadAssetGroups(assetGroups); err == nil {
+		if ags, err = session.apiSession.LoadAssetGroupsContext(ctx, assetGroups); err == nil {
 
 			if ags != nil {
 				session.lstream.Send(log.Info("Completed Loading asset groups with Engines from Qualys"))
@@ -219,7 +220,7 @@ func (session *QsSession) getAssetGroupWithOnlineAppliancesForIP(ip string, grou
 	return applicable, err
 }
 
-func (session *QsSession) populateOnlineAppliances(groups []*qualys.QSAssetGroup) (err error) {
+func (session *QsSession) populateOnlineAppliances(ctx context.Context, groups []*qualys.QSAssetGroup) (err error) {
 
 	var appliances = make([]string, 0)
 	var seenAppliance = make(map[string]bool)
@@ -244,7 +245,7 @@ func (session *QsSession) populateOnlineAppliances(groups []*qualys.QSAssetGroup
 
 	if len(appliances) > 0 {
 		var output *qualys.QAppliances
-		output, err = session.apiSession.GetApplianceInformation(appliances)
+		output, err = session.apiSession.GetApplianceInformationContext(ctx, appliances)
 
 		if err == nil {
 			for _, group := range groups {
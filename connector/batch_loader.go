@@ -0,0 +1,183 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// batchLoader coalesces the loads of integer keys, such as QIDs or host IDs, into batches that are loaded by a single
+// fetch. Concurrent loads of the same key share a single call, and the keys requested within the window are fetched
+// together with no more than size keys per fetch. A batch is fetched under a context of its own, which is cancelled once
+// every caller waiting on the batch has given up so that a batch nobody is waiting on stops loading
+type batchLoader struct {
+	lstream logger
+	size    int
+	window  time.Duration
+	fetch   func(ctx context.Context, keys []int) (found map[int]interface{}, err error)
+
+	lock    sync.Mutex
+	pending map[int]*batchCall
+	batch   *loaderBatch
+	timer   *time.Timer
+}
+
+// batchCall is a pending load of a key, which is shared by every caller requesting the key until its batch completes
+type batchCall struct {
+	done  chan bool
+	batch *loaderBatch
+	value interface{}
+	err   error
+}
+
+// loaderBatch is a set of keys fetched together along with the context the fetch is made under
+type loaderBatch struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	keys   []int
+
+	lock    sync.Mutex
+	waiters int
+}
+
+func newBatchLoader(lstream logger, size int, window time.Duration, fetch func(ctx context.Context, keys []int) (found map[int]interface{}, err error)) *batchLoader {
+	return &batchLoader{
+		lstream: lstream,
+		size:    size,
+		window:  window,
+		fetch:   fetch,
+		pending: make(map[int]*batchCall),
+	}
+}
+
+// load returns the value fetched for the key, or nil when the fetch did not return the key. The error of the fetch is
+// returned when it failed, and the error of ctx when ctx is cancelled before the batch holding the key was fetched
+func (loader *batchLoader) load(ctx context.Context, key int) (value interface{}, err error) {
+	loader.lock.Lock()
+	var call = loader.pending[key]
+	if call == nil || !call.batch.join() {
+		// the batch of a pending call that every caller has given up on is being cancelled, so the key is loaded again
+		call = &batchCall{done: make(chan bool), batch: loader.enqueue(key)}
+		loader.pending[key] = call
+	}
+	loader.lock.Unlock()
+
+	if err = call.batch.wait(ctx, call.done); err == nil {
+		value, err = call.value, call.err
+	}
+
+	return value, err
+}
+
+// enqueue adds the key to the batch that is being queued and joins the caller to the batch. A new batch is started when
+// no batch is queued or when every caller of the queued batch has given up on it. The lock of the loader must be held
+func (loader *batchLoader) enqueue(key int) (batch *loaderBatch) {
+	if loader.batch != nil && !loader.batch.join() {
+		loader.flush()
+	}
+
+	if loader.batch == nil {
+		loader.batch = newLoaderBatch()
+		loader.batch.join()
+
+		loader.timer = time.AfterFunc(loader.window, func() {
+			loader.lock.Lock()
+			defer loader.lock.Unlock()
+			loader.flush()
+		})
+	}
+
+	batch = loader.batch
+	batch.keys = append(batch.keys, key)
+
+	if len(batch.keys) >= loader.size {
+		loader.flush()
+	}
+
+	return batch
+}
+
+// flush starts fetching the queued batch, the lock of the loader must be held
+func (loader *batchLoader) flush() {
+	if loader.timer != nil {
+		loader.timer.Stop()
+		loader.timer = nil
+	}
+
+	if loader.batch != nil {
+		var batch = loader.batch
+		loader.batch = nil
+
+		go loader.run(batch)
+	}
+}
+
+// run fetches the batch and hands the results to its pending calls. The calls are completed even when the fetch panics
+// so that no caller is left waiting
+func (loader *batchLoader) run(batch *loaderBatch) {
+	defer handleRoutinePanic(loader.lstream)
+
+	var found map[int]interface{}
+	var err = fmt.Errorf("the load of [%d] keys was interrupted", len(batch.keys))
+	defer func() {
+		loader.complete(batch, found, err)
+	}()
+
+	if err = batch.ctx.Err(); err == nil {
+		found, err = loader.fetch(batch.ctx, batch.keys)
+	}
+}
+
+// complete hands the results of a batch to the calls that are still pending on the batch
+func (loader *batchLoader) complete(batch *loaderBatch, found map[int]interface{}, err error) {
+	loader.lock.Lock()
+	defer loader.lock.Unlock()
+
+	for _, key := range batch.keys {
+		if call := loader.pending[key]; call != nil && call.batch == batch {
+			delete(loader.pending, key)
+
+			call.value, call.err = found[key], err
+			close(call.done)
+		}
+	}
+
+	batch.cancel()
+}
+
+func newLoaderBatch() *loaderBatch {
+	var ctx, cancel = context.WithCancel(context.Background())
+	return &loaderBatch{ctx: ctx, cancel: cancel}
+}
+
+// join adds a caller to the batch. False is returned when every caller has already given up on the batch
+func (batch *loaderBatch) join() bool {
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+
+	if batch.ctx.Err() != nil {
+		return false
+	}
+
+	batch.waiters++
+	return true
+}
+
+// wait blocks until done is closed. A caller whose context is cancelled first gives up on the batch, and the batch is
+// cancelled when no other caller is waiting on it
+func (batch *loaderBatch) wait(ctx context.Context, done <-chan bool) (err error) {
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+
+		batch.lock.Lock()
+		if batch.waiters--; batch.waiters == 0 {
+			batch.cancel()
+		}
+		batch.lock.Unlock()
+	}
+
+	return err
+}
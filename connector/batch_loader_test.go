@@ -0,0 +1,202 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nortonlifelock/log"
+)
+
+// testLogger discards the logs of the connector under test
+type testLogger struct{}
+
+func (testLogger) Send(log.Log) {}
+
+// recordingFetch returns the key doubled for every even key and records the keys of each batch it fetches
+type recordingFetch struct {
+	lock    sync.Mutex
+	batches [][]int
+	err     error
+	block   chan bool
+}
+
+func (fetch *recordingFetch) fetch(ctx context.Context, keys []int) (found map[int]interface{}, err error) {
+	fetch.lock.Lock()
+	fetch.batches = append(fetch.batches, append([]int(nil), keys...))
+	fetch.lock.Unlock()
+
+	if fetch.block != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-fetch.block:
+		}
+	}
+
+	found = make(map[int]interface{})
+	for _, key := range keys {
+		if key%2 == 0 {
+			found[key] = key * 2
+		}
+	}
+
+	return found, fetch.err
+}
+
+func (fetch *recordingFetch) fetched() [][]int {
+	fetch.lock.Lock()
+	defer fetch.lock.Unlock()
+
+	return append([][]int(nil), fetch.batches...)
+}
+
+func TestBatchLoaderCoalesces(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []int
+		size    int
+		batches int
+		err     error
+	}{
+		{"single key", []int{2}, 10, 1, nil},
+		{"distinct keys share a batch", []int{1, 2, 3, 4}, 10, 1, nil},
+		{"duplicate keys share a call", []int{2, 2, 2, 4, 4}, 10, 1, nil},
+		{"batches split at the size", []int{1, 2, 3, 4, 5, 6}, 2, 3, nil},
+		{"fetch error returned to every caller", []int{1, 2}, 10, 1, fmt.Errorf("unavailable")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fetch = &recordingFetch{err: test.err}
+			var loader = newBatchLoader(testLogger{}, test.size, 50*time.Millisecond, fetch.fetch)
+
+			var wg sync.WaitGroup
+			var errs = make(chan error, len(test.keys))
+			for _, key := range test.keys {
+				wg.Add(1)
+				go func(key int) {
+					defer wg.Done()
+
+					value, err := loader.load(context.Background(), key)
+					if err != test.err {
+						errs <- fmt.Errorf("expected the error [%v] for key [%d], got [%v]", test.err, key, err)
+					} else if err == nil && key%2 == 0 && value != key*2 {
+						errs <- fmt.Errorf("expected [%d] for key [%d], got [%v]", key*2, key, value)
+					} else if err == nil && key%2 != 0 && value != nil {
+						errs <- fmt.Errorf("expected no value for key [%d], got [%v]", key, value)
+					}
+				}(key)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				t.Error(err)
+			}
+
+			var batches = fetch.fetched()
+			if len(batches) != test.batches {
+				t.Fatalf("expected [%d] batches, got %v", test.batches, batches)
+			}
+
+			var seen = make(map[int]bool)
+			for _, batch := range batches {
+				if len(batch) > test.size {
+					t.Errorf("batch %v is larger than [%d]", batch, test.size)
+				}
+
+				for _, key := range batch {
+					if seen[key] {
+						t.Errorf("key [%d] was fetched more than once", key)
+					}
+					seen[key] = true
+				}
+			}
+		})
+	}
+}
+
+func TestBatchLoaderCancelsAbandonedBatch(t *testing.T) {
+	var fetch = &recordingFetch{block: make(chan bool)}
+	var loader = newBatchLoader(testLogger{}, 1, time.Millisecond, fetch.fetch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var result = make(chan error)
+	go func() {
+		_, err := loader.load(ctx, 2)
+		result <- err
+	}()
+
+	// the fetch blocks until its context is cancelled, which only happens once the only caller gives up
+	for len(fetch.fetched()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := <-result; err != context.Canceled {
+		t.Fatalf("expected the caller to give up with [%v], got [%v]", context.Canceled, err)
+	}
+
+	// the key is loaded again by a new batch once the abandoned batch has been cancelled
+	close(fetch.block)
+	if value, err := loader.load(context.Background(), 2); err != nil || value != 4 {
+		t.Fatalf("expected the key to load again, got [%v] and [%v]", value, err)
+	}
+}
+
+func TestBatchLoaderKeepsBatchWhileCallersWait(t *testing.T) {
+	var fetch = &recordingFetch{block: make(chan bool)}
+	var loader = newBatchLoader(testLogger{}, 10, 10*time.Millisecond, fetch.fetch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var abandoned = make(chan error)
+	go func() {
+		_, err := loader.load(ctx, 2)
+		abandoned <- err
+	}()
+
+	var waiting = make(chan error)
+	go func() {
+		value, err := loader.load(context.Background(), 2)
+		if err == nil && value != 4 {
+			err = fmt.Errorf("expected [4], got [%v]", value)
+		}
+		waiting <- err
+	}()
+
+	// both callers must be waiting on the batch before the first gives up
+	for len(fetch.fetched()) == 0 || waiters(loader, 2) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := <-abandoned; err != context.Canceled {
+		t.Fatalf("expected the cancelled caller to give up, got [%v]", err)
+	}
+
+	close(fetch.block)
+	if err := <-waiting; err != nil {
+		t.Fatalf("expected the remaining caller to receive the value, got [%v]", err)
+	}
+
+	if batches := fetch.fetched(); len(batches) != 1 {
+		t.Errorf("expected a single batch, got %v", batches)
+	}
+}
+
+// waiters returns the number of callers waiting on the pending call of the key
+func waiters(loader *batchLoader, key int) int {
+	loader.lock.Lock()
+	defer loader.lock.Unlock()
+
+	if call := loader.pending[key]; call != nil {
+		call.batch.lock.Lock()
+		defer call.batch.lock.Unlock()
+		return call.batch.waiters
+	}
+
+	return 0
+}
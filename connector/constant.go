@@ -1,5 +1,17 @@
 package connector
 
+import "github.com/nortonlifelock/domain"
+
 const (
 	templateDelimiter = ":"
 )
+
+// Reference sources of the exploits and malware returned by References, which continue the reference types of the domain
+// package (CVE, MS and Vendor)
+const (
+	// ExploitReference is the source of the references to exploits Qualys correlates with a vulnerability
+	ExploitReference = domain.Vendor + 1
+
+	// MalwareReference is the source of the references to malware Qualys correlates with a vulnerability
+	MalwareReference = domain.Vendor + 2
+)
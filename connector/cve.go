@@ -0,0 +1,195 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"github.com/nortonlifelock/domain"
+	"github.com/nortonlifelock/log"
+	"github.com/nortonlifelock/qualys"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// loadCVEIndex returns the CVE index of the session, indexing the vulnerabilities of the knowledge base store the first
+// time the index is used
+func (session *QsSession) loadCVEIndex() (index *qualys.CVEIndex, err error) {
+	session.cveLock.Lock()
+	defer session.cveLock.Unlock()
+
+	if session.cves == nil {
+		var vulns []*qualys.QVulnerability
+		if vulns, err = session.knowledgeBaseStore().Vulnerabilities(); err == nil {
+			session.cves = qualys.NewCVEIndex(vulns...)
+		}
+	}
+
+	return session.cves, err
+}
+
+// storeVulnerabilities adds the vulnerabilities to the knowledge base store, and to the CVE index once it is in use
+func (session *QsSession) storeVulnerabilities(vulns []*qualys.QVulnerability) (err error) {
+	if err = session.knowledgeBaseStore().StoreVulnerabilities(vulns); err == nil {
+		session.cveLock.Lock()
+		defer session.cveLock.Unlock()
+
+		if session.cves != nil {
+			session.cves.Add(vulns...)
+		}
+	}
+
+	return err
+}
+
+// QIDsForCVE returns the QIDs that detect the CVE. CVEs that are not referenced by the knowledge base store are looked up
+// in the Qualys knowledge base
+func (session *QsSession) QIDsForCVE(ctx context.Context, cve string) (qids []int, err error) {
+	return session.qidsForCVEs(ctx, []string{cve})
+}
+
+// CVEsForQID returns the CVEs detected by the QID. QIDs that are not in the knowledge base store are loaded from Qualys
+func (session *QsSession) CVEsForQID(ctx context.Context, qid int) (cves []string, err error) {
+	var index *qualys.CVEIndex
+	if index, err = session.loadCVEIndex(); err == nil {
+		if !index.Indexed(qid) {
+			// the loader stores the vulnerability, which adds it to the index
+			_, err = session.qids.load(ctx, qid)
+		}
+
+		if err == nil {
+			cves = index.CVEsForQID(qid)
+		} else {
+			err = fmt.Errorf("error while loading QID [%d] - %s", qid, err.Error())
+		}
+	}
+
+	return cves, err
+}
+
+// qidsForCVEs returns the QIDs that detect any of the CVEs, loading the vulnerabilities of the CVEs that aren't indexed
+// from Qualys in a single request. CVEs that Qualys has no QIDs for are remembered for missingQIDTTL so that they aren't
+// looked up on every call
+func (session *QsSession) qidsForCVEs(ctx context.Context, cves []string) (qids []int, err error) {
+	var index *qualys.CVEIndex
+	if index, err = session.loadCVEIndex(); err == nil {
+		var unknown = make([]string, 0)
+		for _, cve := range cves {
+			if len(index.QIDsForCVE(cve)) == 0 && !session.missingCVE(cve) {
+				unknown = append(unknown, cve)
+			}
+		}
+
+		if len(unknown) > 0 {
+			var vulns []qualys.QVulnerability
+			if vulns, err = session.apiSession.LoadVulnerabilitiesByCVEContext(ctx, unknown); err == nil {
+				var stored = make([]*qualys.QVulnerability, 0, len(vulns))
+				for i := range vulns {
+					stored = append(stored, &vulns[i])
+				}
+
+				if err = session.storeVulnerabilities(stored); err == nil {
+					session.cveLock.Lock()
+					for _, cve := range unknown {
+						if len(index.QIDsForCVE(cve)) == 0 {
+							session.missingCVEs[strings.ToUpper(strings.TrimSpace(cve))] = time.Now()
+						}
+					}
+					session.cveLock.Unlock()
+				}
+			}
+		}
+
+		if err == nil {
+			var seen = make(map[int]bool)
+			for _, cve := range cves {
+				for _, qid := range index.QIDsForCVE(cve) {
+					if !seen[qid] {
+						seen[qid] = true
+						qids = append(qids, qid)
+					}
+				}
+			}
+		}
+	}
+
+	return qids, err
+}
+
+// missingCVE determines whether the CVE was recently found to have no QIDs in the Qualys knowledge base
+func (session *QsSession) missingCVE(cve string) (missing bool) {
+	session.cveLock.Lock()
+	defer session.cveLock.Unlock()
+
+	var missed time.Time
+	cve = strings.ToUpper(strings.TrimSpace(cve))
+	if missed, missing = session.missingCVEs[cve]; missing && time.Since(missed) >= missingQIDTTL {
+		delete(session.missingCVEs, cve)
+		missing = false
+	}
+
+	return missing
+}
+
+// DetectionsForCVEs returns the current detections of the QIDs that detect any of the CVEs. The ids select the hosts in
+// the same format as Detections, with tags prefixed by "tag-", and the asset groups of the payload are used when no ids
+// are passed. Web applications are not searched. Watermarks are neither used nor advanced, so the detections are loaded
+// regardless of when they were last synced
+func (session *QsSession) DetectionsForCVEs(ctx context.Context, cves []string, ids []string) (detections <-chan domain.Detection, err error) {
+	var out = make(chan domain.Detection)
+
+	var qids []int
+	if qids, err = session.qidsForCVEs(ctx, cves); err == nil {
+		if len(ids) == 0 {
+			for _, groupID := range session.payload.AssetGroups {
+				ids = append(ids, strconv.Itoa(groupID))
+			}
+		}
+
+		var tags = make([]string, 0)
+		var groupIDs = make([]string, 0)
+		for _, id := range ids {
+			if strings.Index(id, tagPrefix) >= 0 {
+				tags = append(tags, id[strings.Index(id, tagPrefix)+len(tagPrefix):])
+			} else if strings.Index(id, webPrefix) < 0 {
+				groupIDs = append(groupIDs, id)
+			}
+		}
+
+		go func(out chan<- domain.Detection) {
+			defer handleRoutinePanic(session.lstream)
+			defer close(out)
+
+			// a detection query without QIDs would load every detection of the hosts
+			if len(qids) == 0 {
+				session.lstream.Send(log.Warningf(nil, "no QIDs detect the CVEs [%s]", strings.Join(cves, ",")))
+				return
+			}
+
+			session.lstream.Send(log.Infof("Loading detections of [%d] QIDs for CVEs [%s]", len(qids), strings.Join(cves, ",")))
+
+			var processedDevVulns = make(map[string]bool)
+			var devVulnMutex = &sync.Mutex{}
+
+			if len(groupIDs) > 0 {
+				var query = qualys.NewHostDetectionQuery(groupIDs, session.payload.KernelFilter)
+				query.QIDs = qids
+				if err := session.pushDetectionsForQuery(ctx, query, "", devVulnMutex, processedDevVulns, out); err != nil {
+					session.lstream.Send(log.Errorf(err, "Error while loading host detections for CVEs from groups [%s]", strings.Join(groupIDs, ",")))
+				}
+			}
+
+			if len(tags) > 0 {
+				var query = qualys.NewTagDetectionQuery(tags, session.payload.KernelFilter)
+				query.QIDs = qids
+				if err := session.pushDetectionsForQuery(ctx, query, "", devVulnMutex, processedDevVulns, out); err != nil {
+					session.lstream.Send(log.Errorf(err, "Error while loading host detections for CVEs from tags [%s]", strings.Join(tags, ",")))
+				}
+			}
+		}(out)
+	} else {
+		close(out)
+	}
+
+	return out, err
+}
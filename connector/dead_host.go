@@ -2,19 +2,21 @@ package connector
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
+	"github.com/nortonlifelock/log"
 	"github.com/nortonlifelock/qualys"
 	"net"
 	"strings"
 	"time"
 )
 
-func (session *QsSession) getDeadHostsForScan(scanID string, created time.Time) (map[string]string, error) {
+func (session *QsSession) getDeadHostsForScan(ctx context.Context, scanID string, created time.Time) (map[string]string, error) {
 	var deadHostIPToProof = make(map[string]string)
 	var output *qualys.ScanSummaryOutput
 	var err error
-	if output, err = session.apiSession.GatherDeadHostsFoundSince(created); err == nil {
+	if output, err = session.apiSession.GatherDeadHostsFoundSinceContext(ctx, created); err == nil {
 		for _, scan := range output.Response.ScanSummaryList.ScanSummary {
 			if scan.ScanRef == scanID {
 				for _, host := range scan.HostSummary {
@@ -46,6 +48,23 @@ func (session *QsSession) getDeadHostsForScan(scanID string, created time.Time)
 	return deadHostIPToProof, err
 }
 
+// purgeDeadHostsForScan purges the hosts the scan found dead when the payload enables the purge of dead hosts. It is
+// called once the detections of the scan have been reported, as the purge removes the VM data of the hosts
+func (session *QsSession) purgeDeadHostsForScan(ctx context.Context, scanID string, deadHostIPToProof map[string]string) {
+	if session.payload != nil && session.payload.PurgeDeadHosts && len(deadHostIPToProof) > 0 {
+		var ips = make([]string, 0, len(deadHostIPToProof))
+		for ip := range deadHostIPToProof {
+			ips = append(ips, ip)
+		}
+
+		if hostIDs, err := session.PurgeDeadHosts(ctx, ips, session.payload.PurgeDryRun); err == nil {
+			session.lstream.Send(log.Infof("Purge of the dead hosts of scan [%s] selected the hosts %v [dry run: %v]", scanID, hostIDs, session.payload.PurgeDryRun))
+		} else {
+			session.lstream.Send(log.Errorf(err, "error while purging the dead hosts of scan [%s]", scanID))
+		}
+	}
+}
+
 // returns all IPs within a range of IPs (e.g. 100.0.0.0 - 100.0.0.100). If it is not a range, returns only the input IP
 func getAllIPsInRange(ipRange string) (allIPsInRange []string) {
 
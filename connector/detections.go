@@ -4,11 +4,98 @@ import (
 	"context"
 	"fmt"
 	"github.com/nortonlifelock/domain"
+	"github.com/nortonlifelock/log"
 	"github.com/nortonlifelock/qualys"
 	"sync"
+	"time"
 )
 
+// pushDetectionsForQuery loads the hosts matched by the query and pushes the detections of each host onto the channel. When
+// a watermark key is provided only the detections updated since the watermark of the key are loaded, and the watermark is
+// advanced to the time Qualys answered the first request of the sync once every host has been loaded and processed
+// without error. The time reported by Qualys is used rather than the local clock so that clock skew can't skip detections
+func (session *QsSession) pushDetectionsForQuery(ctx context.Context, query *qualys.DetectionQuery, key string, devVulnMutex *sync.Mutex, processedDevVulns map[string]bool, out chan<- domain.Detection) (err error) {
+	if len(key) > 0 {
+		var since time.Time
+		if since, err = session.watermarks.Watermark(key); err == nil {
+			if !since.IsZero() {
+				session.lstream.Send(log.Infof("Loading detections for [%s] updated since [%s]", key, since.Format(time.RFC3339)))
+				query.DetectionUpdatedSince = &since
+			} else {
+				session.lstream.Send(log.Infof("No watermark found for [%s], loading all detections", key))
+			}
+		}
+	}
+
+	var cursors []*qualys.HostDetectionCursor
+	if err == nil {
+		query.Partitions = session.payload.DetectionPartitions
+		cursors, err = session.apiSession.GetDetectionsCursors(ctx, query)
+	}
+
+	if err == nil {
+		var hosts = session.apiSession.MergeHostDetections(ctx, cursors)
+
+		wg := &sync.WaitGroup{}
+		func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case h, ok := <-hosts:
+					if ok {
+						wg.Add(1)
+						go func(h qualys.QHost) {
+							defer handleRoutinePanic(session.lstream)
+							defer wg.Done()
+							session.pushCombosForHost(ctx, h, devVulnMutex, processedDevVulns, out)
+						}(h)
+					} else {
+						return
+					}
+				}
+			}
+		}()
+		wg.Wait()
+
+		if err = ctx.Err(); err == nil {
+			if err = qualys.CursorsErr(cursors); err == nil && len(key) > 0 {
+				// the watermark is only advanced once the whole stream was processed so a failed sync is retried from the old watermark
+				if responded := earliestResponse(cursors); !responded.IsZero() {
+					var watermark = responded.Add(-watermarkOverlap)
+					if err = session.watermarks.SetWatermark(key, watermark); err == nil {
+						session.lstream.Send(log.Infof("Advanced watermark for [%s] to [%s]", key, watermark.Format(time.RFC3339)))
+					}
+				} else {
+					session.lstream.Send(log.Warningf(nil, "Qualys did not report the time of the detection responses for [%s], the watermark was not advanced", key))
+				}
+			}
+		}
+	}
+
+	return err
+}
+
+// earliestResponse returns the earliest time at which Qualys answered the first request of the cursors, or the zero time
+// when any of the cursors did not report the time of its response
+func earliestResponse(cursors []*qualys.HostDetectionCursor) (earliest time.Time) {
+	for index, cursor := range cursors {
+		var responded = cursor.ResponseTime()
+		if responded.IsZero() {
+			return time.Time{}
+		}
+
+		if index == 0 || responded.Before(earliest) {
+			earliest = responded
+		}
+	}
+
+	return earliest
+}
+
 func (session *QsSession) pushCombosForHost(ctx context.Context, h qualys.QHost, devVulnMutex *sync.Mutex, processedDevVulns map[string]bool, out chan<- domain.Detection) {
+	var wrapped = session.newHost(ctx, h)
+
 	for index := range h.Detections {
 		v := h.Detections[index]
 
@@ -40,11 +127,10 @@ func (session *QsSession) pushCombosForHost(ctx context.Context, h qualys.QHost,
 				case <-ctx.Done():
 					return
 				case out <- &hostDetectionCombo{
-					host: &host{
-						h: h,
-					},
+					host: wrapped,
 					detection: &detection{
 						d:       v,
+						ctx:     ctx,
 						session: session,
 					},
 				}:
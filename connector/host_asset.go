@@ -0,0 +1,94 @@
+package connector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nortonlifelock/log"
+	"github.com/nortonlifelock/qualys"
+)
+
+const (
+	// hostAssetBatchSize is the most host IDs whose host assets are searched for by a single request
+	hostAssetBatchSize = 500
+
+	// hostAssetBatchWindow is how long a host waits for other hosts to be enriched before the host assets of its batch are
+	// searched for. The hosts of a detection page are pushed for processing together, so they share a batch
+	hostAssetBatchWindow = 25 * time.Millisecond
+)
+
+// hostAssetLoader loads the host assets of the hosts in batches through the qwebHostId of the host assets, and caches the
+// host asset of every host it loaded for the life of the session. Hosts without a host asset and hosts whose batch failed
+// to load are cached as nil so that they aren't searched for again
+type hostAssetLoader struct {
+	session *QsSession
+	batches *batchLoader
+
+	lock   sync.Mutex
+	assets map[int]*qualys.HostAsset
+}
+
+func newHostAssetLoader(session *QsSession) (loader *hostAssetLoader) {
+	loader = &hostAssetLoader{
+		session: session,
+		assets:  make(map[int]*qualys.HostAsset),
+	}
+	loader.batches = newBatchLoader(session.lstream, hostAssetBatchSize, hostAssetBatchWindow, loader.fetch)
+
+	return loader
+}
+
+// load returns the host asset of the host ID, waiting for the batch holding the host to load the first time the host is
+// seen. Nil is returned when the host has no host asset or the host asset could not be loaded
+func (loader *hostAssetLoader) load(ctx context.Context, hostID int) (asset *qualys.HostAsset) {
+	loader.lock.Lock()
+	asset, cached := loader.assets[hostID]
+	loader.lock.Unlock()
+
+	if !cached {
+		if value, err := loader.batches.load(ctx, hostID); err == nil {
+			asset, _ = value.(*qualys.HostAsset)
+		}
+	}
+
+	return asset
+}
+
+// fetch searches for the host assets of the host IDs and caches the results. A failed search is cached along with the
+// misses unless it failed because every caller waiting on the batch gave up
+func (loader *hostAssetLoader) fetch(ctx context.Context, hostIDs []int) (found map[int]interface{}, err error) {
+	found = make(map[int]interface{}, len(hostIDs))
+
+	var assets map[int]*qualys.HostAsset
+	if assets, err = loader.session.apiSession.GetHostAssetsByHostIDs(ctx, hostIDs); err == nil || ctx.Err() == nil {
+		if err != nil {
+			loader.session.lstream.Send(log.Warningf(err, "could not load the host assets of [%d] hosts, the hosts will not be enriched", len(hostIDs)))
+		}
+
+		loader.lock.Lock()
+		for _, hostID := range hostIDs {
+			loader.assets[hostID] = assets[hostID]
+
+			if assets[hostID] != nil {
+				found[hostID] = assets[hostID]
+			}
+		}
+		loader.lock.Unlock()
+	}
+
+	return found, err
+}
+
+// newHost wraps the host, attaching its host asset when the payload enables the host asset enrichment
+func (session *QsSession) newHost(ctx context.Context, h qualys.QHost) *host {
+	var wrapped = &host{
+		h: h,
+	}
+
+	if session.payload != nil && session.payload.EnrichHostAssets {
+		wrapped.asset = session.hostAssets.load(ctx, h.HostID)
+	}
+
+	return wrapped
+}
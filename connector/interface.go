@@ -25,12 +25,25 @@ func (session *QsSession) KnowledgeBase(ctx context.Context, since *time.Time) <
 		var err error
 
 		start := time.Now()
-		if err = session.loadAndCacheQualysKB(since); err == nil {
-			session.lstream.Send(log.Infof("%d vulnerabilities loaded, took %s - beginning processing", len(session.vulnerabilities), time.Since(start).Round(time.Second)))
+		var vulns []*qualys.QVulnerability
+		if err = session.loadAndCacheQualysKB(ctx, since); err == nil {
+			vulns, err = session.knowledgeBaseStore().Vulnerabilities()
+		}
+
+		if err == nil {
+			// the store holds vulnerabilities from earlier syncs, so only those modified since the time are returned
+			var modified = make([]*qualys.QVulnerability, 0, len(vulns))
+			for _, vuln := range vulns {
+				if modifiedSince(vuln, since) {
+					modified = append(modified, vuln)
+				}
+			}
+
+			session.lstream.Send(log.Infof("%d vulnerabilities loaded, took %s - beginning processing", len(modified), time.Since(start).Round(time.Second)))
 
 			var wg = &sync.WaitGroup{}
 			var count = 0
-			for index := range session.vulnerabilities {
+			for index := range modified {
 
 				select {
 				case <-ctx.Done():
@@ -52,7 +65,7 @@ func (session *QsSession) KnowledgeBase(ctx context.Context, since *time.Time) <
 							return
 						case out <- &vulnerabilityInfo{v: v}:
 						}
-					}(session.vulnerabilities[index])
+					}(modified[index])
 				}
 			}
 
@@ -91,36 +104,22 @@ func (session *QsSession) Detections(ctx context.Context, ids []string) (detecti
 		if len(groupIDs) > 0 {
 			session.lstream.Send(log.Infof("Loading Detections from Qualys using group IDs [%s]", strings.Join(groupIDs, ",")))
 
-			var hosts <-chan qualys.QHost
-			if hosts, err = session.apiSession.GetHostDetections(groupIDs, session.payload.KernelFilter); err == nil {
-
-				var processedDevVulns = make(map[string]bool)
-				var devVulnMutex = &sync.Mutex{}
+			var processedDevVulns = make(map[string]bool)
+			var devVulnMutex = &sync.Mutex{}
 
-				wg := &sync.WaitGroup{}
-				func() {
-					for {
-						select {
-						case <-ctx.Done():
-							return
-						case h, ok := <-hosts:
-							if ok {
-								wg.Add(1)
-								go func(h qualys.QHost) {
-									defer handleRoutinePanic(session.lstream)
-									defer wg.Done()
-									session.pushCombosForHost(ctx, h, devVulnMutex, processedDevVulns, out)
-								}(h)
-							} else {
-								return
-							}
-						}
+			if session.watermarks != nil {
+				// each asset group is loaded on its own so that each group keeps its own watermark
+				for _, groupID := range groupIDs {
+					var query = qualys.NewHostDetectionQuery([]string{groupID}, session.payload.KernelFilter)
+					if err = session.pushDetectionsForQuery(ctx, query, groupWatermarkKey(groupID), devVulnMutex, processedDevVulns, out); err != nil {
+						session.lstream.Send(log.Errorf(err, "Error while loading host detections from Qualys for group [%s]", groupID))
 					}
-				}()
-				wg.Wait()
-
+				}
 			} else {
-				session.lstream.Send(log.Error("Error while loading host detections from Qualys", err))
+				var query = qualys.NewHostDetectionQuery(groupIDs, session.payload.KernelFilter)
+				if err = session.pushDetectionsForQuery(ctx, query, "", devVulnMutex, processedDevVulns, out); err != nil {
+					session.lstream.Send(log.Error("Error while loading host detections from Qualys", err))
+				}
 			}
 		}
 
@@ -129,7 +128,7 @@ func (session *QsSession) Detections(ctx context.Context, ids []string) (detecti
 				session.lstream.Send(log.Infof("Loading web application detection from Qualys using ID [%s]", webAppID))
 
 				var findings []*qualys.WebAppFinding
-				if findings, err = session.apiSession.GetVulnerabilitiesForSite(webAppID); err == nil {
+				if findings, err = session.apiSession.GetVulnerabilitiesForSiteContext(ctx, webAppID); err == nil {
 					for _, finding := range findings {
 
 						detectionWrapper := &webAppFindingWrapper{
@@ -154,35 +153,16 @@ func (session *QsSession) Detections(ctx context.Context, ids []string) (detecti
 		if len(tags) > 0 {
 			session.lstream.Send(log.Infof("Loading Detections from Qualys using tags [%s]", strings.Join(tags, ",")))
 
-			var hosts <-chan qualys.QHost
-			if hosts, err = session.apiSession.GetTagDetections(tags, session.payload.KernelFilter); err == nil {
-
-				var processedDevVulns = make(map[string]bool)
-				var devVulnMutex = &sync.Mutex{}
+			var processedDevVulns = make(map[string]bool)
+			var devVulnMutex = &sync.Mutex{}
 
-				wg := &sync.WaitGroup{}
-				func() {
-					for {
-						select {
-						case <-ctx.Done():
-							return
-						case h, ok := <-hosts:
-							if ok {
-								wg.Add(1)
-								go func(h qualys.QHost) {
-									defer handleRoutinePanic(session.lstream)
-									defer wg.Done()
-									session.pushCombosForHost(ctx, h, devVulnMutex, processedDevVulns, out)
-								}(h)
-							} else {
-								return
-							}
-						}
-					}
-				}()
-				wg.Wait()
+			var key string
+			if session.watermarks != nil {
+				key = tagWatermarkKey(tags)
+			}
 
-			} else {
+			var query = qualys.NewTagDetectionQuery(tags, session.payload.KernelFilter)
+			if err = session.pushDetectionsForQuery(ctx, query, key, devVulnMutex, processedDevVulns, out); err != nil {
 				session.lstream.Send(log.Error("Error while loading host detections from Qualys", err))
 			}
 		}
@@ -243,17 +223,17 @@ func (session *QsSession) pushDetectionsByScanTarget(ctx context.Context, scanIn
 
 	// Ask Qualys for the scan so we can find what IPs it scanned
 	var scan qualys.ScanQualys
-	scan, err = session.apiSession.GetScanByReference(scanInfo.ScanID)
+	scan, err = session.apiSession.GetScanByReferenceContext(ctx, scanInfo.ScanID)
 	if err == nil {
 		ipList := cleanIPList(scan.Target)
 
-		// Use the IPs to grab the host detections
-		var output *qualys.QHostListDetectionOutput
-		output, err = session.apiSession.GetHostSpecificDetections(ipList, []string{scanInfo.AssetGroupID}, session.payload.KernelFilter)
+		// Use the IPs to grab the host detections, the IPs are split into chunks which are each paged through
+		var cursors []*qualys.HostDetectionCursor
+		cursors, err = session.apiSession.GetHostSpecificDetectionsCursors(ipList, []string{scanInfo.AssetGroupID}, session.payload.KernelFilter)
 		if err == nil {
 
 			var deadHostIPToProof map[string]string
-			if deadHostIPToProof, err = session.getDeadHostsForScan(scanInfo.ScanID, scanInfo.Created); err == nil {
+			if deadHostIPToProof, err = session.getDeadHostsForScan(ctx, scanInfo.ScanID, scanInfo.Created); err == nil {
 
 				needToClose = false
 				go func() {
@@ -271,8 +251,19 @@ func (session *QsSession) pushDetectionsByScanTarget(ctx context.Context, scanIn
 					}
 				}()
 
-				if session.pushDetectionsOnChannel(ctx, output, deadHostIPToProof, out) {
-					return
+				for _, cursor := range cursors {
+					if session.pushDetectionsOnChannel(ctx, cursor.Hosts(ctx), deadHostIPToProof, out) {
+						return
+					}
+
+					if err = cursor.Err(); err != nil {
+						session.lstream.Send(log.Errorf(err, "error while loading host detections for scan %v", scanInfo.ScanID))
+						break
+					}
+				}
+
+				if err == nil {
+					session.purgeDeadHostsForScan(ctx, scanInfo.ScanID, deadHostIPToProof)
 				}
 			} else {
 				session.lstream.Send(log.Errorf(err, "error while loading dead hosts for scan %v", scanInfo.ScanID))
@@ -282,13 +273,13 @@ func (session *QsSession) pushDetectionsByScanTarget(ctx context.Context, scanIn
 			if scanInfo.TemplateID != strconv.Itoa(session.payload.DiscoveryOptionProfileID) && scanInfo.TemplateID != strconv.Itoa(session.payload.OptionProfileID) {
 				if len(scanInfo.TemplateID) > 0 {
 					templateFields := strings.Split(scanInfo.TemplateID, templateDelimiter)
-					if err = session.apiSession.DeleteOptionProfile(templateFields[0]); err != nil {
+					if err = session.apiSession.DeleteOptionProfileContext(ctx, templateFields[0]); err != nil {
 						session.lstream.Send(log.Errorf(err, "error while deleting option profile for scan %v", scanInfo.ScanID))
 					}
 
 					// search list only exists in vulnerability scans
 					if len(templateFields) > 1 {
-						if err = session.apiSession.DeleteSearchList(templateFields[1]); err != nil {
+						if err = session.apiSession.DeleteSearchListContext(ctx, templateFields[1]); err != nil {
 							session.lstream.Send(log.Errorf(err, "error while deleting search list for scan %v", scanInfo.ScanID))
 						}
 					}
@@ -336,8 +327,10 @@ func (session *QsSession) pushDetectionsByAssetGroup(ctx context.Context, out ch
 	}
 }
 
-func (session *QsSession) pushDetectionsOnChannel(ctx context.Context, output *qualys.QHostListDetectionOutput, deadHostIPToProof map[string]string, out chan<- domain.Detection) bool {
-	for _, h := range output.Hosts {
+func (session *QsSession) pushDetectionsOnChannel(ctx context.Context, hosts <-chan qualys.QHost, deadHostIPToProof map[string]string, out chan<- domain.Detection) bool {
+	for h := range hosts {
+		var wrapped = session.newHost(ctx, h)
+
 		for _, d := range h.Detections {
 
 			var unconfirmedDetection bool
@@ -357,11 +350,10 @@ func (session *QsSession) pushDetectionsOnChannel(ctx context.Context, output *q
 				case <-ctx.Done():
 					return true
 				case out <- &hostDetectionCombo{
-					host: &host{
-						h: h,
-					},
+					host: wrapped,
 					detection: &detection{
 						d:       d,
+						ctx:     ctx,
 						session: session,
 					},
 				}:
@@ -396,7 +388,7 @@ func (session *QsSession) Discovery(ctx context.Context, matches []domain.Match)
 
 		var err error
 		var groupIDToScanBundle map[string]*scanBundle
-		if groupIDToScanBundle, err = session.prepareIPsAndAGMapping(matches); err == nil {
+		if groupIDToScanBundle, err = session.prepareIPsAndAGMapping(ctx, matches); err == nil {
 			// wg to ensure we don't close the out channel before the threads finish
 			wg := &sync.WaitGroup{}
 
@@ -478,7 +470,7 @@ func (session *QsSession) Scans(ctx context.Context, payloads <-chan []byte) (sc
 
 							// the empty scan ID means that a scheduled scan isn't running with that name currently, or the recently created scan with that name hasn't had it's scan ID
 							// loaded yet. We check for a running scan and populate the scan ID of the scheduled
-							scheduledScan, err := session.apiSession.GetScheduledScan(scan.Name)
+							scheduledScan, err := session.apiSession.GetScheduledScanContext(ctx, scan.Name)
 							if err == nil {
 								if scheduledScan != nil {
 									seen[scan.Name] = true
@@ -1,25 +1,84 @@
 package connector
 
 import (
+	"context"
 	"github.com/nortonlifelock/log"
 	"github.com/nortonlifelock/qualys"
 	"time"
 )
 
-// loadAndCacheQualysKB loads ALL vulnerabilities from the Qualys KB into a map that is globally available on the session
-func (session *QsSession) loadAndCacheQualysKB(since *time.Time) (err error) {
-	var output *qualys.QKnowledgeBaseVulnOutput
-	if output, err = session.apiSession.LoadVulnerabilities(since); err == nil {
-		session.lstream.Send(log.Info("Vulnerabilities loaded. Beginning processing."))
+// knowledgeBaseBatch is the number of downloaded vulnerabilities passed to the knowledge base store at a time
+const knowledgeBaseBatch = 1000
+
+// loadAndCacheQualysKB refreshes the knowledge base store of the session. When the store has been synced before only the
+// vulnerabilities modified since the last sync are downloaded, otherwise the vulnerabilities modified since the time are
+// downloaded, or the ENTIRE knowledge base when no time is passed
+func (session *QsSession) loadAndCacheQualysKB(ctx context.Context, since *time.Time) (err error) {
+	session.knowledgeBaseLock.Lock()
+	defer session.knowledgeBaseLock.Unlock()
+
+	var start = time.Now()
+
+	var synced time.Time
+	if synced, err = session.knowledgeBaseStore().LastSync(); err == nil {
+		var from = since
+		if !synced.IsZero() {
+			// the store holds every vulnerability as of the last sync, so only the changes since then are needed
+			from = &synced
+		}
+
+		var batch = make([]*qualys.QVulnerability, 0, knowledgeBaseBatch)
 
 		// NOTE: DO NOT FILTER OUT POTENTIAL VULNERABILITIES HERE!!! POTENTIAL VULNERABILITIES CAN STILL BE ACTUAL
 		// VULNERABILITIES ON THE HOST WHEN DETECTED AS PART OF A SCAN
-		for _, v := range output.Vulnerabilities {
-			var vuln = v
-			// Add the vulnerability to the map on the session object
-			session.vulnerabilities[v.QualysID] = &vuln
+		if err = session.apiSession.DownloadVulnerabilities(ctx, qualys.KBDownload{Since: from}, func(vuln qualys.QVulnerability) (err error) {
+			if batch = append(batch, &vuln); len(batch) >= knowledgeBaseBatch {
+				err = session.storeVulnerabilities(batch)
+				batch = make([]*qualys.QVulnerability, 0, knowledgeBaseBatch)
+			}
+			return err
+		}); err == nil {
+			if err = session.storeVulnerabilities(batch); err == nil {
+				// a download limited to the time passed by the caller doesn't hold the whole knowledge base, so it is
+				// not recorded as a sync
+				if from == nil || !synced.IsZero() {
+					err = session.knowledgeBaseStore().SetLastSync(start)
+				}
+
+				if err == nil {
+					session.lstream.Send(log.Info("Vulnerabilities loaded. Beginning processing."))
+				}
+			}
 		}
 	}
 
 	return err
 }
+
+// refreshPersistedKnowledgeBase refreshes a persisted knowledge base when the session is created. A knowledge base that
+// has never been synced is left to be downloaded by KnowledgeBase, and a failed refresh only leaves the knowledge base
+// stale so it doesn't fail the session
+func (session *QsSession) refreshPersistedKnowledgeBase(ctx context.Context) {
+	if synced, err := session.knowledgeBaseStore().LastSync(); err == nil {
+		if !synced.IsZero() {
+			session.lstream.Send(log.Infof("Refreshing the persisted knowledge base with the vulnerabilities modified since [%s]", synced.Format(time.RFC3339)))
+
+			if err = session.loadAndCacheQualysKB(ctx, nil); err != nil {
+				session.lstream.Send(log.Warningf(err, "error while refreshing the persisted knowledge base"))
+			}
+		}
+	} else {
+		session.lstream.Send(log.Warningf(err, "error while reading the persisted knowledge base"))
+	}
+}
+
+// modifiedSince determines whether the vulnerability was modified by Qualys or customized by a user since the time. The
+// knowledge base API filters by date, so the time is compared at the same granularity
+func modifiedSince(vuln *qualys.QVulnerability, since *time.Time) bool {
+	if since == nil {
+		return true
+	}
+
+	var day = since.UTC().Truncate(24 * time.Hour)
+	return !vuln.LastServiceModification.Before(day) || (vuln.LastCustomization != nil && !vuln.LastCustomization.Date.Before(day))
+}
@@ -0,0 +1,305 @@
+package connector
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nortonlifelock/qualys"
+)
+
+// defaultKnowledgeBasePath is the file the knowledge base is persisted to when persistence is enabled without a path
+const defaultKnowledgeBasePath = "qualys_kb.jsonl"
+
+// KnowledgeBaseStore holds the vulnerabilities of the Qualys knowledge base along with the time of the last sync, so
+// that the knowledge base can be refreshed with only the vulnerabilities modified since then
+type KnowledgeBaseStore interface {
+	// Vulnerability returns the stored vulnerability of the QID, or nil when the QID is not stored
+	Vulnerability(qid int) (vuln *qualys.QVulnerability, err error)
+
+	// Vulnerabilities returns every stored vulnerability
+	Vulnerabilities() (vulns []*qualys.QVulnerability, err error)
+
+	// StoreVulnerabilities adds the vulnerabilities to the store, replacing those already stored under their QIDs
+	StoreVulnerabilities(vulns []*qualys.QVulnerability) (err error)
+
+	// LastSync returns the time of the last complete sync of the knowledge base, or the zero time when the knowledge base
+	// has not been synced
+	LastSync() (synced time.Time, err error)
+
+	// SetLastSync stores the time of the last complete sync of the knowledge base
+	SetLastSync(synced time.Time) (err error)
+}
+
+// memoryKnowledgeBaseStore holds the knowledge base in memory for the life of the session
+type memoryKnowledgeBaseStore struct {
+	vulns  map[int]*qualys.QVulnerability
+	synced time.Time
+	lock   sync.RWMutex
+}
+
+// NewMemoryKnowledgeBaseStore returns a KnowledgeBaseStore that holds the knowledge base in memory, so the knowledge
+// base is downloaded again by every new session
+func NewMemoryKnowledgeBaseStore() KnowledgeBaseStore {
+	return newMemoryKnowledgeBaseStore()
+}
+
+func newMemoryKnowledgeBaseStore() *memoryKnowledgeBaseStore {
+	return &memoryKnowledgeBaseStore{vulns: make(map[int]*qualys.QVulnerability)}
+}
+
+// Vulnerability returns the stored vulnerability of the QID
+func (store *memoryKnowledgeBaseStore) Vulnerability(qid int) (vuln *qualys.QVulnerability, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	return store.vulns[qid], nil
+}
+
+// Vulnerabilities returns every stored vulnerability
+func (store *memoryKnowledgeBaseStore) Vulnerabilities() (vulns []*qualys.QVulnerability, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	vulns = make([]*qualys.QVulnerability, 0, len(store.vulns))
+	for _, vuln := range store.vulns {
+		vulns = append(vulns, vuln)
+	}
+
+	return vulns, nil
+}
+
+// StoreVulnerabilities adds the vulnerabilities to the store
+func (store *memoryKnowledgeBaseStore) StoreVulnerabilities(vulns []*qualys.QVulnerability) (err error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	for _, vuln := range vulns {
+		if vuln != nil {
+			store.vulns[vuln.QualysID] = vuln
+		}
+	}
+
+	return nil
+}
+
+// LastSync returns the time of the last complete sync of the knowledge base
+func (store *memoryKnowledgeBaseStore) LastSync() (synced time.Time, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	return store.synced, nil
+}
+
+// SetLastSync stores the time of the last complete sync of the knowledge base
+func (store *memoryKnowledgeBaseStore) SetLastSync(synced time.Time) (err error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.synced = synced.UTC()
+	return nil
+}
+
+// knowledgeBaseRecord is a line of the file of a fileKnowledgeBaseStore, which holds either a vulnerability or the time
+// of the last sync
+type knowledgeBaseRecord struct {
+	Vulnerability *qualys.QVulnerability `json:"vulnerability,omitempty"`
+	LastSync      *time.Time             `json:"last_sync,omitempty"`
+}
+
+// fileKnowledgeBaseStore is the default persistent KnowledgeBaseStore, which serves lookups from memory and persists the
+// knowledge base as JSON lines in a file. Stored vulnerabilities are appended to the file so that caching a single QID
+// doesn't rewrite the whole knowledge base, and the file is compacted each time a sync completes
+type fileKnowledgeBaseStore struct {
+	path   string
+	memory *memoryKnowledgeBaseStore
+	loaded bool
+	lock   sync.Mutex
+}
+
+// NewFileKnowledgeBaseStore returns a KnowledgeBaseStore that persists the knowledge base to a file at the path. The file
+// is read the first time the store is used
+func NewFileKnowledgeBaseStore(path string) KnowledgeBaseStore {
+	return &fileKnowledgeBaseStore{path: path, memory: newMemoryKnowledgeBaseStore()}
+}
+
+// Vulnerability returns the stored vulnerability of the QID
+func (store *fileKnowledgeBaseStore) Vulnerability(qid int) (vuln *qualys.QVulnerability, err error) {
+	if err = store.load(); err == nil {
+		vuln, err = store.memory.Vulnerability(qid)
+	}
+
+	return vuln, err
+}
+
+// Vulnerabilities returns every stored vulnerability
+func (store *fileKnowledgeBaseStore) Vulnerabilities() (vulns []*qualys.QVulnerability, err error) {
+	if err = store.load(); err == nil {
+		vulns, err = store.memory.Vulnerabilities()
+	}
+
+	return vulns, err
+}
+
+// StoreVulnerabilities adds the vulnerabilities to the store and appends them to the file
+func (store *fileKnowledgeBaseStore) StoreVulnerabilities(vulns []*qualys.QVulnerability) (err error) {
+	if err = store.load(); err == nil {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+
+		var records = make([]knowledgeBaseRecord, 0, len(vulns))
+		for _, vuln := range vulns {
+			if vuln != nil {
+				records = append(records, knowledgeBaseRecord{Vulnerability: vuln})
+			}
+		}
+
+		if err = store.append(records); err == nil {
+			err = store.memory.StoreVulnerabilities(vulns)
+		}
+	}
+
+	return err
+}
+
+// LastSync returns the time of the last complete sync of the knowledge base
+func (store *fileKnowledgeBaseStore) LastSync() (synced time.Time, err error) {
+	if err = store.load(); err == nil {
+		synced, err = store.memory.LastSync()
+	}
+
+	return synced, err
+}
+
+// SetLastSync stores the time of the last complete sync and compacts the file, so that it holds a single line for each
+// QID. The file is replaced through a rename so that a crash while writing doesn't lose the knowledge base
+func (store *fileKnowledgeBaseStore) SetLastSync(synced time.Time) (err error) {
+	if err = store.load(); err == nil {
+		store.lock.Lock()
+		defer store.lock.Unlock()
+
+		if err = store.memory.SetLastSync(synced); err == nil {
+			var temp = fmt.Sprintf("%s.tmp", store.path)
+
+			var file *os.File
+			if file, err = os.OpenFile(temp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600); err == nil {
+				var writer = bufio.NewWriter(file)
+				var encoder = json.NewEncoder(writer)
+
+				var vulns []*qualys.QVulnerability
+				if vulns, err = store.memory.Vulnerabilities(); err == nil {
+					for _, vuln := range vulns {
+						if err = encoder.Encode(knowledgeBaseRecord{Vulnerability: vuln}); err != nil {
+							break
+						}
+					}
+				}
+
+				if err == nil {
+					var utc = synced.UTC()
+					if err = encoder.Encode(knowledgeBaseRecord{LastSync: &utc}); err == nil {
+						err = writer.Flush()
+					}
+				}
+
+				if closeErr := file.Close(); err == nil {
+					err = closeErr
+				}
+
+				if err == nil {
+					err = os.Rename(temp, store.path)
+				}
+			}
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("error while storing knowledge base to [%s] - %s", filepath.Clean(store.path), err.Error())
+	}
+
+	return err
+}
+
+// append writes the records to the end of the file, the lock of the store must be held
+func (store *fileKnowledgeBaseStore) append(records []knowledgeBaseRecord) (err error) {
+	var file *os.File
+	if file, err = os.OpenFile(store.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600); err == nil {
+		var writer = bufio.NewWriter(file)
+		var encoder = json.NewEncoder(writer)
+
+		for _, record := range records {
+			if err = encoder.Encode(record); err != nil {
+				break
+			}
+		}
+
+		if err == nil {
+			err = writer.Flush()
+		}
+
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("error while storing vulnerabilities to [%s] - %s", filepath.Clean(store.path), err.Error())
+	}
+
+	return err
+}
+
+// load reads the file into memory the first time the store is used. Later lines replace the vulnerabilities of earlier
+// lines with the same QID, and a missing file holds an empty knowledge base
+func (store *fileKnowledgeBaseStore) load() (err error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if !store.loaded {
+		var file *os.File
+		if file, err = os.OpenFile(store.path, os.O_RDWR, 0600); err == nil {
+			defer file.Close()
+
+			var reader = bufio.NewReader(file)
+			var complete int64
+			for err == nil {
+				var line []byte
+				if line, err = reader.ReadBytes('\n'); err == nil {
+					complete += int64(len(line))
+
+					var record knowledgeBaseRecord
+					if err = json.Unmarshal(line, &record); err == nil {
+						if record.Vulnerability != nil {
+							err = store.memory.StoreVulnerabilities([]*qualys.QVulnerability{record.Vulnerability})
+						}
+
+						if record.LastSync != nil {
+							err = store.memory.SetLastSync(*record.LastSync)
+						}
+					}
+				}
+			}
+
+			if err == io.EOF {
+				// a partial line at the end of the file is left by a crash while appending. It is cut from the file so
+				// the next append starts on a new line, and its vulnerabilities are downloaded again by the next refresh
+				err = file.Truncate(complete)
+			}
+		} else if os.IsNotExist(err) {
+			err = nil
+		}
+
+		if err == nil {
+			store.loaded = true
+		} else {
+			err = fmt.Errorf("error while loading knowledge base from [%s] - %s", filepath.Clean(store.path), err.Error())
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,207 @@
+package connector
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nortonlifelock/qualys"
+)
+
+// tempKnowledgeBase returns the path of a knowledge base file in a new temporary directory, and a function that removes
+// the directory
+func tempKnowledgeBase(t *testing.T) (path string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "qualys_kb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "kb.jsonl"), func() { _ = os.RemoveAll(dir) }
+}
+
+// storedQIDs returns the sorted QIDs and titles of the vulnerabilities of the store
+func storedQIDs(t *testing.T, store KnowledgeBaseStore) (qids []int, titles map[int]string) {
+	vulns, err := store.Vulnerabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles = make(map[int]string)
+	for _, vuln := range vulns {
+		qids = append(qids, vuln.QualysID)
+		titles[vuln.QualysID] = vuln.Title
+	}
+	sort.Ints(qids)
+
+	return qids, titles
+}
+
+func lines(t *testing.T, path string) int {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestFileKnowledgeBaseStoreRoundTrip(t *testing.T) {
+	path, cleanup := tempKnowledgeBase(t)
+	defer cleanup()
+
+	var synced = time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("EST", -5*60*60))
+
+	var store = NewFileKnowledgeBaseStore(path)
+	if err := store.StoreVulnerabilities([]*qualys.QVulnerability{{QualysID: 1, Title: "first"}, nil, {QualysID: 2, Title: "second"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.StoreVulnerabilities([]*qualys.QVulnerability{{QualysID: 1, Title: "first updated"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := lines(t, path); count != 3 {
+		t.Errorf("expected every stored vulnerability to be appended as a line, got [%d] lines", count)
+	}
+
+	// the appended lines are replayed in order, so the later line of a QID replaces the earlier one
+	var reopened = NewFileKnowledgeBaseStore(path)
+	qids, titles := storedQIDs(t, reopened)
+	if len(qids) != 2 || titles[1] != "first updated" || titles[2] != "second" {
+		t.Fatalf("expected QIDs 1 and 2 with the latest titles, got %v %v", qids, titles)
+	}
+
+	if last, err := reopened.LastSync(); err != nil || !last.IsZero() {
+		t.Fatalf("expected no sync before SetLastSync, got [%v] [%v]", last, err)
+	}
+
+	// completing a sync compacts the file to a single line for each QID followed by the time of the sync
+	if err := reopened.SetLastSync(synced); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := lines(t, path); count != 3 {
+		t.Errorf("expected the compacted file to hold [3] lines, got [%d]", count)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be renamed over the knowledge base, got [%v]", err)
+	}
+
+	var compacted = NewFileKnowledgeBaseStore(path)
+	if last, err := compacted.LastSync(); err != nil || !last.Equal(synced) {
+		t.Fatalf("expected the sync time [%v], got [%v] [%v]", synced, last, err)
+	}
+
+	if vuln, err := compacted.Vulnerability(1); err != nil || vuln == nil || vuln.Title != "first updated" {
+		t.Fatalf("expected QID 1 to survive the compaction, got [%v] [%v]", vuln, err)
+	}
+
+	if vuln, err := compacted.Vulnerability(3); err != nil || vuln != nil {
+		t.Fatalf("expected QID 3 to be missing, got [%v] [%v]", vuln, err)
+	}
+}
+
+func TestFileKnowledgeBaseStoreTruncatesPartialLine(t *testing.T) {
+	path, cleanup := tempKnowledgeBase(t)
+	defer cleanup()
+
+	var store = NewFileKnowledgeBaseStore(path)
+	if err := store.StoreVulnerabilities([]*qualys.QVulnerability{{QualysID: 1}, {QualysID: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a crash while appending leaves the last line unterminated
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.WriteString(`{"vulnerability":{"QualysID":3`)
+	_ = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reopened = NewFileKnowledgeBaseStore(path)
+	if qids, _ := storedQIDs(t, reopened); len(qids) != 2 || qids[0] != 1 || qids[1] != 2 {
+		t.Fatalf("expected only the complete lines to be loaded, got %v", qids)
+	}
+
+	// the partial line is cut, so the next append starts on a line of its own and the file loads again
+	if err = reopened.StoreVulnerabilities([]*qualys.QVulnerability{{QualysID: 4}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if qids, _ := storedQIDs(t, NewFileKnowledgeBaseStore(path)); len(qids) != 3 || qids[2] != 4 {
+		t.Fatalf("expected QIDs 1, 2 and 4 after the append, got %v", qids)
+	}
+}
+
+func TestFileKnowledgeBaseStoreMissingFile(t *testing.T) {
+	path, cleanup := tempKnowledgeBase(t)
+	defer cleanup()
+
+	var store = NewFileKnowledgeBaseStore(path)
+	if qids, _ := storedQIDs(t, store); len(qids) != 0 {
+		t.Fatalf("expected an empty knowledge base, got %v", qids)
+	}
+
+	if last, err := store.LastSync(); err != nil || !last.IsZero() {
+		t.Fatalf("expected no sync, got [%v] [%v]", last, err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected reading the store not to create the file, got [%v]", err)
+	}
+}
+
+func TestFileKnowledgeBaseStoreCorruptLine(t *testing.T) {
+	path, cleanup := tempKnowledgeBase(t)
+	defer cleanup()
+
+	if err := ioutil.WriteFile(path, []byte("not json\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileKnowledgeBaseStore(path).Vulnerabilities(); err == nil {
+		t.Fatal("expected a corrupt line to fail the load")
+	}
+}
+
+func TestSetKnowledgeBaseStoreConcurrentUse(t *testing.T) {
+	var session = &QsSession{
+		knowledgeBase:     NewMemoryKnowledgeBaseStore(),
+		knowledgeBaseLock: &sync.Mutex{},
+		storeLock:         &sync.RWMutex{},
+		cveLock:           &sync.Mutex{},
+	}
+
+	var replacement = NewMemoryKnowledgeBaseStore()
+	if err := replacement.StoreVulnerabilities([]*qualys.QVulnerability{{QualysID: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			session.SetKnowledgeBaseStore(replacement)
+		}()
+
+		go func() {
+			defer wg.Done()
+			_, _ = session.knowledgeBaseStore().Vulnerability(1)
+		}()
+	}
+	wg.Wait()
+
+	if vuln, err := session.knowledgeBaseStore().Vulnerability(1); err != nil || vuln == nil {
+		t.Errorf("expected the vulnerability of the replacement store, got [%v] [%v]", vuln, err)
+	}
+}
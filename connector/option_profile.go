@@ -1,6 +1,7 @@
 package connector
 
 import (
+	"context"
 	"fmt"
 	"github.com/nortonlifelock/domain"
 	"github.com/nortonlifelock/qualys"
@@ -42,13 +43,13 @@ func (session *QsSession) deleteSearchListAndOptionProfile(scan domain.ScanSumma
 	return err
 }
 
-func (session *QsSession) createCopyOfOptionProfile(optionProfileToCopy int) (optionProfileID string, err error) {
+func (session *QsSession) createCopyOfOptionProfile(ctx context.Context, optionProfileToCopy int) (optionProfileID string, err error) {
 	var optionProfileTemplate *qualys.OptionProfiles
-	if optionProfileTemplate, err = session.apiSession.GetOptionProfile(optionProfileToCopy); err == nil {
+	if optionProfileTemplate, err = session.apiSession.GetOptionProfileContext(ctx, optionProfileToCopy); err == nil {
 		if optionProfileTemplate.OptionProfile.BasicInfo.ID == strconv.Itoa(optionProfileToCopy) {
 			var title = fmt.Sprintf(session.payload.OptionProfileFormatString, strconv.Itoa(time.Now().Nanosecond()))
 			optionProfileTemplate.OptionProfile.BasicInfo.GroupName = title
-			optionProfileID, err = session.apiSession.CreateOptionProfile(optionProfileTemplate)
+			optionProfileID, err = session.apiSession.CreateOptionProfileContext(ctx, optionProfileTemplate)
 		} else {
 			err = fmt.Errorf("could not find option profile [%v]", optionProfileToCopy)
 		}
@@ -57,11 +58,11 @@ func (session *QsSession) createCopyOfOptionProfile(optionProfileToCopy int) (op
 	return optionProfileID, err
 }
 
-func (session *QsSession) createOptionProfileWithSearchList(QIDs []string, optionProfileToCopy int) (optionProfileID string, searchListID string, err error) {
+func (session *QsSession) createOptionProfileWithSearchList(ctx context.Context, QIDs []string, optionProfileToCopy int) (optionProfileID string, searchListID string, err error) {
 	var searchListTitle string
-	if searchListID, searchListTitle, err = session.apiSession.CreateSearchList(QIDs, session.payload.SearchListFormatString); err == nil {
+	if searchListID, searchListTitle, err = session.apiSession.CreateSearchListContext(ctx, QIDs, session.payload.SearchListFormatString); err == nil {
 		var optionProfileTemplate *qualys.OptionProfiles
-		if optionProfileTemplate, err = session.apiSession.GetOptionProfile(optionProfileToCopy); err == nil {
+		if optionProfileTemplate, err = session.apiSession.GetOptionProfileContext(ctx, optionProfileToCopy); err == nil {
 
 			var title = fmt.Sprintf(session.payload.OptionProfileFormatString, strconv.Itoa(time.Now().Nanosecond()))
 			optionProfileTemplate.OptionProfile.BasicInfo.GroupName = title
@@ -70,7 +71,7 @@ func (session *QsSession) createOptionProfileWithSearchList(QIDs []string, optio
 				Title: searchListTitle,
 			})
 
-			optionProfileID, err = session.apiSession.CreateOptionProfile(optionProfileTemplate)
+			optionProfileID, err = session.apiSession.CreateOptionProfileContext(ctx, optionProfileTemplate)
 		} else {
 			err = fmt.Errorf("error while gathering the option profile template - %s", err.Error())
 		}
@@ -0,0 +1,40 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nortonlifelock/log"
+	"github.com/nortonlifelock/qualys"
+)
+
+// defaultPurgeGracePeriod is the time a dead host must go without being found alive before it is purged when the payload
+// does not set a grace period
+const defaultPurgeGracePeriod = 30 * 24 * time.Hour
+
+// PurgeDeadHosts purges the VM data of the hosts of the IPs, which should have been confirmed dead, once they have gone
+// without being found alive by a VM scan for the grace period of the payload. Hosts found alive within the grace period
+// are left in place. When dryRun is set nothing is purged and the IDs of the hosts that would be purged are returned
+func (session *QsSession) PurgeDeadHosts(ctx context.Context, ips []string, dryRun bool) (hostIDs []int, err error) {
+	if len(ips) > 0 {
+		var grace = defaultPurgeGracePeriod
+		if session.payload != nil && session.payload.PurgeGracePeriodDays > 0 {
+			grace = time.Duration(session.payload.PurgeGracePeriodDays) * 24 * time.Hour
+		}
+
+		var since = time.Now().Add(-grace)
+		if hostIDs, err = session.apiSession.PurgeHostsContext(ctx, &qualys.PurgeQuery{
+			IPs:           ips,
+			NoVMScanSince: &since,
+			DataScope:     qualys.PurgeScopeVM,
+			DryRun:        dryRun,
+		}); err == nil {
+			session.lstream.Send(log.Infof("Purge of dead hosts not scanned since [%s] selected [%d] hosts [dry run: %v]", since.Format(time.RFC3339), len(hostIDs), dryRun))
+		}
+	} else {
+		err = fmt.Errorf("no IPs passed to purge")
+	}
+
+	return hostIDs, err
+}
@@ -0,0 +1,112 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nortonlifelock/log"
+	"github.com/nortonlifelock/qualys"
+)
+
+const (
+	// qidBatchSize is the most QIDs loaded from the knowledge base by a single request
+	qidBatchSize = 500
+
+	// qidBatchWindow is how long a QID waits for other QIDs to be requested before its batch is loaded
+	qidBatchWindow = 25 * time.Millisecond
+
+	// missingQIDTTL is how long a QID that is not in the knowledge base is remembered before it is requested again
+	missingQIDTTL = 6 * time.Hour
+)
+
+// errMissingQID is returned for QIDs that are not in the knowledge base
+var errMissingQID = fmt.Errorf("QID not found in the Qualys knowledge base")
+
+// qidLoader loads the vulnerabilities of QIDs that are missing from the knowledge base store. Concurrent requests for the
+// same QID share a single load, and the QIDs requested within a short window are loaded together by a single request.
+// QIDs that Qualys doesn't return are remembered so that they aren't requested for every detection
+type qidLoader struct {
+	session *QsSession
+	batches *batchLoader
+
+	// lookup loads the vulnerabilities of the QIDs from the Qualys knowledge base
+	lookup func(ctx context.Context, qids []int) (vulns []qualys.QVulnerability, err error)
+
+	lock    sync.Mutex
+	missing map[int]time.Time
+}
+
+func newQIDLoader(session *QsSession) (loader *qidLoader) {
+	loader = &qidLoader{
+		session: session,
+		missing: make(map[int]time.Time),
+		lookup: func(ctx context.Context, qids []int) ([]qualys.QVulnerability, error) {
+			return session.apiSession.LoadVulnerabilitiesByIDContext(ctx, qids)
+		},
+	}
+	loader.batches = newBatchLoader(session.lstream, qidBatchSize, qidBatchWindow, loader.fetch)
+
+	return loader
+}
+
+// load returns the vulnerability of the QID, waiting for the batch holding the QID to load. The wait is abandoned when ctx
+// is cancelled, and the batch stops loading once every caller waiting on it has given up
+func (loader *qidLoader) load(ctx context.Context, qid int) (vuln *qualys.QVulnerability, err error) {
+	loader.lock.Lock()
+	missed, isMissing := loader.missing[qid]
+	if isMissing && time.Since(missed) >= missingQIDTTL {
+		delete(loader.missing, qid)
+		isMissing = false
+	}
+	loader.lock.Unlock()
+
+	if isMissing {
+		return nil, errMissingQID
+	}
+
+	var value interface{}
+	if value, err = loader.batches.load(ctx, qid); err == nil {
+		if vuln, _ = value.(*qualys.QVulnerability); vuln == nil {
+			err = errMissingQID
+		}
+	}
+
+	return vuln, err
+}
+
+// fetch loads the QIDs from Qualys and adds the vulnerabilities to the knowledge base store. The QIDs are only remembered
+// as missing when the load succeeded
+func (loader *qidLoader) fetch(ctx context.Context, qids []int) (found map[int]interface{}, err error) {
+	found = make(map[int]interface{}, len(qids))
+
+	var vulns []qualys.QVulnerability
+	if vulns, err = loader.lookup(ctx, qids); err == nil {
+		var stored = make([]*qualys.QVulnerability, 0, len(vulns))
+		for index := range vulns {
+			found[vulns[index].QualysID] = &vulns[index]
+			stored = append(stored, &vulns[index])
+		}
+
+		if storeErr := loader.session.storeVulnerabilities(stored); storeErr != nil {
+			loader.session.lstream.Send(log.Errorf(storeErr, "error while storing [%d] vulnerabilities", len(stored)))
+		}
+
+		var missing int
+		loader.lock.Lock()
+		for _, qid := range qids {
+			if _, ok := found[qid]; !ok {
+				loader.missing[qid] = time.Now()
+				missing++
+			}
+		}
+		loader.lock.Unlock()
+
+		if missing > 0 {
+			loader.session.lstream.Send(log.Warningf(errMissingQID, "[%d] of [%d] QIDs were not found in the Qualys knowledge base", missing, len(qids)))
+		}
+	}
+
+	return found, err
+}
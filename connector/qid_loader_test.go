@@ -0,0 +1,203 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nortonlifelock/qualys"
+)
+
+// knowledgeBaseLookup serves the QIDs below 100 as vulnerabilities of the knowledge base and records the QIDs of every
+// lookup it receives
+type knowledgeBaseLookup struct {
+	lock    sync.Mutex
+	lookups [][]int
+	err     error
+}
+
+func (kb *knowledgeBaseLookup) lookup(ctx context.Context, qids []int) (vulns []qualys.QVulnerability, err error) {
+	kb.lock.Lock()
+	defer kb.lock.Unlock()
+
+	var sorted = append([]int(nil), qids...)
+	sort.Ints(sorted)
+	kb.lookups = append(kb.lookups, sorted)
+
+	if kb.err == nil {
+		for _, qid := range qids {
+			if qid < 100 {
+				vulns = append(vulns, qualys.QVulnerability{QualysID: qid, Title: fmt.Sprintf("QID %d", qid)})
+			}
+		}
+	}
+
+	return vulns, kb.err
+}
+
+func (kb *knowledgeBaseLookup) requested() [][]int {
+	kb.lock.Lock()
+	defer kb.lock.Unlock()
+
+	return append([][]int(nil), kb.lookups...)
+}
+
+func newTestQIDLoader(kb *knowledgeBaseLookup) (session *QsSession) {
+	session = &QsSession{
+		lstream:       testLogger{},
+		knowledgeBase: NewMemoryKnowledgeBaseStore(),
+		storeLock:     &sync.RWMutex{},
+		cveLock:       &sync.Mutex{},
+	}
+	session.qids = newQIDLoader(session)
+	session.qids.lookup = kb.lookup
+
+	return session
+}
+
+// loadAll loads the QIDs concurrently and returns the error of each load
+func loadAll(loader *qidLoader, qids ...int) (errs map[int]error) {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	errs = make(map[int]error)
+
+	for _, qid := range qids {
+		wg.Add(1)
+		go func(qid int) {
+			defer wg.Done()
+
+			vuln, err := loader.load(context.Background(), qid)
+			if err == nil && (vuln == nil || vuln.QualysID != qid) {
+				err = fmt.Errorf("expected the vulnerability of QID [%d], got [%v]", qid, vuln)
+			}
+
+			lock.Lock()
+			errs[qid] = err
+			lock.Unlock()
+		}(qid)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+func TestQIDLoaderCoalesces(t *testing.T) {
+	var kb = &knowledgeBaseLookup{}
+	var session = newTestQIDLoader(kb)
+
+	for qid, err := range loadAll(session.qids, 1, 2, 2, 3, 3, 3) {
+		if err != nil {
+			t.Errorf("QID [%d]: %v", qid, err)
+		}
+	}
+
+	// the loads requested within the window share a lookup, and concurrent loads of a QID share a call
+	var looked = make(map[int]int)
+	for _, lookup := range kb.requested() {
+		for _, qid := range lookup {
+			looked[qid]++
+		}
+	}
+
+	if len(looked) != 3 || looked[1] != 1 || looked[2] != 1 || looked[3] != 1 {
+		t.Fatalf("expected QIDs 1, 2 and 3 to be looked up once each, got the lookups %v", kb.requested())
+	}
+
+	// the loaded vulnerabilities are added to the knowledge base store
+	if vuln, err := session.knowledgeBase.Vulnerability(2); err != nil || vuln == nil {
+		t.Errorf("expected QID 2 to be stored, got [%v] [%v]", vuln, err)
+	}
+}
+
+func TestQIDLoaderNegativeCache(t *testing.T) {
+	var kb = &knowledgeBaseLookup{}
+	var session = newTestQIDLoader(kb)
+
+	var errs = loadAll(session.qids, 5, 500)
+	if errs[5] != nil || errs[500] != errMissingQID {
+		t.Fatalf("expected QID 5 to load and QID 500 to be missing, got %v", errs)
+	}
+
+	// a missing QID is answered from the negative cache until the TTL expires
+	if _, err := session.qids.load(context.Background(), 500); err != errMissingQID {
+		t.Fatalf("expected QID 500 to be missing, got [%v]", err)
+	}
+
+	if lookups := kb.requested(); len(lookups) != 1 {
+		t.Fatalf("expected the missing QID to be remembered, got the lookups %v", lookups)
+	}
+
+	session.qids.lock.Lock()
+	session.qids.missing[500] = time.Now().Add(-missingQIDTTL)
+	session.qids.lock.Unlock()
+
+	if _, err := session.qids.load(context.Background(), 500); err != errMissingQID {
+		t.Fatalf("expected QID 500 to be missing, got [%v]", err)
+	}
+
+	if lookups := kb.requested(); len(lookups) != 2 || fmt.Sprint(lookups[1]) != "[500]" {
+		t.Fatalf("expected the missing QID to be requested again once the TTL expired, got the lookups %v", lookups)
+	}
+}
+
+func TestQIDLoaderFailureNotCached(t *testing.T) {
+	var kb = &knowledgeBaseLookup{err: fmt.Errorf("unavailable")}
+	var session = newTestQIDLoader(kb)
+
+	if _, err := session.qids.load(context.Background(), 500); err != kb.err {
+		t.Fatalf("expected the error of the lookup, got [%v]", err)
+	}
+
+	kb.lock.Lock()
+	kb.err = nil
+	kb.lock.Unlock()
+
+	if vuln, err := session.qids.load(context.Background(), 7); err != nil || vuln == nil {
+		t.Fatalf("expected QID 7 to load, got [%v] [%v]", vuln, err)
+	}
+
+	// a failed lookup doesn't remember the QIDs as missing
+	if _, err := session.qids.load(context.Background(), 500); err != errMissingQID {
+		t.Fatalf("expected QID 500 to be looked up again, got [%v]", err)
+	}
+
+	if lookups := kb.requested(); len(lookups) != 3 {
+		t.Fatalf("expected every load to look up its QID, got %v", lookups)
+	}
+}
+
+func TestQIDLoaderCancelled(t *testing.T) {
+	var session = newTestQIDLoader(&knowledgeBaseLookup{})
+
+	var block = make(chan bool)
+	var cancelled = make(chan error, 1)
+	session.qids.lookup = func(ctx context.Context, qids []int) ([]qualys.QVulnerability, error) {
+		select {
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+			return nil, ctx.Err()
+		case <-block:
+			return nil, nil
+		}
+	}
+	defer close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := session.qids.load(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("expected the load to give up with the context, got [%v]", err)
+	}
+
+	select {
+	case err := <-cancelled:
+		if err != context.Canceled {
+			t.Fatalf("expected the lookup to be cancelled, got [%v]", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the lookup to be cancelled once its only caller gave up")
+	}
+}
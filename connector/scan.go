@@ -6,7 +6,7 @@ import (
 	"github.com/nortonlifelock/domain"
 	"github.com/nortonlifelock/log"
 	"github.com/nortonlifelock/qualys"
-	"regexp"
+	"github.com/pkg/errors"
 	"strconv"
 	"strings"
 	"sync"
@@ -72,14 +72,14 @@ func (session *QsSession) createVulnerabilityScanForGroup(ctx context.Context, o
 	var optionProfileID, searchListID string
 
 	if len(bundle.devices) > 0 && len(bundle.vulns) > 0 {
-		if optionProfileID, searchListID, err = session.createOptionProfileWithSearchList(bundle.vulns, session.payload.OptionProfileID); err == nil {
+		if optionProfileID, searchListID, err = session.createOptionProfileWithSearchList(ctx, bundle.vulns, session.payload.OptionProfileID); err == nil {
 
 			var scanCreationFunctions = make([]func() (string, string, error), 0)
 
 			if session.payload.EC2ScanSettings[bundle.groupID] == nil {
 				scanCreationFunctions = append(scanCreationFunctions, func() (string, string, error) {
 					var scanTitle = fmt.Sprintf(session.payload.ScanNameFormatString, time.Now().Format(time.RFC3339))
-					_, scanRef, err = session.apiSession.CreateScan(scanTitle, optionProfileID, intArrayToStringArray(bundle.appliances), bundle.networkID, bundle.devices, bundle.external)
+					_, scanRef, err = session.apiSession.CreateScanContext(ctx, scanTitle, optionProfileID, intArrayToStringArray(bundle.appliances), bundle.networkID, bundle.devices, bundle.external)
 					return scanTitle, scanRef, err
 				})
 			} else {
@@ -105,7 +105,7 @@ func (session *QsSession) createVulnerabilityScanForGroup(ctx context.Context, o
 								bundle.seenDevice[instanceID] = true
 							}
 							var scanTitle = fmt.Sprintf(session.payload.ScanNameFormatString, time.Now().Format(time.RFC3339))
-							_, scanRef, err = session.apiSession.CreateEC2Scan(scanTitle, optionProfileID, instancesCoveredInThisScan, region, settings.ConnectorName, settings.ScannerName)
+							_, scanRef, err = session.apiSession.CreateEC2ScanContext(ctx, scanTitle, optionProfileID, instancesCoveredInThisScan, region, settings.ConnectorName, settings.ScannerName)
 							return scanTitle, scanRef, err
 						})
 					}
@@ -208,7 +208,7 @@ func (session *QsSession) createScanForWebApplication(ctx context.Context, detec
 		if !seen[findingUID] {
 			seen[findingUID] = true
 
-			_, err := session.apiSession.CreateRetestForWebAppVulnerabilityFinding(findingUID)
+			_, err := session.apiSession.CreateRetestForWebAppVulnerabilityFindingContext(ctx, findingUID)
 			if err == nil {
 				scan := &scan{
 					Name:       fmt.Sprintf("was_aegis_retest_%s_%s", findingUID, time.Now().Format(time.RFC3339)),
@@ -238,7 +238,7 @@ func (session *QsSession) createScanForDetections(ctx context.Context, detection
 	var err error
 
 	var groupIDToScanBundle map[string]*scanBundle
-	if groupIDToScanBundle, err = session.prepareIPsAndAGMapping(detections); err == nil {
+	if groupIDToScanBundle, err = session.prepareIPsAndAGMapping(ctx, detections); err == nil {
 		if err = session.populateGroupVulnerabilityChecks(detections, groupIDToScanBundle); err == nil {
 			// wg to ensure we don't close the out channel before the writing threads finish
 			wg := &sync.WaitGroup{}
@@ -277,10 +277,10 @@ func (session *QsSession) createDiscoveryScanForGroup(ctx context.Context, out c
 			var scanRef string
 			var optionProfileID string
 
-			if optionProfileID, err = session.createCopyOfOptionProfile(session.payload.DiscoveryOptionProfileID); err == nil {
+			if optionProfileID, err = session.createCopyOfOptionProfile(ctx, session.payload.DiscoveryOptionProfileID); err == nil {
 				var scanTitle = fmt.Sprintf(session.payload.ScanNameFormatString, time.Now().Format(time.RFC3339))
 
-				if _, scanRef, err = session.apiSession.CreateScan(scanTitle, optionProfileID, intArrayToStringArray(bundle.appliances), bundle.networkID, bundle.devices, bundle.external); err == nil {
+				if _, scanRef, err = session.apiSession.CreateScanContext(ctx, scanTitle, optionProfileID, intArrayToStringArray(bundle.appliances), bundle.networkID, bundle.devices, bundle.external); err == nil {
 
 					scan := &scan{
 						Name:       scanTitle,
@@ -370,14 +370,14 @@ func (session *QsSession) populateGroupVulnerabilityChecks(detections []domain.M
 	return err
 }
 
-func (session *QsSession) prepareIPsAndAGMapping(matches []domain.Match) (groupIDToScanBundle map[string]*scanBundle, err error) {
+func (session *QsSession) prepareIPsAndAGMapping(ctx context.Context, matches []domain.Match) (groupIDToScanBundle map[string]*scanBundle, err error) {
 	var groups []*qualys.QSAssetGroup
-	if groups, err = session.getAssetGroups(append(session.payload.AssetGroups, session.payload.ExternalGroups...)); err == nil {
+	if groups, err = session.getAssetGroups(ctx, append(session.payload.AssetGroups, session.payload.ExternalGroups...)); err == nil {
 		groupIDToScanBundle = make(map[string]*scanBundle)
 
 		// even though slices in golang are pass-by-value, groups is a slice of pointers, so modifying the elements on the slice
 		// within a method call will effect the elements of the slice of the caller
-		if err = session.populateOnlineAppliances(groups); err == nil {
+		if err = session.populateOnlineAppliances(ctx, groups); err == nil {
 
 			// initialize the group map for each group that has at least one online appliance (scanning engine)
 			for _, group := range groups {
@@ -446,13 +446,5 @@ func (session *QsSession) isExternalGroup(groupID int) (val bool) {
 }
 
 func errShowsThatScanLimitHit(err error) (scanLimitHit bool) {
-	if err != nil {
-		errContents := err.Error()
-		if len(errContents) > 0 {
-			scanLimitHit = regexp.MustCompile("You are allowed to run \\d+ concurrent scans").MatchString(errContents) &&
-				regexp.MustCompile("This limit has already been reached").MatchString(errContents)
-		}
-	}
-
-	return scanLimitHit
+	return errors.Is(err, qualys.ErrScanLimitReached)
 }
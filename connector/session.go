@@ -7,6 +7,7 @@ import (
 	"github.com/nortonlifelock/log"
 	"github.com/nortonlifelock/qualys"
 	"sync"
+	"time"
 )
 
 type logger interface {
@@ -54,6 +55,35 @@ type QSPayload struct {
 	// WebAppOptionProfile holds the ID of the option profile that you'd like to use for web application scans (WAS - optional)
 	WebAppOptionProfile string `json:"web_app_option_profile"`
 
+	// DetectionPartitions splits the hosts of each detection load into ranges of host IDs which are loaded concurrently
+	DetectionPartitions int `json:"detection_partitions"`
+
+	// Incremental only loads the detections that changed since the last successful sync of each asset group or tag set
+	Incremental bool `json:"incremental"`
+
+	// WatermarkPath is the file the watermarks of the incremental sync are persisted to when no other store is set
+	WatermarkPath string `json:"watermark_path"`
+
+	// EnrichHostAssets loads the Asset Management host asset of each host to populate the MAC address, region and group of
+	// the devices returned alongside the detections
+	EnrichHostAssets bool `json:"enrich_host_assets"`
+
+	// PersistKnowledgeBase persists the knowledge base between sessions so that only the vulnerabilities modified since the
+	// last sync are downloaded
+	PersistKnowledgeBase bool `json:"persist_knowledge_base"`
+
+	// KnowledgeBasePath is the file the knowledge base is persisted to when no other store is set
+	KnowledgeBasePath string `json:"knowledge_base_path"`
+
+	// PurgeDeadHosts purges the hosts a scan found dead once their detections have been reported, and PurgeDryRun only
+	// logs the hosts that would be purged
+	PurgeDeadHosts bool `json:"purge_dead_hosts"`
+	PurgeDryRun    bool `json:"purge_dry_run"`
+
+	// PurgeGracePeriodDays is the number of days a dead host must go without being found alive by a VM scan before it is
+	// purged by PurgeDeadHosts. Defaults to 30 days
+	PurgeGracePeriodDays int `json:"purge_grace_period_days"`
+
 	// EC2ScanSettings controls the parameters used to create the ec2 scans
 	EC2ScanSettings map[string]*struct {
 		ConnectorName string `json:"connector_name"`
@@ -65,10 +95,22 @@ type QSPayload struct {
 type QsSession struct {
 	apiSession *qualys.Session
 
-	// The map of the vulnerability knowledge base which will eventually be replaced by a database
-	// for handling the vulnerabilities from Qualys
-	vulnerabilities   map[int]*qualys.QVulnerability
-	vulnerabilityLock *sync.Mutex
+	// knowledgeBase holds the vulnerabilities of the Qualys knowledge base, knowledgeBaseLock prevents concurrent refreshes.
+	// The store is read through knowledgeBaseStore as it can be replaced while the session is used, which storeLock guards
+	knowledgeBase     KnowledgeBaseStore
+	knowledgeBaseLock *sync.Mutex
+	storeLock         *sync.RWMutex
+
+	// qids loads the vulnerabilities of detections whose QIDs are missing from the knowledge base store
+	qids *qidLoader
+
+	// cves cross-references the CVEs and QIDs of the knowledge base store, it is built the first time it is used
+	cves    *qualys.CVEIndex
+	cveLock *sync.Mutex
+
+	// missingCVEs remembers the CVEs that no QID of the Qualys knowledge base detects, so that they aren't looked up again
+	// until missingQIDTTL has passed. It is guarded by cveLock
+	missingCVEs map[string]time.Time
 
 	lstream logger
 
@@ -80,22 +122,97 @@ type QsSession struct {
 
 	// Cache of asset groups (corresponding to the asset group slice in the QSPayload)
 	assetGroupCache []*qualys.QSAssetGroup
+
+	// watermarks persists the progress of the incremental detection sync
+	watermarks WatermarkStore
+
+	// hostAssets loads and caches the host assets of the hosts when the host asset enrichment is enabled
+	hostAssets *hostAssetLoader
 }
 
-// Connect returns a QsSession, which is used to process information returned from the Qualys API
-func Connect(ctx context.Context, lstream logger, sourceConfig domain.SourceConfig) (session *QsSession, err error) {
+// Connect returns a QsSession, which is used to process information returned from the Qualys API. The options are passed
+// through to the underlying Qualys API session
+func Connect(ctx context.Context, lstream logger, sourceConfig domain.SourceConfig, opts ...qualys.Option) (session *QsSession, err error) {
 	session = &QsSession{
 		lstream:           lstream,
-		vulnerabilities:   make(map[int]*qualys.QVulnerability),
-		vulnerabilityLock: &sync.Mutex{},
+		knowledgeBase:     NewMemoryKnowledgeBaseStore(),
+		knowledgeBaseLock: &sync.Mutex{},
+		storeLock:         &sync.RWMutex{},
 		appliances:        make(map[int][]int),
+		cveLock:           &sync.Mutex{},
+		missingCVEs:       make(map[string]time.Time),
 	}
+	session.qids = newQIDLoader(session)
+	session.hostAssets = newHostAssetLoader(session)
 
 	var payload = &QSPayload{}
 	if err = json.Unmarshal([]byte(sord(sourceConfig.Payload())), payload); err == nil {
 		session.payload = payload
-		session.apiSession, err = qualys.NewQualysAPISession(ctx, lstream, sourceConfig)
+
+		if payload.Incremental {
+			var path = payload.WatermarkPath
+			if len(path) == 0 {
+				path = defaultWatermarkPath
+			}
+			session.watermarks = NewFileWatermarkStore(path)
+		}
+
+		if payload.PersistKnowledgeBase {
+			var path = payload.KnowledgeBasePath
+			if len(path) == 0 {
+				path = defaultKnowledgeBasePath
+			}
+			session.knowledgeBase = NewFileKnowledgeBaseStore(path)
+		}
+
+		if session.apiSession, err = qualys.NewQualysAPISession(ctx, lstream, sourceConfig, opts...); err == nil && payload.PersistKnowledgeBase {
+			session.refreshPersistedKnowledgeBase(ctx)
+		}
 	}
 
 	return session, err
 }
+
+// SetWatermarkStore replaces the store the watermarks of the incremental detection sync are persisted to and enables
+// the incremental sync. Passing nil disables the incremental sync
+func (session *QsSession) SetWatermarkStore(store WatermarkStore) {
+	session.watermarks = store
+}
+
+// SetKnowledgeBaseStore replaces the store the knowledge base is held in. Passing nil holds the knowledge base in memory
+// for the life of the session. The store is replaced once a refresh of the knowledge base in progress has completed, and
+// it can be replaced while the session is used
+func (session *QsSession) SetKnowledgeBaseStore(store KnowledgeBaseStore) {
+	if store == nil {
+		store = NewMemoryKnowledgeBaseStore()
+	}
+
+	session.knowledgeBaseLock.Lock()
+	defer session.knowledgeBaseLock.Unlock()
+
+	session.storeLock.Lock()
+	session.knowledgeBase = store
+	session.storeLock.Unlock()
+
+	// the index is rebuilt from the new store the next time it is used
+	session.cveLock.Lock()
+	defer session.cveLock.Unlock()
+	session.cves = nil
+}
+
+// Close releases the underlying Qualys API session
+func (session *QsSession) Close() (err error) {
+	if session.apiSession != nil {
+		err = session.apiSession.Close()
+	}
+
+	return err
+}
+
+// knowledgeBaseStore returns the store the knowledge base is held in
+func (session *QsSession) knowledgeBaseStore() KnowledgeBaseStore {
+	session.storeLock.RLock()
+	defer session.storeLock.RUnlock()
+
+	return session.knowledgeBase
+}
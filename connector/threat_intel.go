@@ -0,0 +1,41 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"github.com/nortonlifelock/qualys"
+)
+
+// ThreatIntelligence is implemented by the vulnerabilities returned by KnowledgeBase and the vulnerabilities of the
+// detections returned by the connector, so that the threat intelligence of Qualys can be reached through a type assertion
+// on a domain.Vulnerability
+type ThreatIntelligence interface {
+	// ThreatIntel returns the threat intelligence tags Qualys attaches to the vulnerability
+	ThreatIntel() []qualys.ThreatIntelFlag
+
+	// Exploits returns the exploits Qualys correlates with the vulnerability
+	Exploits() []qualys.ExploitReference
+
+	// Malware returns the malware Qualys correlates with the vulnerability
+	Malware() []qualys.MalwareReference
+
+	// Weaponized determines whether attackers have the means to exploit the vulnerability
+	Weaponized() bool
+}
+
+// IsWeaponized determines whether attackers have the means to exploit the vulnerability of the QID, as reported by the
+// threat intelligence and the exploit and malware correlations of the Qualys knowledge base
+func (session *QsSession) IsWeaponized(ctx context.Context, qid int) (weaponized bool, err error) {
+	var vuln *qualys.QVulnerability
+	if vuln, err = session.knowledgeBaseStore().Vulnerability(qid); err == nil && vuln == nil {
+		vuln, err = session.qids.load(ctx, qid)
+	}
+
+	if err == nil {
+		weaponized = vuln.Weaponized()
+	} else {
+		err = fmt.Errorf("error while loading QID [%d] - %s", qid, err.Error())
+	}
+
+	return weaponized, err
+}
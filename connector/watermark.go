@@ -0,0 +1,112 @@
+package connector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultWatermarkPath is the file the watermarks are persisted to when incremental sync is enabled without a path
+	defaultWatermarkPath = "qualys_watermarks.json"
+
+	// watermarkOverlap is subtracted from the time Qualys answered the first request of a sync before it is stored as the
+	// watermark so that detections updated while Qualys was generating the response aren't skipped. Detections within the
+	// overlap are loaded twice
+	watermarkOverlap = 15 * time.Minute
+)
+
+// groupWatermarkKey returns the key the watermark of an asset group is stored under
+func groupWatermarkKey(groupID string) string {
+	return fmt.Sprintf("group-%s", groupID)
+}
+
+// tagWatermarkKey returns the key the watermark of a set of tags is stored under. The hosts must hold every tag of the
+// set, so the set is stored under a single key regardless of the order of the tags
+func tagWatermarkKey(tags []string) string {
+	var sorted = append([]string{}, tags...)
+	sort.Strings(sorted)
+	return fmt.Sprintf("%s%s", tagPrefix, strings.Join(sorted, ","))
+}
+
+// WatermarkStore persists the point in time up to which the detections of an asset group or tag set have been synced.
+// Keys are opaque strings created by the connector
+type WatermarkStore interface {
+	// Watermark returns the watermark stored for the key, or the zero time when the key has not been synced yet
+	Watermark(key string) (watermark time.Time, err error)
+
+	// SetWatermark stores the watermark for the key
+	SetWatermark(key string, watermark time.Time) (err error)
+}
+
+// fileWatermarkStore is the default WatermarkStore which persists the watermarks as a JSON object in a file
+type fileWatermarkStore struct {
+	path string
+	lock sync.Mutex
+}
+
+// NewFileWatermarkStore returns a WatermarkStore that persists the watermarks to a JSON file at the path
+func NewFileWatermarkStore(path string) WatermarkStore {
+	return &fileWatermarkStore{path: path}
+}
+
+// Watermark returns the watermark stored for the key
+func (store *fileWatermarkStore) Watermark(key string) (watermark time.Time, err error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	var watermarks map[string]time.Time
+	if watermarks, err = store.load(); err == nil {
+		watermark = watermarks[key]
+	}
+
+	return watermark, err
+}
+
+// SetWatermark stores the watermark for the key. The file is replaced through a rename so that a crash while writing
+// doesn't corrupt the watermarks of the other keys
+func (store *fileWatermarkStore) SetWatermark(key string, watermark time.Time) (err error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	var watermarks map[string]time.Time
+	if watermarks, err = store.load(); err == nil {
+		watermarks[key] = watermark.UTC()
+
+		var data []byte
+		if data, err = json.MarshalIndent(watermarks, "", "  "); err == nil {
+			var temp = fmt.Sprintf("%s.tmp", store.path)
+			if err = ioutil.WriteFile(temp, data, 0600); err == nil {
+				err = os.Rename(temp, store.path)
+			}
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("error while storing watermark to [%s] - %s", filepath.Clean(store.path), err.Error())
+	}
+
+	return err
+}
+
+// load reads the watermarks from the file, a missing file holds no watermarks
+func (store *fileWatermarkStore) load() (watermarks map[string]time.Time, err error) {
+	watermarks = make(map[string]time.Time)
+
+	var data []byte
+	if data, err = ioutil.ReadFile(store.path); err == nil {
+		if len(data) > 0 {
+			err = json.Unmarshal(data, &watermarks)
+		}
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+
+	return watermarks, err
+}
@@ -0,0 +1,132 @@
+package connector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// tempWatermarks returns the path of a watermark file in a new temporary directory, and a function that removes the
+// directory
+func tempWatermarks(t *testing.T) (path string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "qualys_watermarks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "watermarks.json"), func() { _ = os.RemoveAll(dir) }
+}
+
+func TestFileWatermarkStoreRoundTrip(t *testing.T) {
+	path, cleanup := tempWatermarks(t)
+	defer cleanup()
+
+	var group = time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("EST", -5*60*60))
+	var tags = time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	var store = NewFileWatermarkStore(path)
+	if err := store.SetWatermark(groupWatermarkKey("42"), group); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SetWatermark(tagWatermarkKey([]string{"web", "prod"}), tags); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be renamed over the watermarks, got [%v]", err)
+	}
+
+	// the watermarks are read back from the file by a new store
+	var reopened = NewFileWatermarkStore(path)
+	if watermark, err := reopened.Watermark(groupWatermarkKey("42")); err != nil || !watermark.Equal(group) {
+		t.Fatalf("expected the watermark of the group [%v], got [%v] [%v]", group, watermark, err)
+	}
+
+	if watermark, err := reopened.Watermark(tagWatermarkKey([]string{"prod", "web"})); err != nil || !watermark.Equal(tags) {
+		t.Fatalf("expected the watermark of the tags [%v], got [%v] [%v]", tags, watermark, err)
+	}
+
+	// setting a watermark keeps the watermarks of the other keys
+	var later = group.Add(time.Hour)
+	if err := reopened.SetWatermark(groupWatermarkKey("42"), later); err != nil {
+		t.Fatal(err)
+	}
+
+	if watermark, err := NewFileWatermarkStore(path).Watermark(groupWatermarkKey("42")); err != nil || !watermark.Equal(later) {
+		t.Fatalf("expected the watermark of the group to move to [%v], got [%v] [%v]", later, watermark, err)
+	}
+
+	if watermark, err := NewFileWatermarkStore(path).Watermark(tagWatermarkKey([]string{"web", "prod"})); err != nil || !watermark.Equal(tags) {
+		t.Fatalf("expected the watermark of the tags to be kept, got [%v] [%v]", watermark, err)
+	}
+}
+
+func TestFileWatermarkStoreMissingFile(t *testing.T) {
+	path, cleanup := tempWatermarks(t)
+	defer cleanup()
+
+	if watermark, err := NewFileWatermarkStore(path).Watermark(groupWatermarkKey("42")); err != nil || !watermark.IsZero() {
+		t.Fatalf("expected no watermark, got [%v] [%v]", watermark, err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected reading the store not to create the file, got [%v]", err)
+	}
+}
+
+func TestFileWatermarkStoreCorruptFile(t *testing.T) {
+	path, cleanup := tempWatermarks(t)
+	defer cleanup()
+
+	if err := ioutil.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var store = NewFileWatermarkStore(path)
+	if _, err := store.Watermark(groupWatermarkKey("42")); err == nil {
+		t.Fatal("expected a corrupt file to fail the read")
+	}
+
+	// the corrupt file isn't replaced, so the watermarks it held aren't silently dropped
+	if err := store.SetWatermark(groupWatermarkKey("42"), time.Now()); err == nil {
+		t.Fatal("expected a corrupt file to fail the write")
+	}
+
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "not json" {
+		t.Errorf("expected the corrupt file to be left alone, got [%s] [%v]", data, err)
+	}
+}
+
+func TestWatermarkKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		expected string
+	}{
+		{"asset group", groupWatermarkKey("42"), "group-42"},
+		{"single tag", tagWatermarkKey([]string{"web"}), "tag-web"},
+		{"tags sorted", tagWatermarkKey([]string{"web", "prod", "db"}), "tag-db,prod,web"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.key != test.expected {
+				t.Errorf("expected [%s], got [%s]", test.expected, test.key)
+			}
+		})
+	}
+
+	// the tags passed to the key aren't reordered
+	var tags = []string{"web", "prod"}
+	_ = tagWatermarkKey(tags)
+	if tags[0] != "web" || tags[1] != "prod" {
+		t.Errorf("expected the tags to be left in their order, got %v", tags)
+	}
+
+	if groupWatermarkKey("42") == tagWatermarkKey([]string{"42"}) {
+		t.Error("expected an asset group and a tag of the same name to be stored under different keys")
+	}
+}
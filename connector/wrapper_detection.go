@@ -14,6 +14,8 @@ type detection struct {
 	d                 qualys.QDetection
 	vulnerabilityInfo *vulnerabilityInfo
 
+	// ctx is the context of the call that returned the detection, which aborts the lazy load of its vulnerability
+	ctx     context.Context
 	session *QsSession
 	lock    sync.Mutex
 }
@@ -30,33 +32,26 @@ func (detection *detection) lazyLoadVulnerabilityInfoForDetection() {
 	}
 
 	if needToLoad {
-		detection.vulnerabilityInfo = lazyLoadVulnerabilityInfo(detection.d.QualysID, detection.session)
+		detection.vulnerabilityInfo = lazyLoadVulnerabilityInfo(detection.ctx, detection.d.QualysID, detection.session)
 	}
 }
 
-func lazyLoadVulnerabilityInfo(qid int, session *QsSession) (vi *vulnerabilityInfo) {
-	session.vulnerabilityLock.Lock()
-	if session.vulnerabilities[qid] == nil {
-		session.vulnerabilityLock.Unlock()
-
-		var err error
-		vulnInfo := &vulnerabilityInfo{}
-		vulnInfo.v, err = session.apiSession.LoadVulnerability(strconv.Itoa(qid))
-
-		session.vulnerabilityLock.Lock()
-		session.vulnerabilities[qid] = vulnInfo.v
-		session.vulnerabilityLock.Unlock()
-
-		if err == nil {
-			vi = vulnInfo
-		} else {
-			session.lstream.Send(log.Errorf(err, "error while loading vulnerability information for detection [%v]", qid))
-		}
-	} else {
-		vi = &vulnerabilityInfo{
-			v: session.vulnerabilities[qid],
-		}
-		session.vulnerabilityLock.Unlock()
+// lazyLoadVulnerabilityInfo returns the vulnerability of the QID from the knowledge base store. QIDs that are not stored
+// are loaded through the QID loader of the session, which batches the misses of concurrent detections into one request
+func lazyLoadVulnerabilityInfo(ctx context.Context, qid int, session *QsSession) (vi *vulnerabilityInfo) {
+	var vuln *qualys.QVulnerability
+	var err error
+	if vuln, err = session.knowledgeBaseStore().Vulnerability(qid); err == nil && vuln == nil {
+		vuln, err = session.qids.load(ctx, qid)
+	}
+
+	if vuln != nil {
+		vi = &vulnerabilityInfo{v: vuln}
+	}
+
+	// missing QIDs are reported once by the loader when they are requested from Qualys
+	if err != nil && err != errMissingQID {
+		session.lstream.Send(log.Errorf(err, "error while loading vulnerability information for detection [%v]", qid))
 	}
 
 	return vi
@@ -138,3 +133,43 @@ func (detection *detection) DetectionInformation() string {
 	detection.lazyLoadVulnerabilityInfoForDetection()
 	return detection.vulnerabilityInfo.DetectionInformation()
 }
+
+// ThreatIntel returns the threat intelligence tags of the vulnerability of the detection
+func (detection *detection) ThreatIntel() (flags []qualys.ThreatIntelFlag) {
+	detection.lazyLoadVulnerabilityInfoForDetection()
+	if detection.vulnerabilityInfo != nil {
+		flags = detection.vulnerabilityInfo.ThreatIntel()
+	}
+
+	return flags
+}
+
+// Exploits returns the exploits Qualys correlates with the vulnerability of the detection
+func (detection *detection) Exploits() (exploits []qualys.ExploitReference) {
+	detection.lazyLoadVulnerabilityInfoForDetection()
+	if detection.vulnerabilityInfo != nil {
+		exploits = detection.vulnerabilityInfo.Exploits()
+	}
+
+	return exploits
+}
+
+// Malware returns the malware Qualys correlates with the vulnerability of the detection
+func (detection *detection) Malware() (malware []qualys.MalwareReference) {
+	detection.lazyLoadVulnerabilityInfoForDetection()
+	if detection.vulnerabilityInfo != nil {
+		malware = detection.vulnerabilityInfo.Malware()
+	}
+
+	return malware
+}
+
+// Weaponized determines whether attackers have the means to exploit the vulnerability of the detection
+func (detection *detection) Weaponized() (weaponized bool) {
+	detection.lazyLoadVulnerabilityInfoForDetection()
+	if detection.vulnerabilityInfo != nil {
+		weaponized = detection.vulnerabilityInfo.Weaponized()
+	}
+
+	return weaponized
+}
@@ -11,6 +11,9 @@ import (
 
 type host struct {
 	h qualys.QHost
+
+	// asset is the Asset Management host asset of the host, which is nil unless the host asset enrichment is enabled
+	asset *qualys.HostAsset
 }
 
 func (h *host) SourceID() *string {
@@ -26,7 +29,12 @@ func (h *host) HostName() string {
 	return h.h.DNS.Text
 }
 
+// MAC returns the MAC address of the interface holding the IP of the host, taken from the host asset of the host
 func (h *host) MAC() string {
+	if h.asset != nil {
+		return h.asset.MACAddress(h.h.IPAddress)
+	}
+
 	return ""
 }
 
@@ -35,15 +43,44 @@ func (h *host) IP() string {
 }
 
 func (h *host) Region() *string {
+	if region := h.h.Region(); len(region) > 0 {
+		return &region
+	}
+
+	if h.asset != nil {
+		if region := h.asset.Region(); len(region) > 0 {
+			return &region
+		}
+	}
+
 	return nil
 }
 
 func (h *host) InstanceID() *string {
-	if len(h.h.EC2Id) > 0 {
-		return &h.h.EC2Id
-	} else {
-		return nil
+	if instanceID := h.h.InstanceID(); len(instanceID) > 0 {
+		return &instanceID
 	}
+
+	if h.asset != nil && len(h.asset.EC2Sources) > 0 && len(h.asset.EC2Sources[0].InstanceID) > 0 {
+		return &h.asset.EC2Sources[0].InstanceID
+	}
+
+	return nil
+}
+
+// Tags returns the names of the Qualys asset tags assigned to the host
+func (h *host) Tags() []string {
+	var tags = make([]string, 0, len(h.h.Tags))
+	for _, tag := range h.h.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return tags
+}
+
+// CloudProvider returns the cloud provider hosting the host along with the ID of the resource in the cloud provider
+func (h *host) CloudProvider() (provider string, resourceID string) {
+	return h.h.CloudProvider, h.h.CloudResourceID
 }
 
 func (h *host) ID() string {
@@ -57,7 +94,14 @@ func (h *host) Vulnerabilities(ctx context.Context) (param <-chan domain.Detecti
 	return out, err
 }
 
+// GroupID returns the group of the cloud instance of the host, taken from the host asset of the host
 func (h *host) GroupID() *string {
+	if h.asset != nil {
+		if groupID := h.asset.CloudGroupID(); len(groupID) > 0 {
+			return &groupID
+		}
+	}
+
 	return nil
 }
 
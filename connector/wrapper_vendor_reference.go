@@ -9,6 +9,12 @@ func (vr *vendorReference) Reference() string {
 	return vr.name
 }
 
+// Source returns the type of the reference, either one of the reference types of the domain package or ExploitReference
+// and MalwareReference
+func (vr *vendorReference) Source() int {
+	return vr.source
+}
+
 func (vr *vendorReference) String() string {
 	return vr.name
 }
@@ -2,11 +2,9 @@ package connector
 
 import (
 	"context"
-	"fmt"
 	"github.com/nortonlifelock/domain"
 	"github.com/nortonlifelock/qualys"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -167,11 +165,47 @@ func (vi *vulnerabilityInfo) References(ctx context.Context) (<-chan domain.Vuln
 			case out <- &vendorReference{source: domain.Vendor, name: vendor}:
 			}
 		}
+
+		for _, exploit := range vi.v.Exploits() {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- &vendorReference{source: ExploitReference, name: exploit.Reference}:
+			}
+		}
+
+		for _, malware := range vi.v.Malware() {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- &vendorReference{source: MalwareReference, name: malware.ID}:
+			}
+		}
 	}(out)
 
 	return out, nil
 }
 
+// ThreatIntel returns the threat intelligence tags Qualys attaches to the vulnerability
+func (vi *vulnerabilityInfo) ThreatIntel() []qualys.ThreatIntelFlag {
+	return vi.v.ThreatIntelFlags()
+}
+
+// Exploits returns the exploits Qualys correlates with the vulnerability
+func (vi *vulnerabilityInfo) Exploits() []qualys.ExploitReference {
+	return vi.v.Exploits()
+}
+
+// Malware returns the malware Qualys correlates with the vulnerability
+func (vi *vulnerabilityInfo) Malware() []qualys.MalwareReference {
+	return vi.v.Malware()
+}
+
+// Weaponized determines whether attackers have the means to exploit the vulnerability
+func (vi *vulnerabilityInfo) Weaponized() bool {
+	return vi.v.Weaponized()
+}
+
 func (vi *vulnerabilityInfo) extractVendors() []string {
 	vi.lazyLoadLock.Lock()
 	defer vi.lazyLoadLock.Unlock()
@@ -204,62 +238,42 @@ func (vi *vulnerabilityInfo) Updated() time.Time {
 
 // extractCVSSInformation extract the CVSS2 and CVSS3 vectors and base scores from the Qualys vulnerability object
 // from what I've seen, a CVSS2 score is always present, but a CVSS3 is not
-// therefore, a pointer to the CVSS3 score is passed returned. If a nil value was returned, a CVSS score was not provided
+// therefore, a pointer to the CVSS3 score is passed returned. If a nil value was returned, a CVSS score was not provided.
+// The vectors are the canonical vector strings (e.g. AV:N/AC:L/Au:N/C:P/I:P/A:P and CVSS:3.1/AV:N/AC:L/PR:N/...) built
+// from the VECTOR_STRING or the metric enumerations returned by Qualys, and are only empty when neither holds a valid
+// vector. A base score Qualys left at zero is calculated from the vector
 func (vi *vulnerabilityInfo) extractCVSSInformation() (float32, *float32, string, string) {
-	var cvssAttributes = make([]string, 0)
-	var cvss3Attributes = make([]string, 0)
 	var cvssBase = float32(0.0)
 	var cvss3Base *float32
-	// The CVSS/CVSS3 is stored as json, so we store individual fields where they're found and build the json later
+	var cvssVector, cvss3Vector string
+
 	if vi.v.CVSS != nil {
 		cvssBase = vi.v.CVSS.Base
-
-		if vi.v.CVSS.Impact != nil {
-			cvssAttributes = append(cvssAttributes,
-				fmt.Sprintf("\"IC\":%d", vi.v.CVSS.Impact.Confidentiality),
-				fmt.Sprintf("\"IA\":%d", vi.v.CVSS.Impact.Availability),
-				fmt.Sprintf("\"II\":%d", vi.v.CVSS.Impact.Integrity))
+		if vector, err := vi.v.CVSS.ParseVector(); err == nil {
+			cvssVector = vector.String()
+			cvssBase = baseScore(cvssBase, vector)
 		}
-		if vi.v.CVSS.Access != nil {
-			cvssAttributes = append(cvssAttributes,
-				fmt.Sprintf("\"AC\":%d", vi.v.CVSS.Access.Complexity),
-				fmt.Sprintf("\"AV\":%d", vi.v.CVSS.Access.Vector))
-		}
-
-		cvssAttributes = append(cvssAttributes,
-			fmt.Sprintf("\"RC\":%d", vi.v.CVSS.ReportConfidence),
-			fmt.Sprintf("\"RL\":%d", vi.v.CVSS.RemediationLevel),
-			fmt.Sprintf("\"Exploitability\":%d", vi.v.CVSS.Exploitability),
-			fmt.Sprintf("\"Auth\":%d", vi.v.CVSS.Authentication),
-			fmt.Sprintf("\"Base\":%.1f", vi.v.CVSS.Base),
-			fmt.Sprintf("\"Temporal\":%.1f", vi.v.CVSS.Temporal))
 	}
-	if vi.v.CVSS3 != nil {
-		cvss3Base = &vi.v.CVSS3.Base
 
-		if vi.v.CVSS3.Impact != nil {
-			cvss3Attributes = append(cvss3Attributes,
-				fmt.Sprintf("\"IC\":%d", vi.v.CVSS3.Impact.Confidentiality),
-				fmt.Sprintf("\"IA\":%d", vi.v.CVSS3.Impact.Availability),
-				fmt.Sprintf("\"II\":%d", vi.v.CVSS3.Impact.Integrity))
-		}
-		if vi.v.CVSS3.Access != nil {
-			cvss3Attributes = append(cvss3Attributes,
-				fmt.Sprintf("\"AC\":%d", vi.v.CVSS3.Access.Complexity),
-				fmt.Sprintf("\"AV\":%d", vi.v.CVSS3.Access.Vector))
+	if vi.v.CVSS3 != nil {
+		var base = vi.v.CVSS3.Base
+		if vector, err := vi.v.CVSS3.ParseVector(); err == nil {
+			cvss3Vector = vector.String()
+			base = baseScore(base, vector)
 		}
+		cvss3Base = &base
+	}
 
-		cvss3Attributes = append(cvss3Attributes,
-			fmt.Sprintf("\"RC\":%d", vi.v.CVSS3.ReportConfidence),
-			fmt.Sprintf("\"RL\":%d", vi.v.CVSS3.RemediationLevel),
-			fmt.Sprintf("\"Exploitability\":%d", vi.v.CVSS3.Exploitability),
-			fmt.Sprintf("\"Auth\":%d", vi.v.CVSS3.Authentication),
-			fmt.Sprintf("\"Base\":%.1f", vi.v.CVSS3.Base),
-			fmt.Sprintf("\"Temporal\":%.1f", vi.v.CVSS3.Temporal))
+	return cvssBase, cvss3Base, cvssVector, cvss3Vector
+}
+
+// baseScore returns the base score Qualys returned, or the base score of the vector when Qualys returned none
+func baseScore(base float32, vector qualys.CVSSVector) float32 {
+	if base == 0 {
+		if scores, err := vector.Scores(); err == nil {
+			base = float32(scores.Base)
+		}
 	}
-	// Buld the json strings
-	var cvssString = "{" + strings.Join(cvssAttributes, ",") + "}"
-	var cvss3String = "{" + strings.Join(cvss3Attributes, ",") + "}"
 
-	return cvssBase, cvss3Base, cvssString, cvss3String
+	return base
 }
@@ -0,0 +1,86 @@
+package connector
+
+import (
+	"context"
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	"github.com/nortonlifelock/domain"
+	"github.com/nortonlifelock/qualys"
+)
+
+// correlatedVuln is a VULN of the knowledge base with CVE and vendor references, and exploits and malware in its
+// CORRELATION section
+const correlatedVuln = `<VULN>
+	<QID>91234</QID>
+	<SEVERITY_LEVEL>5</SEVERITY_LEVEL>
+	<VENDOR_REFERENCE_LIST>
+		<VENDOR_REFERENCE><ID><![CDATA[KB4551762]]></ID><URL><![CDATA[https://support.microsoft.com/kb/4551762]]></URL></VENDOR_REFERENCE>
+	</VENDOR_REFERENCE_LIST>
+	<CVE_LIST>
+		<CVE><ID><![CDATA[CVE-2020-0796]]></ID><URL><![CDATA[http://cve.mitre.org/cgi-bin/cvename.cgi?name=CVE-2020-0796]]></URL></CVE>
+	</CVE_LIST>
+	<CORRELATION>
+		<EXPLOITS>
+			<EXPLT_SRC>
+				<SRC_NAME><![CDATA[The Exploit-DB]]></SRC_NAME>
+				<EXPLT_LIST>
+					<EXPLT><REF><![CDATA[48267]]></REF><DESC><![CDATA[SMBGhost Local Privilege Escalation]]></DESC><LINK><![CDATA[http://www.exploit-db.com/exploits/48267]]></LINK></EXPLT>
+				</EXPLT_LIST>
+			</EXPLT_SRC>
+		</EXPLOITS>
+		<MALWARE>
+			<MW_SRC>
+				<SRC_NAME><![CDATA[Trend Micro]]></SRC_NAME>
+				<MW_LIST>
+					<MW_INFO><MW_ID><![CDATA[TROJ_SMBGHOST.A]]></MW_ID><MW_TYPE><![CDATA[Trojan]]></MW_TYPE></MW_INFO>
+				</MW_LIST>
+			</MW_SRC>
+		</MALWARE>
+	</CORRELATION>
+	<THREAT_INTELLIGENCE>
+		<THREAT_INTEL id="2"><![CDATA[Exploit_Public]]></THREAT_INTEL>
+	</THREAT_INTELLIGENCE>
+</VULN>`
+
+func TestVulnerabilityInfoReferences(t *testing.T) {
+	var vuln qualys.QVulnerability
+	if err := xml.Unmarshal([]byte(correlatedVuln), &vuln); err != nil {
+		t.Fatal(err)
+	}
+
+	var info = &vulnerabilityInfo{v: &vuln}
+	references, err := info.References(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received = make([]vendorReference, 0)
+	for reference := range references {
+		received = append(received, *reference.(*vendorReference))
+	}
+
+	var expected = []vendorReference{
+		{source: domain.CVE, name: "CVE-2020-0796"},
+		{source: domain.Vendor, name: "KB4551762"},
+		{source: ExploitReference, name: "48267"},
+		{source: MalwareReference, name: "TROJ_SMBGHOST.A"},
+	}
+
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected the references %+v, got %+v", expected, received)
+	}
+
+	if ExploitReference == domain.CVE || ExploitReference == domain.Vendor || MalwareReference == ExploitReference {
+		t.Error("expected the exploit and malware sources to be kept apart from the sources of the domain package")
+	}
+
+	if flags := info.ThreatIntel(); len(flags) != 1 || flags[0] != qualys.ThreatPublicExploit {
+		t.Errorf("expected the threat intelligence of the vulnerability, got %v", flags)
+	}
+
+	if !info.Weaponized() || len(info.Exploits()) != 1 || len(info.Malware()) != 1 {
+		t.Errorf("expected the vulnerability to be weaponized with its correlations, got %+v %+v", info.Exploits(), info.Malware())
+	}
+}
@@ -1,6 +1,7 @@
 package connector
 
 import (
+	"context"
 	"fmt"
 	"github.com/nortonlifelock/domain"
 	"github.com/nortonlifelock/qualys"
@@ -158,7 +159,8 @@ func (f *webAppFindingWrapper) Vulnerability() (domain.Vulnerability, error) {
 	var err error
 	if f.vuln == nil {
 		qidInt, _ := strconv.Atoi(f.f.Qid)
-		f.vuln = lazyLoadVulnerabilityInfo(qidInt, f.session)
+		// the domain interface doesn't pass a context to the finding
+		f.vuln = lazyLoadVulnerabilityInfo(context.Background(), qidInt, f.session)
 	}
 	return f.vuln, err
 }
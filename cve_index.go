@@ -0,0 +1,97 @@
+package qualys
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CVEIndex cross-references the CVEs of the knowledge base with the QIDs that detect them. The index is safe for
+// concurrent use
+type CVEIndex struct {
+	lock sync.RWMutex
+	qids map[string]map[int]bool
+	cves map[int][]string
+}
+
+// NewCVEIndex returns an index of the CVEs of the vulnerabilities
+func NewCVEIndex(vulns ...*QVulnerability) (index *CVEIndex) {
+	index = &CVEIndex{
+		qids: make(map[string]map[int]bool),
+		cves: make(map[int][]string),
+	}
+
+	index.Add(vulns...)
+	return index
+}
+
+// Add indexes the CVEs of the vulnerabilities. A vulnerability that is already indexed replaces the CVEs indexed for its
+// QID, so CVEs removed from a vulnerability by Qualys are removed from the index
+func (index *CVEIndex) Add(vulns ...*QVulnerability) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	for _, vuln := range vulns {
+		if vuln == nil {
+			continue
+		}
+
+		for _, cve := range index.cves[vuln.QualysID] {
+			if delete(index.qids[cve], vuln.QualysID); len(index.qids[cve]) == 0 {
+				delete(index.qids, cve)
+			}
+		}
+
+		var cves = make([]string, 0, len(vuln.CVEs))
+		for _, reference := range vuln.CVEs {
+			var cve = normalizeCVE(reference.ID)
+			if len(cve) > 0 && index.qids[cve][vuln.QualysID] == false {
+				if index.qids[cve] == nil {
+					index.qids[cve] = make(map[int]bool)
+				}
+
+				index.qids[cve][vuln.QualysID] = true
+				cves = append(cves, cve)
+			}
+		}
+
+		sort.Strings(cves)
+		index.cves[vuln.QualysID] = cves
+	}
+}
+
+// QIDsForCVE returns the QIDs that detect the CVE, in ascending order
+func (index *CVEIndex) QIDsForCVE(cve string) (qids []int) {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	qids = make([]int, 0, len(index.qids[normalizeCVE(cve)]))
+	for qid := range index.qids[normalizeCVE(cve)] {
+		qids = append(qids, qid)
+	}
+
+	sort.Ints(qids)
+	return qids
+}
+
+// CVEsForQID returns the CVEs detected by the QID, in ascending order
+func (index *CVEIndex) CVEsForQID(qid int) (cves []string) {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	return append(make([]string, 0, len(index.cves[qid])), index.cves[qid]...)
+}
+
+// Indexed determines whether the QID has been added to the index, as a QID that references no CVEs is indexed without any
+func (index *CVEIndex) Indexed(qid int) (indexed bool) {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	_, indexed = index.cves[qid]
+	return indexed
+}
+
+// normalizeCVE formats a CVE ID the way Qualys lists them (e.g. CVE-2021-44228) so that lookups are case insensitive
+func normalizeCVE(cve string) string {
+	return strings.ToUpper(strings.TrimSpace(cve))
+}
@@ -0,0 +1,111 @@
+package qualys
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func vulnerabilityWithCVEs(qid int, cves ...string) *QVulnerability {
+	var vuln = &QVulnerability{QualysID: qid}
+	for _, cve := range cves {
+		vuln.CVEs = append(vuln.CVEs, QCVE{ID: cve})
+	}
+
+	return vuln
+}
+
+func TestCVEIndex(t *testing.T) {
+	var index = NewCVEIndex(
+		vulnerabilityWithCVEs(3, "CVE-2021-44228", "CVE-2021-45046"),
+		vulnerabilityWithCVEs(1, " cve-2021-44228 ", "CVE-2021-44228", ""),
+		vulnerabilityWithCVEs(2),
+		nil,
+	)
+
+	tests := []struct {
+		name string
+		cve  string
+		qids []int
+	}{
+		{"QIDs in ascending order", "CVE-2021-44228", []int{1, 3}},
+		{"case insensitive", "cve-2021-45046", []int{3}},
+		{"surrounding whitespace", " CVE-2021-45046\t", []int{3}},
+		{"unknown CVE", "CVE-2014-0160", []int{}},
+		{"empty CVE", "", []int{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if qids := index.QIDsForCVE(test.cve); !reflect.DeepEqual(qids, test.qids) {
+				t.Errorf("expected %v, got %v", test.qids, qids)
+			}
+		})
+	}
+
+	if cves := index.CVEsForQID(3); !reflect.DeepEqual(cves, []string{"CVE-2021-44228", "CVE-2021-45046"}) {
+		t.Errorf("expected the CVEs of QID 3, got %v", cves)
+	}
+
+	// a CVE listed twice, or in a different case, is indexed once for the QID
+	if cves := index.CVEsForQID(1); !reflect.DeepEqual(cves, []string{"CVE-2021-44228"}) {
+		t.Errorf("expected a single CVE for QID 1, got %v", cves)
+	}
+
+	if !index.Indexed(2) || len(index.CVEsForQID(2)) != 0 {
+		t.Errorf("expected QID 2 to be indexed without CVEs")
+	}
+
+	if index.Indexed(4) || len(index.CVEsForQID(4)) != 0 {
+		t.Errorf("expected QID 4 not to be indexed")
+	}
+}
+
+func TestCVEIndexReplacesVulnerability(t *testing.T) {
+	var index = NewCVEIndex(vulnerabilityWithCVEs(1, "CVE-2021-44228", "CVE-2021-45046"), vulnerabilityWithCVEs(2, "CVE-2021-45046"))
+
+	// Qualys removed a CVE from QID 1 and added another
+	index.Add(vulnerabilityWithCVEs(1, "CVE-2021-45105", "CVE-2021-45046"))
+
+	if qids := index.QIDsForCVE("CVE-2021-44228"); len(qids) != 0 {
+		t.Errorf("expected the removed CVE to be dropped from the index, got %v", qids)
+	}
+
+	if qids := index.QIDsForCVE("CVE-2021-45046"); !reflect.DeepEqual(qids, []int{1, 2}) {
+		t.Errorf("expected the CVE kept by QID 1 to stay indexed along with QID 2, got %v", qids)
+	}
+
+	if cves := index.CVEsForQID(1); !reflect.DeepEqual(cves, []string{"CVE-2021-45046", "CVE-2021-45105"}) {
+		t.Errorf("expected the CVEs of QID 1 to be replaced, got %v", cves)
+	}
+
+	// the returned slices are copies, so callers can't change the index
+	index.CVEsForQID(1)[0] = "CVE-0000-0000"
+	if cves := index.CVEsForQID(1); cves[0] != "CVE-2021-45046" {
+		t.Errorf("expected the index to be unchanged, got %v", cves)
+	}
+}
+
+func TestCVEIndexConcurrentUse(t *testing.T) {
+	var index = NewCVEIndex()
+
+	var wg sync.WaitGroup
+	for qid := 1; qid <= 50; qid++ {
+		wg.Add(2)
+		go func(qid int) {
+			defer wg.Done()
+			index.Add(vulnerabilityWithCVEs(qid, "CVE-2021-44228"))
+		}(qid)
+
+		go func(qid int) {
+			defer wg.Done()
+			_ = index.QIDsForCVE("CVE-2021-44228")
+			_ = index.Indexed(qid)
+		}(qid)
+	}
+	wg.Wait()
+
+	if qids := index.QIDsForCVE("CVE-2021-44228"); len(qids) != 50 {
+		t.Errorf("expected every QID to be indexed, got [%d]", len(qids))
+	}
+}
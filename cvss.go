@@ -0,0 +1,528 @@
+package qualys
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CVSS versions of a CVSSVector
+const (
+	CVSSVersion2  = "2.0"
+	CVSSVersion30 = "3.0"
+	CVSSVersion31 = "3.1"
+)
+
+// cvssMetric is a metric of a CVSS version along with the weight of each of its values. Metrics that aren't base metrics
+// may be left out of a vector, in which case they hold their undefined value
+type cvssMetric struct {
+	key       string
+	weights   map[string]float64
+	base      bool
+	undefined string
+}
+
+// cvss2Metrics are the metrics of CVSS v2 in the order of the specification
+var cvss2Metrics = []cvssMetric{
+	{key: "AV", base: true, weights: map[string]float64{"L": 0.395, "A": 0.646, "N": 1.0}},
+	{key: "AC", base: true, weights: map[string]float64{"H": 0.35, "M": 0.61, "L": 0.71}},
+	{key: "Au", base: true, weights: map[string]float64{"M": 0.45, "S": 0.56, "N": 0.704}},
+	{key: "C", base: true, weights: map[string]float64{"N": 0, "P": 0.275, "C": 0.660}},
+	{key: "I", base: true, weights: map[string]float64{"N": 0, "P": 0.275, "C": 0.660}},
+	{key: "A", base: true, weights: map[string]float64{"N": 0, "P": 0.275, "C": 0.660}},
+	{key: "E", undefined: "ND", weights: map[string]float64{"U": 0.85, "POC": 0.9, "F": 0.95, "H": 1, "ND": 1}},
+	{key: "RL", undefined: "ND", weights: map[string]float64{"OF": 0.87, "TF": 0.9, "W": 0.95, "U": 1, "ND": 1}},
+	{key: "RC", undefined: "ND", weights: map[string]float64{"UC": 0.9, "UR": 0.95, "C": 1, "ND": 1}},
+	{key: "CDP", undefined: "ND", weights: map[string]float64{"N": 0, "L": 0.1, "LM": 0.3, "MH": 0.4, "H": 0.5, "ND": 0}},
+	{key: "TD", undefined: "ND", weights: map[string]float64{"N": 0, "L": 0.25, "M": 0.75, "H": 1, "ND": 1}},
+	{key: "CR", undefined: "ND", weights: map[string]float64{"L": 0.5, "M": 1, "H": 1.51, "ND": 1}},
+	{key: "IR", undefined: "ND", weights: map[string]float64{"L": 0.5, "M": 1, "H": 1.51, "ND": 1}},
+	{key: "AR", undefined: "ND", weights: map[string]float64{"L": 0.5, "M": 1, "H": 1.51, "ND": 1}},
+}
+
+// cvss3Metrics are the metrics of CVSS v3.0 and v3.1 in the order of the specification. The weights of PR are those of
+// an unchanged scope, and the modified base metrics take the weights of the metric they modify
+var cvss3Metrics = []cvssMetric{
+	{key: "AV", base: true, weights: map[string]float64{"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2}},
+	{key: "AC", base: true, weights: map[string]float64{"L": 0.77, "H": 0.44}},
+	{key: "PR", base: true, weights: map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}},
+	{key: "UI", base: true, weights: map[string]float64{"N": 0.85, "R": 0.62}},
+	{key: "S", base: true, weights: map[string]float64{"U": 0, "C": 0}},
+	{key: "C", base: true, weights: map[string]float64{"H": 0.56, "L": 0.22, "N": 0}},
+	{key: "I", base: true, weights: map[string]float64{"H": 0.56, "L": 0.22, "N": 0}},
+	{key: "A", base: true, weights: map[string]float64{"H": 0.56, "L": 0.22, "N": 0}},
+	{key: "E", undefined: "X", weights: map[string]float64{"X": 1, "U": 0.91, "P": 0.94, "F": 0.97, "H": 1}},
+	{key: "RL", undefined: "X", weights: map[string]float64{"X": 1, "O": 0.95, "T": 0.96, "W": 0.97, "U": 1}},
+	{key: "RC", undefined: "X", weights: map[string]float64{"X": 1, "U": 0.92, "R": 0.96, "C": 1}},
+	{key: "CR", undefined: "X", weights: map[string]float64{"X": 1, "L": 0.5, "M": 1, "H": 1.5}},
+	{key: "IR", undefined: "X", weights: map[string]float64{"X": 1, "L": 0.5, "M": 1, "H": 1.5}},
+	{key: "AR", undefined: "X", weights: map[string]float64{"X": 1, "L": 0.5, "M": 1, "H": 1.5}},
+	{key: "MAV", undefined: "X", weights: map[string]float64{"X": 0, "N": 0, "A": 0, "L": 0, "P": 0}},
+	{key: "MAC", undefined: "X", weights: map[string]float64{"X": 0, "L": 0, "H": 0}},
+	{key: "MPR", undefined: "X", weights: map[string]float64{"X": 0, "N": 0, "L": 0, "H": 0}},
+	{key: "MUI", undefined: "X", weights: map[string]float64{"X": 0, "N": 0, "R": 0}},
+	{key: "MS", undefined: "X", weights: map[string]float64{"X": 0, "U": 0, "C": 0}},
+	{key: "MC", undefined: "X", weights: map[string]float64{"X": 0, "H": 0, "L": 0, "N": 0}},
+	{key: "MI", undefined: "X", weights: map[string]float64{"X": 0, "H": 0, "L": 0, "N": 0}},
+	{key: "MA", undefined: "X", weights: map[string]float64{"X": 0, "H": 0, "L": 0, "N": 0}},
+}
+
+// cvss2Enums map the enumerations Qualys returns for the CVSS v2 metrics to the values of the metrics
+var cvss2Enums = map[string]map[int]string{
+	"AV":  {1: "L", 2: "A", 3: "N"},
+	"AC":  {1: "H", 2: "M", 3: "L"},
+	"Au":  {1: "N", 2: "S", 3: "M"},
+	"CIA": {1: "N", 2: "P", 3: "C"},
+	"E":   {1: "U", 2: "POC", 3: "F", 4: "H"},
+	"RL":  {1: "OF", 2: "TF", 3: "W", 4: "U"},
+	"RC":  {1: "UC", 2: "UR", 3: "C"},
+}
+
+// cvss3Enums map the enumerations Qualys returns for the CVSS v3.x metrics to the values of the metrics, which follow the
+// same order as the enumerations of CVSS v2 from the least to the most severe value
+var cvss3Enums = map[string]map[int]string{
+	"AV":  {1: "P", 2: "L", 3: "A", 4: "N"},
+	"AC":  {1: "H", 2: "L"},
+	"PR":  {1: "H", 2: "L", 3: "N"},
+	"UI":  {1: "R", 2: "N"},
+	"S":   {1: "U", 2: "C"},
+	"CIA": {1: "N", 2: "L", 3: "H"},
+	"E":   {1: "U", 2: "P", 3: "F", 4: "H"},
+	"RL":  {1: "O", 2: "T", 3: "W", 4: "U"},
+	"RC":  {1: "U", 2: "R", 3: "C"},
+}
+
+// CVSSVector holds the metrics of a CVSS v2 or v3.x vector keyed by their abbreviation (e.g. AV, AC, PR). Metrics that
+// aren't base metrics are only held when the vector defines them
+type CVSSVector struct {
+	Version string
+	Metrics map[string]string
+}
+
+// CVSSScores are the scores of a CVSS vector. The temporal and environmental scores are calculated with the metrics the
+// vector doesn't define left undefined, as the specification requires
+type CVSSScores struct {
+	Base          float64
+	Temporal      float64
+	Environmental float64
+}
+
+// ParseCVSSVector parses a CVSS v2 vector (AV:N/AC:L/Au:N/C:P/I:P/A:P, with or without a CVSS:2.0 prefix or parentheses)
+// or a CVSS v3.x vector (CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H). Every base metric is required
+func ParseCVSSVector(vector string) (parsed CVSSVector, err error) {
+	var text = strings.Trim(strings.TrimSpace(vector), "()")
+
+	parsed = CVSSVector{Version: CVSSVersion2, Metrics: make(map[string]string)}
+	if strings.HasPrefix(text, "CVSS:") {
+		var prefix = strings.SplitN(text, "/", 2)
+		parsed.Version = strings.TrimPrefix(prefix[0], "CVSS:")
+		text = ""
+		if len(prefix) > 1 {
+			text = prefix[1]
+		}
+	}
+
+	var metrics []cvssMetric
+	if metrics, err = parsed.metrics(); err == nil {
+		for _, part := range strings.Split(text, "/") {
+			var pair = strings.SplitN(part, ":", 2)
+			if len(pair) != 2 {
+				err = fmt.Errorf("invalid metric [%s] in CVSS vector [%s]", part, vector)
+				break
+			}
+
+			if err = parsed.set(metrics, pair[0], pair[1]); err != nil {
+				err = fmt.Errorf("%s in CVSS vector [%s]", err.Error(), vector)
+				break
+			}
+		}
+
+		if err == nil {
+			for _, metric := range metrics {
+				if _, ok := parsed.Metrics[metric.key]; metric.base && !ok {
+					err = fmt.Errorf("base metric [%s] missing from CVSS vector [%s]", metric.key, vector)
+					break
+				}
+			}
+		}
+	}
+
+	return parsed, err
+}
+
+// CalculateCVSS calculates the scores of a CVSS v2 or v3.x vector after applying the environmental modifiers (e.g. CR:H or
+// MAV:L), which replace the metrics of the same name in the vector
+func CalculateCVSS(vector string, modifiers map[string]string) (scores CVSSScores, err error) {
+	var parsed CVSSVector
+	if parsed, err = ParseCVSSVector(vector); err == nil {
+		if parsed, err = parsed.WithModifiers(modifiers); err == nil {
+			scores, err = parsed.Scores()
+		}
+	}
+
+	return scores, err
+}
+
+// WithModifiers returns a copy of the vector with the metrics replaced by the modifiers. Only the temporal and
+// environmental metrics may be modified, the base metrics are modified through the modified base metrics of v3.x
+func (vector CVSSVector) WithModifiers(modifiers map[string]string) (modified CVSSVector, err error) {
+	modified = CVSSVector{Version: vector.Version, Metrics: make(map[string]string, len(vector.Metrics)+len(modifiers))}
+	for key, value := range vector.Metrics {
+		modified.Metrics[key] = value
+	}
+
+	var metrics []cvssMetric
+	if metrics, err = vector.metrics(); err == nil {
+		for key, value := range modifiers {
+			for _, metric := range metrics {
+				if metric.key == key && metric.base {
+					err = fmt.Errorf("base metric [%s] of the CVSS vector can't be modified", key)
+				}
+			}
+
+			if err == nil {
+				delete(modified.Metrics, key)
+				err = modified.set(metrics, key, value)
+			}
+
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	return modified, err
+}
+
+// String renders the vector in its canonical form, with the metrics in the order of the specification and the metrics
+// that aren't defined left out
+func (vector CVSSVector) String() string {
+	var parts = make([]string, 0, len(vector.Metrics)+1)
+	if vector.Version != CVSSVersion2 {
+		parts = append(parts, "CVSS:"+vector.Version)
+	}
+
+	var metrics, _ = vector.metrics()
+	for _, metric := range metrics {
+		if value, ok := vector.Metrics[metric.key]; ok && value != metric.undefined {
+			parts = append(parts, metric.key+":"+value)
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// Scores calculates the base, temporal and environmental scores of the vector
+func (vector CVSSVector) Scores() (scores CVSSScores, err error) {
+	var metrics []cvssMetric
+	if metrics, err = vector.metrics(); err == nil {
+		var weight = func(key string, value string) float64 {
+			for _, metric := range metrics {
+				if metric.key == key {
+					return metric.weights[value]
+				}
+			}
+
+			return 0
+		}
+
+		if vector.Version == CVSSVersion2 {
+			scores = vector.scores2(weight)
+		} else {
+			scores = vector.scores3(weight)
+		}
+	}
+
+	return scores, err
+}
+
+// scores2 calculates the scores of a CVSS v2 vector
+func (vector CVSSVector) scores2(weight func(key string, value string) float64) (scores CVSSScores) {
+	var w = func(key string) float64 {
+		return weight(key, vector.value(key))
+	}
+
+	var exploitability = 20 * w("AV") * w("AC") * w("Au")
+	var impact = 10.41 * (1 - (1-w("C"))*(1-w("I"))*(1-w("A")))
+	var temporal = w("E") * w("RL") * w("RC")
+
+	scores.Base = cvss2Base(impact, exploitability)
+	scores.Temporal = roundCVSS2(scores.Base * temporal)
+
+	var adjustedImpact = math.Min(10, 10.41*(1-(1-w("C")*w("CR"))*(1-w("I")*w("IR"))*(1-w("A")*w("AR"))))
+	var adjustedTemporal = roundCVSS2(cvss2Base(adjustedImpact, exploitability) * temporal)
+	scores.Environmental = roundCVSS2((adjustedTemporal + (10-adjustedTemporal)*w("CDP")) * w("TD"))
+
+	return scores
+}
+
+// scores3 calculates the scores of a CVSS v3.x vector
+func (vector CVSSVector) scores3(weight func(key string, value string) float64) (scores CVSSScores) {
+	var roundUp = roundUpCVSS31
+	if vector.Version == CVSSVersion30 {
+		roundUp = roundUpCVSS30
+	}
+
+	// the modified base metrics hold the value of the base metric they modify when they aren't defined
+	var modified = func(key string) string {
+		if value := vector.value("M" + key); value != "X" {
+			return value
+		}
+
+		return vector.value(key)
+	}
+
+	var temporal = weight("E", vector.value("E")) * weight("RL", vector.value("RL")) * weight("RC", vector.value("RC"))
+
+	var changed = vector.value("S") == "C"
+	var iss = 1 - (1-weight("C", vector.value("C")))*(1-weight("I", vector.value("I")))*(1-weight("A", vector.value("A")))
+	var impact = 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+
+	var exploitability = 8.22 * weight("AV", vector.value("AV")) * weight("AC", vector.value("AC")) *
+		cvss3Privileges(weight, vector.value("PR"), changed) * weight("UI", vector.value("UI"))
+
+	if impact > 0 {
+		scores.Base = roundUp(math.Min(cvss3Scope(impact+exploitability, changed), 10))
+	}
+	scores.Temporal = roundUp(scores.Base * temporal)
+
+	var modifiedChanged = modified("S") == "C"
+	var miss = math.Min(1-
+		(1-weight("CR", vector.value("CR"))*weight("C", modified("C")))*
+			(1-weight("IR", vector.value("IR"))*weight("I", modified("I")))*
+			(1-weight("AR", vector.value("AR"))*weight("A", modified("A"))), 0.915)
+
+	var modifiedImpact = 6.42 * miss
+	if modifiedChanged {
+		if vector.Version == CVSSVersion30 {
+			modifiedImpact = 7.52*(miss-0.029) - 3.25*math.Pow(miss-0.02, 15)
+		} else {
+			modifiedImpact = 7.52*(miss-0.029) - 3.25*math.Pow(miss*0.9731-0.02, 13)
+		}
+	}
+
+	var modifiedExploitability = 8.22 * weight("AV", modified("AV")) * weight("AC", modified("AC")) *
+		cvss3Privileges(weight, modified("PR"), modifiedChanged) * weight("UI", modified("UI"))
+
+	if modifiedImpact > 0 {
+		scores.Environmental = roundUp(roundUp(math.Min(cvss3Scope(modifiedImpact+modifiedExploitability, modifiedChanged), 10)) * temporal)
+	}
+
+	return scores
+}
+
+// metrics returns the metrics of the version of the vector
+func (vector CVSSVector) metrics() (metrics []cvssMetric, err error) {
+	switch vector.Version {
+	case CVSSVersion2:
+		metrics = cvss2Metrics
+	case CVSSVersion30, CVSSVersion31:
+		metrics = cvss3Metrics
+	default:
+		err = fmt.Errorf("unsupported CVSS version [%s]", vector.Version)
+	}
+
+	return metrics, err
+}
+
+// set validates the value of the metric before setting it, a metric may only be set once
+func (vector CVSSVector) set(metrics []cvssMetric, key string, value string) (err error) {
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+	if _, ok := vector.Metrics[key]; ok {
+		return fmt.Errorf("duplicate metric [%s]", key)
+	}
+
+	err = fmt.Errorf("unknown metric [%s] for CVSS %s", key, vector.Version)
+	for _, metric := range metrics {
+		if metric.key == key {
+			if _, ok := metric.weights[value]; ok {
+				vector.Metrics[key] = value
+				err = nil
+			} else {
+				err = fmt.Errorf("invalid value [%s] for metric [%s]", value, key)
+			}
+			break
+		}
+	}
+
+	return err
+}
+
+// value returns the value of the metric, or its undefined value when the vector doesn't define it
+func (vector CVSSVector) value(key string) string {
+	if value, ok := vector.Metrics[key]; ok {
+		return value
+	}
+
+	var metrics, _ = vector.metrics()
+	for _, metric := range metrics {
+		if metric.key == key {
+			return metric.undefined
+		}
+	}
+
+	return ""
+}
+
+// cvss2Base calculates the base score of CVSS v2 from the impact and exploitability sub scores
+func cvss2Base(impact float64, exploitability float64) float64 {
+	if impact == 0 {
+		return 0
+	}
+
+	return roundCVSS2(((0.6 * impact) + (0.4 * exploitability) - 1.5) * 1.176)
+}
+
+// cvss3Privileges returns the weight of the privileges required, which is higher when the scope is changed
+func cvss3Privileges(weight func(key string, value string) float64, value string, changed bool) float64 {
+	if changed {
+		switch value {
+		case "L":
+			return 0.68
+		case "H":
+			return 0.5
+		}
+	}
+
+	return weight("PR", value)
+}
+
+// cvss3Scope applies the multiplier of a changed scope to the sum of the impact and exploitability sub scores
+func cvss3Scope(sum float64, changed bool) float64 {
+	if changed {
+		return 1.08 * sum
+	}
+
+	return sum
+}
+
+// roundCVSS2 rounds a CVSS v2 score to one decimal
+func roundCVSS2(score float64) float64 {
+	return math.Round(score*10) / 10
+}
+
+// roundUpCVSS30 rounds a CVSS v3.0 score up to one decimal
+func roundUpCVSS30(score float64) float64 {
+	return math.Ceil(score*10) / 10
+}
+
+// roundUpCVSS31 rounds a CVSS v3.1 score up to one decimal, working on integers as the specification requires so that
+// floating point errors don't round a score up by an extra tenth
+func roundUpCVSS31(score float64) float64 {
+	var integer = int64(math.Round(score * 100000))
+	if integer%10000 == 0 {
+		return float64(integer) / 100000
+	}
+
+	return float64(integer/10000+1) / 10
+}
+
+// VectorString returns the canonical CVSS v2 vector of the vulnerability. The vector is built from the VECTOR_STRING
+// returned by Qualys, or from the metric enumerations when Qualys didn't return one. An empty string is returned when
+// neither holds a valid vector
+func (cvss *QCVSS) VectorString() string {
+	var vector, err = cvss.ParseVector()
+	if err != nil {
+		return ""
+	}
+
+	return vector.String()
+}
+
+// ParseVector returns the CVSS v2 vector of the vulnerability
+func (cvss *QCVSS) ParseVector() (vector CVSSVector, err error) {
+	if cvss == nil {
+		return vector, fmt.Errorf("no CVSS v2 metrics for the vulnerability")
+	}
+
+	if len(strings.TrimSpace(cvss.Vector)) > 0 {
+		return ParseCVSSVector(cvss.Vector)
+	}
+
+	vector = CVSSVector{Version: CVSSVersion2, Metrics: make(map[string]string)}
+	if cvss.Access != nil && cvss.Impact != nil {
+		for key, value := range map[string]string{
+			"AV": cvss2Enums["AV"][cvss.Access.Vector],
+			"AC": cvss2Enums["AC"][cvss.Access.Complexity],
+			"Au": cvss2Enums["Au"][cvss.Authentication],
+			"C":  cvss2Enums["CIA"][cvss.Impact.Confidentiality],
+			"I":  cvss2Enums["CIA"][cvss.Impact.Integrity],
+			"A":  cvss2Enums["CIA"][cvss.Impact.Availability],
+			"E":  cvss2Enums["E"][cvss.Exploitability],
+			"RL": cvss2Enums["RL"][cvss.RemediationLevel],
+			"RC": cvss2Enums["RC"][cvss.ReportConfidence],
+		} {
+			if len(value) > 0 {
+				vector.Metrics[key] = value
+			}
+		}
+
+		// the enumerations are parsed back so that a vector missing a base metric is rejected
+		vector, err = ParseCVSSVector(vector.String())
+	} else {
+		err = fmt.Errorf("no CVSS v2 vector for the vulnerability")
+	}
+
+	return vector, err
+}
+
+// VectorString returns the canonical CVSS v3.x vector of the vulnerability. The vector is built from the VECTOR_STRING
+// returned by Qualys, or from the metric enumerations and CVSS3_VERSION when Qualys didn't return one. An empty string is
+// returned when neither holds a valid vector
+func (cvss *QCVSS3) VectorString() string {
+	var vector, err = cvss.ParseVector()
+	if err != nil {
+		return ""
+	}
+
+	return vector.String()
+}
+
+// ParseVector returns the CVSS v3.x vector of the vulnerability. Vectors built from the enumerations are CVSS v3.0 when
+// Qualys doesn't return the version
+func (cvss *QCVSS3) ParseVector() (vector CVSSVector, err error) {
+	if cvss == nil {
+		return vector, fmt.Errorf("no CVSS v3 metrics for the vulnerability")
+	}
+
+	if len(strings.TrimSpace(cvss.Vector)) > 0 {
+		return ParseCVSSVector(cvss.Vector)
+	}
+
+	var version = strings.TrimPrefix(strings.TrimSpace(cvss.Version), "CVSS:")
+	if len(version) == 0 {
+		version = CVSSVersion30
+	}
+
+	vector = CVSSVector{Version: version, Metrics: make(map[string]string)}
+	if cvss.Attack != nil && cvss.Impact != nil {
+		for key, value := range map[string]string{
+			"AV": cvss3Enums["AV"][cvss.Attack.Vector],
+			"AC": cvss3Enums["AC"][cvss.Attack.Complexity],
+			"PR": cvss3Enums["PR"][cvss.PrivilegesRequired],
+			"UI": cvss3Enums["UI"][cvss.UserInteraction],
+			"S":  cvss3Enums["S"][cvss.Scope],
+			"C":  cvss3Enums["CIA"][cvss.Impact.Confidentiality],
+			"I":  cvss3Enums["CIA"][cvss.Impact.Integrity],
+			"A":  cvss3Enums["CIA"][cvss.Impact.Availability],
+			"E":  cvss3Enums["E"][cvss.ExploitCodeMaturity],
+			"RL": cvss3Enums["RL"][cvss.RemediationLevel],
+			"RC": cvss3Enums["RC"][cvss.ReportConfidence],
+		} {
+			if len(value) > 0 {
+				vector.Metrics[key] = value
+			}
+		}
+
+		// the enumerations are parsed back so that a vector missing a base metric or of an unknown version is rejected
+		vector, err = ParseCVSSVector(vector.String())
+	} else {
+		err = fmt.Errorf("no CVSS v3 vector for the vulnerability")
+	}
+
+	return vector, err
+}
@@ -0,0 +1,158 @@
+package qualys
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCalculateCVSS(t *testing.T) {
+	tests := []struct {
+		name      string
+		vector    string
+		modifiers map[string]string
+		expected  CVSSScores
+		err       string
+	}{
+		{"v3.1 critical changed scope", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", nil, CVSSScores{10.0, 10.0, 10.0}, ""},
+		{"v3.1 critical", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", nil, CVSSScores{9.8, 9.8, 9.8}, ""},
+		{"v3.0 medium", "CVSS:3.0/AV:N/AC:L/PR:L/UI:N/S:U/C:L/I:L/A:N", nil, CVSSScores{5.4, 5.4, 5.4}, ""},
+		{"v3.0 temporal", "CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/E:P/RL:O/RC:C", nil, CVSSScores{9.8, 8.8, 8.8}, ""},
+		{"v3.1 no impact", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", nil, CVSSScores{0, 0, 0}, ""},
+
+		// the modified impact of a changed scope is calculated differently by v3.0 and v3.1
+		{"v3.0 environmental", "CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:N", map[string]string{"CR": "L", "MS": "C"}, CVSSScores{9.1, 9.1, 9.6}, ""},
+		{"v3.1 environmental", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:N", map[string]string{"CR": "L", "MS": "C"}, CVSSScores{9.1, 9.1, 9.5}, ""},
+
+		{"v2 base", "AV:N/AC:L/Au:N/C:P/I:P/A:P", nil, CVSSScores{7.5, 7.5, 7.5}, ""},
+		{"v2 prefixed", "(CVSS:2.0/AV:N/AC:L/Au:N/C:P/I:P/A:P)", nil, CVSSScores{7.5, 7.5, 7.5}, ""},
+		{"v2 temporal", "AV:N/AC:L/Au:N/C:P/I:P/A:P/E:U/RL:OF/RC:C", nil, CVSSScores{7.5, 5.5, 5.5}, ""},
+		{"v2 environmental", "AV:N/AC:L/Au:N/C:P/I:P/A:P/E:U/RL:OF/RC:C", map[string]string{"CDP": "L", "TD": "M", "CR": "H", "IR": "H", "AR": "H"}, CVSSScores{7.5, 5.5, 5.1}, ""},
+
+		// the examples of the CVSS v2 specification
+		{"v2 CVE-2002-0392", "AV:N/AC:L/Au:N/C:N/I:N/A:C/E:F/RL:OF/RC:C/CDP:H/TD:H/CR:M/IR:M/AR:H", nil, CVSSScores{7.8, 6.4, 9.2}, ""},
+		{"v2 CVE-2003-0818", "AV:N/AC:L/Au:N/C:C/I:C/A:C/E:F/RL:OF/RC:C/CDP:H/TD:H/CR:M/IR:M/AR:L", nil, CVSSScores{10.0, 8.3, 9.0}, ""},
+
+		{"missing base metric", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/C:H/I:H/A:H", nil, CVSSScores{}, "base metric [S] missing"},
+		{"invalid value", "CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", nil, CVSSScores{}, "invalid value [X] for metric [AV]"},
+		{"duplicate metric", "AV:N/AV:L/AC:L/Au:N/C:P/I:P/A:P", nil, CVSSScores{}, "duplicate metric [AV]"},
+		{"unsupported version", "CVSS:4.0/AV:N", nil, CVSSScores{}, "unsupported CVSS version [4.0]"},
+		{"base metric modified", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", map[string]string{"AV": "L"}, CVSSScores{}, "can't be modified"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scores, err := CalculateCVSS(test.vector, test.modifiers)
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing [%s], got [%v]", test.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if scores != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, scores)
+			}
+		})
+	}
+}
+
+func TestCVSSVectorString(t *testing.T) {
+	tests := []struct {
+		name     string
+		vector   string
+		expected string
+	}{
+		{"v2 canonical", "AV:N/AC:L/Au:N/C:P/I:P/A:P", "AV:N/AC:L/Au:N/C:P/I:P/A:P"},
+		{"v2 reordered and undefined dropped", "(Au:N/AV:N/AC:L/C:P/I:P/A:P/E:ND/RL:OF)", "AV:N/AC:L/Au:N/C:P/I:P/A:P/RL:OF"},
+		{"v3 reordered and undefined dropped", "CVSS:3.1/S:U/AV:N/AC:L/PR:N/UI:N/C:H/I:H/A:H/E:X/RC:C", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/RC:C"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vector, err := ParseCVSSVector(test.vector)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if vector.String() != test.expected {
+				t.Errorf("expected [%s], got [%s]", test.expected, vector.String())
+			}
+		})
+	}
+}
+
+func TestQCVSSVectorString(t *testing.T) {
+	tests := []struct {
+		name     string
+		cvss     *QCVSS
+		expected string
+	}{
+		{"nil", nil, ""},
+		{"vector string", &QCVSS{Vector: "AV:N/AC:L/Au:N/C:P/I:P/A:P"}, "AV:N/AC:L/Au:N/C:P/I:P/A:P"},
+		{
+			"enumerations",
+			&QCVSS{
+				Access:           &QCVSSAccess{Vector: 3, Complexity: 3},
+				Impact:           &QCVSSImpact{Confidentiality: 2, Integrity: 2, Availability: 2},
+				Authentication:   1,
+				RemediationLevel: 1,
+			},
+			"AV:N/AC:L/Au:N/C:P/I:P/A:P/RL:OF",
+		},
+		{"enumerations missing a base metric", &QCVSS{Access: &QCVSSAccess{Vector: 3, Complexity: 3}, Impact: &QCVSSImpact{Confidentiality: 2, Integrity: 2, Availability: 2}}, ""},
+		{"no metrics", &QCVSS{Base: 7.5}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if vector := test.cvss.VectorString(); vector != test.expected {
+				t.Errorf("expected [%s], got [%s]", test.expected, vector)
+			}
+		})
+	}
+}
+
+func TestQCVSS3VectorString(t *testing.T) {
+	var critical = func(version string) *QCVSS3 {
+		return &QCVSS3{
+			Attack:             &QCVSSAttack{Vector: 4, Complexity: 2},
+			Impact:             &QCVSSImpact{Confidentiality: 3, Integrity: 3, Availability: 3},
+			PrivilegesRequired: 3,
+			UserInteraction:    2,
+			Scope:              1,
+			Version:            version,
+		}
+	}
+
+	var temporal = critical("3.1")
+	temporal.ExploitCodeMaturity, temporal.RemediationLevel, temporal.ReportConfidence = 2, 1, 3
+
+	var incomplete = critical("3.1")
+	incomplete.Scope = 0
+
+	tests := []struct {
+		name     string
+		cvss     *QCVSS3
+		expected string
+	}{
+		{"nil", nil, ""},
+		{"vector string", &QCVSS3{Vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", Version: "3.0"}, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H"},
+		{"enumerations", critical("3.1"), "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"},
+		{"enumerations with a prefixed version", critical("CVSS:3.1"), "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"},
+		{"enumerations without a version", critical(""), "CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"},
+		{"temporal enumerations", temporal, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/E:P/RL:O/RC:C"},
+		{"enumerations missing a base metric", incomplete, ""},
+		{"unsupported version", critical("4.0"), ""},
+		{"no metrics", &QCVSS3{Base: 9.8, Version: "3.1"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if vector := test.cvss.VectorString(); vector != test.expected {
+				t.Errorf("expected [%s], got [%s]", test.expected, vector)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package qualys
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ipRange is an inclusive range of IPv4 addresses
+type ipRange struct {
+	low  uint32
+	high uint32
+}
+
+// String formats the range the way Qualys lists IPs, as a single IP when the range holds a single address
+func (r ipRange) String() string {
+	if r.low == r.high {
+		return uint32ToIP(r.low).String()
+	}
+
+	return fmt.Sprintf("%s-%s", uint32ToIP(r.low), uint32ToIP(r.high))
+}
+
+// parseIPRanges parses IPv4 addresses, ranges (a.b.c.d-e.f.g.h) and CIDR blocks into merged ranges. Entries that are not
+// IPv4, such as IPv6 addresses and CIDR blocks, are returned as they were passed so they can be compared as text
+func parseIPRanges(entries []string) (ranges []ipRange, other []string, err error) {
+	ranges = make([]ipRange, 0, len(entries))
+	other = make([]string, 0)
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+
+		var low, high net.IP
+		if strings.Contains(entry, "/") {
+			var block *net.IPNet
+			if _, block, err = net.ParseCIDR(entry); err == nil {
+				if block.IP.To4() == nil {
+					// IPv6 blocks are compared as text along with the other entries that are not IPv4
+					other = append(other, strings.ToLower(entry))
+					continue
+				}
+
+				low = block.IP.To4()
+				high = make(net.IP, len(low))
+				for index := range low {
+					high[index] = low[index] | ^block.Mask[index]
+				}
+			}
+		} else if bounds := strings.Split(entry, "-"); len(bounds) == 2 {
+			low, high = net.ParseIP(strings.TrimSpace(bounds[0])), net.ParseIP(strings.TrimSpace(bounds[1]))
+		} else {
+			low = net.ParseIP(entry)
+			high = low
+		}
+
+		if err != nil {
+			err = fmt.Errorf("invalid IP entry [%s] - %s", entry, err.Error())
+			break
+		}
+
+		if low == nil || high == nil {
+			err = fmt.Errorf("invalid IP entry [%s]", entry)
+			break
+		}
+
+		if low.To4() != nil && high.To4() != nil {
+			var r = ipRange{low: ipToUint32(low), high: ipToUint32(high)}
+			if r.low > r.high {
+				err = fmt.Errorf("invalid IP range [%s], the start of the range is after its end", entry)
+				break
+			}
+
+			ranges = append(ranges, r)
+		} else {
+			other = append(other, strings.ToLower(entry))
+		}
+	}
+
+	return mergeIPRanges(ranges), other, err
+}
+
+// mergeIPRanges sorts the ranges and merges those that overlap or are adjacent
+func mergeIPRanges(ranges []ipRange) (merged []ipRange) {
+	merged = make([]ipRange, 0, len(ranges))
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].low < ranges[j].low
+	})
+
+	for _, r := range ranges {
+		if last := len(merged) - 1; last >= 0 && (merged[last].high == ^uint32(0) || r.low <= merged[last].high+1) {
+			if r.high > merged[last].high {
+				merged[last].high = r.high
+			}
+		} else {
+			merged = append(merged, r)
+		}
+	}
+
+	return merged
+}
+
+// subtractIPRanges returns the addresses of the merged ranges in a that are not in the merged ranges of b
+func subtractIPRanges(a []ipRange, b []ipRange) (remaining []ipRange) {
+	remaining = make([]ipRange, 0)
+
+	for _, r := range a {
+		var current = r
+		var empty bool
+
+		for _, cut := range b {
+			if cut.high < current.low || cut.low > current.high {
+				continue
+			}
+
+			if cut.low > current.low {
+				remaining = append(remaining, ipRange{low: current.low, high: cut.low - 1})
+			}
+
+			if cut.high >= current.high {
+				empty = true
+				break
+			}
+
+			current.low = cut.high + 1
+		}
+
+		if !empty {
+			remaining = append(remaining, current)
+		}
+	}
+
+	return remaining
+}
+
+// ipRangeStrings formats the ranges the way Qualys lists IPs
+func ipRangeStrings(ranges []ipRange) (entries []string) {
+	entries = make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		entries = append(entries, r.String())
+	}
+
+	return entries
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uint32ToIP(value uint32) net.IP {
+	var ip = make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, value)
+	return ip
+}
@@ -0,0 +1,102 @@
+package qualys
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIPRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		ranges  []string
+		other   []string
+		err     string
+	}{
+		{"empty", nil, []string{}, []string{}, ""},
+		{"single IPs", []string{"10.0.0.2", " 10.0.0.1 ", ""}, []string{"10.0.0.1-10.0.0.2"}, []string{}, ""},
+		{"range", []string{"10.0.0.1 - 10.0.0.10"}, []string{"10.0.0.1-10.0.0.10"}, []string{}, ""},
+		{"CIDR block", []string{"10.0.0.0/30"}, []string{"10.0.0.0-10.0.0.3"}, []string{}, ""},
+		{"CIDR block host bits", []string{"10.0.0.5/24"}, []string{"10.0.0.0-10.0.0.255"}, []string{}, ""},
+		{"overlapping entries merged", []string{"10.0.0.0/24", "10.0.0.10-10.0.1.5", "10.0.1.6"}, []string{"10.0.0.0-10.0.1.6"}, []string{}, ""},
+		{"disjoint entries sorted", []string{"10.0.1.1", "10.0.0.1"}, []string{"10.0.0.1", "10.0.1.1"}, []string{}, ""},
+		{"IPv6 address", []string{"2001:DB8::1"}, []string{}, []string{"2001:db8::1"}, ""},
+		{"IPv6 range", []string{"2001:db8::1-2001:db8::10"}, []string{}, []string{"2001:db8::1-2001:db8::10"}, ""},
+		{"IPv6 CIDR block", []string{"10.0.0.1", "2001:DB8::/32"}, []string{"10.0.0.1"}, []string{"2001:db8::/32"}, ""},
+		{"invalid IP", []string{"10.0.0.256"}, nil, nil, "invalid IP entry [10.0.0.256]"},
+		{"invalid CIDR block", []string{"10.0.0.0/33"}, nil, nil, "invalid IP entry [10.0.0.0/33]"},
+		{"inverted range", []string{"10.0.0.10-10.0.0.1"}, nil, nil, "the start of the range is after its end"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ranges, other, err := parseIPRanges(test.entries)
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing [%s], got [%v]", test.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("expected no error, got [%v]", err)
+			}
+
+			if got := ipRangeStrings(ranges); !reflect.DeepEqual(got, test.ranges) {
+				t.Errorf("expected the ranges %v, got %v", test.ranges, got)
+			}
+
+			if !reflect.DeepEqual(other, test.other) {
+				t.Errorf("expected the other entries %v, got %v", test.other, other)
+			}
+		})
+	}
+}
+
+func TestMergeIPRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []ipRange
+		merged []ipRange
+	}{
+		{"empty", nil, []ipRange{}},
+		{"adjacent", []ipRange{{5, 10}, {1, 4}}, []ipRange{{1, 10}}},
+		{"overlapping", []ipRange{{1, 6}, {4, 10}}, []ipRange{{1, 10}}},
+		{"contained", []ipRange{{1, 10}, {3, 4}}, []ipRange{{1, 10}}},
+		{"disjoint", []ipRange{{7, 10}, {1, 5}}, []ipRange{{1, 5}, {7, 10}}},
+		{"end of the address space", []ipRange{{10, ^uint32(0)}, {20, 30}, {0, 9}}, []ipRange{{0, ^uint32(0)}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if merged := mergeIPRanges(test.ranges); !reflect.DeepEqual(merged, test.merged) {
+				t.Errorf("expected %v, got %v", test.merged, merged)
+			}
+		})
+	}
+}
+
+func TestSubtractIPRanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         []ipRange
+		b         []ipRange
+		remaining []ipRange
+	}{
+		{"nothing removed", []ipRange{{1, 10}}, nil, []ipRange{{1, 10}}},
+		{"everything removed", []ipRange{{1, 10}}, []ipRange{{0, 20}}, []ipRange{}},
+		{"disjoint", []ipRange{{1, 10}}, []ipRange{{20, 30}}, []ipRange{{1, 10}}},
+		{"start removed", []ipRange{{1, 10}}, []ipRange{{0, 4}}, []ipRange{{5, 10}}},
+		{"end removed", []ipRange{{1, 10}}, []ipRange{{6, 12}}, []ipRange{{1, 5}}},
+		{"middle removed", []ipRange{{1, 10}}, []ipRange{{4, 6}}, []ipRange{{1, 3}, {7, 10}}},
+		{"several holes", []ipRange{{1, 20}, {30, 40}}, []ipRange{{5, 6}, {10, 12}, {35, 50}}, []ipRange{{1, 4}, {7, 9}, {13, 20}, {30, 34}}},
+		{"end of the address space", []ipRange{{0, ^uint32(0)}}, []ipRange{{1, ^uint32(0) - 1}}, []ipRange{{0, 0}, {^uint32(0), ^uint32(0)}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if remaining := subtractIPRanges(test.a, test.b); !reflect.DeepEqual(remaining, test.remaining) {
+				t.Errorf("expected %v, got %v", test.remaining, remaining)
+			}
+		})
+	}
+}
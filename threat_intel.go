@@ -0,0 +1,146 @@
+package qualys
+
+import (
+	"strings"
+)
+
+// ThreatIntelFlag is a threat intelligence tag Qualys attaches to a vulnerability in the knowledge base
+type ThreatIntelFlag string
+
+// Threat intelligence tags of the knowledge base, named as Qualys returns them in the THREAT_INTEL tags
+const (
+	ThreatZeroDay                  ThreatIntelFlag = "Zero_Day"
+	ThreatPublicExploit            ThreatIntelFlag = "Exploit_Public"
+	ThreatActiveAttacks            ThreatIntelFlag = "Active_Attacks"
+	ThreatHighLateralMovement      ThreatIntelFlag = "High_Lateral_Movement"
+	ThreatEasyExploit              ThreatIntelFlag = "Easy_Exploit"
+	ThreatHighDataLoss             ThreatIntelFlag = "High_Data_Loss"
+	ThreatDenialOfService          ThreatIntelFlag = "Denial_of_Service"
+	ThreatNoPatch                  ThreatIntelFlag = "No_Patch"
+	ThreatMalware                  ThreatIntelFlag = "Malware"
+	ThreatExploitKit               ThreatIntelFlag = "Exploit_Kit"
+	ThreatWormable                 ThreatIntelFlag = "Wormable"
+	ThreatPredictedHighRisk        ThreatIntelFlag = "Predicted_High_Risk"
+	ThreatPrivilegeEscalation      ThreatIntelFlag = "Privilege_Escalation"
+	ThreatUnauthenticated          ThreatIntelFlag = "Unauthenticated_Exploitation"
+	ThreatRemoteCodeExecution      ThreatIntelFlag = "Remote_Code_Execution"
+	ThreatRansomware               ThreatIntelFlag = "Ransomware"
+	ThreatKnownExploitedVulnerable ThreatIntelFlag = "Cisa_Known_Exploited_Vulns"
+)
+
+// weaponizedThreats are the threat intelligence tags which show that the vulnerability is exploited in the wild or that
+// an exploit is available to attackers
+var weaponizedThreats = map[ThreatIntelFlag]bool{
+	ThreatPublicExploit:            true,
+	ThreatActiveAttacks:            true,
+	ThreatMalware:                  true,
+	ThreatExploitKit:               true,
+	ThreatWormable:                 true,
+	ThreatRansomware:               true,
+	ThreatKnownExploitedVulnerable: true,
+}
+
+// ExploitReference is an exploit Qualys correlates with a vulnerability, along with the source that reported it
+type ExploitReference struct {
+	Source      string
+	Reference   string
+	Description string
+	Link        string
+}
+
+// MalwareReference is malware Qualys correlates with a vulnerability, along with the source that reported it
+type MalwareReference struct {
+	Source   string
+	ID       string
+	Type     string
+	Platform string
+	Alias    string
+	Rating   string
+	Link     string
+}
+
+// Flag returns the typed value of the threat intelligence tag. Tags that were stored with the CDATA section of the
+// response still holding the text are unwrapped
+func (intel QThreatIntel) Flag() ThreatIntelFlag {
+	var text = strings.TrimSpace(intel.Intel)
+	text = strings.TrimPrefix(text, "<![CDATA[")
+	text = strings.TrimSuffix(text, "]]>")
+	return ThreatIntelFlag(strings.TrimSpace(text))
+}
+
+// ThreatIntelFlags returns the threat intelligence tags of the vulnerability
+func (vuln *QVulnerability) ThreatIntelFlags() (flags []ThreatIntelFlag) {
+	flags = make([]ThreatIntelFlag, 0, len(vuln.ThreatIntel))
+	for _, intel := range vuln.ThreatIntel {
+		if flag := intel.Flag(); len(flag) > 0 {
+			flags = append(flags, flag)
+		}
+	}
+
+	return flags
+}
+
+// HasThreatIntel determines whether the vulnerability is tagged with the threat intelligence flag
+func (vuln *QVulnerability) HasThreatIntel(flag ThreatIntelFlag) bool {
+	for _, intel := range vuln.ThreatIntel {
+		if strings.EqualFold(string(intel.Flag()), string(flag)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Exploits returns the exploits Qualys correlates with the vulnerability
+func (vuln *QVulnerability) Exploits() (exploits []ExploitReference) {
+	exploits = make([]ExploitReference, 0)
+	for _, correlation := range vuln.Correlations {
+		for _, list := range correlation.ExploitList {
+			for _, exploit := range list.Exploits {
+				exploits = append(exploits, ExploitReference{
+					Source:      list.Source,
+					Reference:   exploit.Reference,
+					Description: exploit.Description,
+					Link:        exploit.Link,
+				})
+			}
+		}
+	}
+
+	return exploits
+}
+
+// Malware returns the malware Qualys correlates with the vulnerability
+func (vuln *QVulnerability) Malware() (malware []MalwareReference) {
+	malware = make([]MalwareReference, 0)
+	for _, correlation := range vuln.Correlations {
+		for _, list := range correlation.MalwareList {
+			for _, info := range list.MalwareList {
+				malware = append(malware, MalwareReference{
+					Source:   list.Source,
+					ID:       info.ID,
+					Type:     info.Type,
+					Platform: info.Platform,
+					Alias:    info.Alias,
+					Rating:   info.Rating,
+					Link:     info.Link,
+				})
+			}
+		}
+	}
+
+	return malware
+}
+
+// Weaponized determines whether attackers have the means to exploit the vulnerability, which is the case when Qualys
+// correlates exploits or malware with it, or tags it as publicly exploited, actively attacked, wormable, used by an
+// exploit kit, malware or ransomware, or as a known exploited vulnerability by CISA
+func (vuln *QVulnerability) Weaponized() bool {
+	for _, flag := range vuln.ThreatIntelFlags() {
+		if weaponizedThreats[flag] {
+			return true
+		}
+	}
+
+	return len(vuln.Exploits()) > 0 || len(vuln.Malware()) > 0
+}
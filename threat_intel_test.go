@@ -0,0 +1,141 @@
+package qualys
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+// threatIntelVuln is a VULN of the knowledge base as Qualys returns it with the THREAT_INTELLIGENCE and CORRELATION
+// sections
+const threatIntelVuln = `<VULN>
+	<QID>91234</QID>
+	<VULN_TYPE>Vulnerability</VULN_TYPE>
+	<SEVERITY_LEVEL>5</SEVERITY_LEVEL>
+	<TITLE><![CDATA[Microsoft Windows Remote Code Execution Vulnerability]]></TITLE>
+	<PATCHABLE>1</PATCHABLE>
+	<CVE_LIST>
+		<CVE><ID><![CDATA[CVE-2020-0796]]></ID><URL><![CDATA[http://cve.mitre.org/cgi-bin/cvename.cgi?name=CVE-2020-0796]]></URL></CVE>
+	</CVE_LIST>
+	<CORRELATION>
+		<EXPLOITS>
+			<EXPLT_SRC>
+				<SRC_NAME><![CDATA[The Exploit-DB]]></SRC_NAME>
+				<EXPLT_LIST>
+					<EXPLT><REF><![CDATA[CVE-2020-0796]]></REF><DESC><![CDATA[SMBGhost Local Privilege Escalation]]></DESC><LINK><![CDATA[http://www.exploit-db.com/exploits/48267]]></LINK></EXPLT>
+					<EXPLT><REF><![CDATA[CVE-2020-0796]]></REF><DESC><![CDATA[SMBGhost Remote Code Execution]]></DESC><LINK><![CDATA[http://www.exploit-db.com/exploits/48537]]></LINK></EXPLT>
+				</EXPLT_LIST>
+			</EXPLT_SRC>
+			<EXPLT_SRC>
+				<SRC_NAME><![CDATA[Metasploit]]></SRC_NAME>
+				<EXPLT_LIST>
+					<EXPLT><REF><![CDATA[CVE-2020-0796]]></REF><DESC><![CDATA[SMBv3 Compression Buffer Overflow]]></DESC><LINK><![CDATA[http://www.metasploit.com/modules/cve_2020_0796_smbghost]]></LINK></EXPLT>
+				</EXPLT_LIST>
+			</EXPLT_SRC>
+		</EXPLOITS>
+		<MALWARE>
+			<MW_SRC>
+				<SRC_NAME><![CDATA[Trend Micro]]></SRC_NAME>
+				<MW_LIST>
+					<MW_INFO><MW_ID><![CDATA[TROJ_SMBGHOST.A]]></MW_ID><MW_TYPE><![CDATA[Trojan]]></MW_TYPE><MW_PLATFORM><![CDATA[Windows]]></MW_PLATFORM><MW_RATING><![CDATA[High]]></MW_RATING><MW_LINK><![CDATA[http://www.trendmicro.com/vinfo/TROJ_SMBGHOST.A]]></MW_LINK></MW_INFO>
+				</MW_LIST>
+			</MW_SRC>
+		</MALWARE>
+	</CORRELATION>
+	<THREAT_INTELLIGENCE>
+		<THREAT_INTEL id="2"><![CDATA[Exploit_Public]]></THREAT_INTEL>
+		<THREAT_INTEL id="5"><![CDATA[High_Lateral_Movement]]></THREAT_INTEL>
+		<THREAT_INTEL id="9"><![CDATA[Wormable]]></THREAT_INTEL>
+	</THREAT_INTELLIGENCE>
+</VULN>`
+
+func TestQVulnerabilityThreatIntelDecode(t *testing.T) {
+	var vuln QVulnerability
+	if err := xml.Unmarshal([]byte(threatIntelVuln), &vuln); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []ThreatIntelFlag{ThreatPublicExploit, ThreatHighLateralMovement, ThreatWormable}; !reflect.DeepEqual(vuln.ThreatIntelFlags(), expected) {
+		t.Errorf("expected the flags %v, got %v", expected, vuln.ThreatIntelFlags())
+	}
+
+	if len(vuln.ThreatIntel) != 3 || vuln.ThreatIntel[1].ID != 5 {
+		t.Errorf("expected the IDs of the threat intelligence tags, got %+v", vuln.ThreatIntel)
+	}
+
+	if !vuln.HasThreatIntel(ThreatWormable) || !vuln.HasThreatIntel("exploit_public") || vuln.HasThreatIntel(ThreatZeroDay) {
+		t.Error("expected the vulnerability to be tagged with exactly the flags of the response")
+	}
+
+	var exploits = []ExploitReference{
+		{Source: "The Exploit-DB", Reference: "CVE-2020-0796", Description: "SMBGhost Local Privilege Escalation", Link: "http://www.exploit-db.com/exploits/48267"},
+		{Source: "The Exploit-DB", Reference: "CVE-2020-0796", Description: "SMBGhost Remote Code Execution", Link: "http://www.exploit-db.com/exploits/48537"},
+		{Source: "Metasploit", Reference: "CVE-2020-0796", Description: "SMBv3 Compression Buffer Overflow", Link: "http://www.metasploit.com/modules/cve_2020_0796_smbghost"},
+	}
+
+	if !reflect.DeepEqual(vuln.Exploits(), exploits) {
+		t.Errorf("expected the exploits\n%+v\ngot\n%+v", exploits, vuln.Exploits())
+	}
+
+	var malware = []MalwareReference{
+		{Source: "Trend Micro", ID: "TROJ_SMBGHOST.A", Type: "Trojan", Platform: "Windows", Rating: "High", Link: "http://www.trendmicro.com/vinfo/TROJ_SMBGHOST.A"},
+	}
+
+	if !reflect.DeepEqual(vuln.Malware(), malware) {
+		t.Errorf("expected the malware\n%+v\ngot\n%+v", malware, vuln.Malware())
+	}
+
+	if !vuln.Weaponized() {
+		t.Error("expected the vulnerability to be weaponized")
+	}
+}
+
+func TestQThreatIntelFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		intel    string
+		expected ThreatIntelFlag
+	}{
+		{"text", "Zero_Day", ThreatZeroDay},
+		{"surrounding whitespace", "\n\t\tZero_Day\n\t", ThreatZeroDay},
+		{"CDATA kept from the response", "<![CDATA[Exploit_Public]]>", ThreatPublicExploit},
+		{"whitespace inside the CDATA", " <![CDATA[ Ransomware ]]> ", ThreatRansomware},
+		{"empty", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if flag := (QThreatIntel{Intel: test.intel}).Flag(); flag != test.expected {
+				t.Errorf("expected [%s], got [%s]", test.expected, flag)
+			}
+		})
+	}
+}
+
+func TestQVulnerabilityWeaponized(t *testing.T) {
+	var exploit = []QCorrelation{{ExploitList: []QExploitList{{Source: "The Exploit-DB", Exploits: []QExploit{{Reference: "CVE-2020-0796"}}}}}}
+	var malware = []QCorrelation{{MalwareList: []QMalwareList{{Source: "Trend Micro", MalwareList: []QMalware{{ID: "TROJ_SMBGHOST.A"}}}}}}
+
+	tests := []struct {
+		name     string
+		vuln     QVulnerability
+		expected bool
+	}{
+		{"nothing", QVulnerability{}, false},
+		{"flags that aren't weaponized", QVulnerability{ThreatIntel: []QThreatIntel{{Intel: "Zero_Day"}, {Intel: "No_Patch"}, {Intel: "Easy_Exploit"}}}, false},
+		{"public exploit", QVulnerability{ThreatIntel: []QThreatIntel{{Intel: "Zero_Day"}, {Intel: "Exploit_Public"}}}, true},
+		{"actively attacked", QVulnerability{ThreatIntel: []QThreatIntel{{Intel: "Active_Attacks"}}}, true},
+		{"known exploited in CDATA", QVulnerability{ThreatIntel: []QThreatIntel{{Intel: "<![CDATA[Cisa_Known_Exploited_Vulns]]>"}}}, true},
+		{"correlated exploit", QVulnerability{Correlations: exploit}, true},
+		{"correlated malware", QVulnerability{Correlations: malware}, true},
+		{"empty correlation", QVulnerability{Correlations: []QCorrelation{{}}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if weaponized := test.vuln.Weaponized(); weaponized != test.expected {
+				t.Errorf("expected [%v], got [%v]", test.expected, weaponized)
+			}
+		})
+	}
+}
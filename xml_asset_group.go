@@ -25,4 +25,8 @@ type QSAssetGroup struct {
 	Ranges           []string `xml:"IP_SET>IP_RANGE"`
 	IPs              []string `xml:"IP_SET>IP"`
 	OnlineAppliances []int
+
+	DefaultApplianceID int      `xml:"DEFAULT_APPLIANCE_ID"`
+	Domains            []string `xml:"DOMAIN_LIST>DOMAIN"`
+	Comments           *CData   `xml:"COMMENTS"`
 }
@@ -2,6 +2,7 @@ package qualys
 
 import (
 	"encoding/xml"
+	"strings"
 	"time"
 )
 
@@ -79,17 +80,22 @@ type QHostListDetectionOutput struct {
 type QHost struct {
 	XMLName                xml.Name     `xml:"HOST"`
 	HostID                 int          `xml:"ID"`
+	AssetID                int          `xml:"ASSET_ID,omitempty"`
 	IPAddress              string       `xml:"IP"`
+	IPv6Address            string       `xml:"IPV6,omitempty"`
 	TrackingMethod         string       `xml:"TRACKING_METHOD"`
 	OperatingSystem        CData        `xml:"OS"`
+	OSCPE                  string       `xml:"OS_CPE,omitempty"`
 	DNS                    CData        `xml:"DNS"`
 	Netbios                string       `xml:"NETBIOS"`
+	QGHostID               string       `xml:"QG_HOSTID,omitempty"`
 	LastScan               time.Time    `xml:"LAST_SCAN_DATETIME"`
 	LastVMScan             time.Time    `xml:"LAST_VM_SCANNED_DATE"`
 	LastVMScanDuration     int          `xml:"LAST_VM_SCANNED_DURATION"`
 	LastVMAuthScan         *time.Time   `xml:"LAST_VM_AUTH_SCANNED_DATE,omitempty"`
 	LastVMAuthScanDuration *int         `xml:"LAST_VM_AUTH_SCANNED_DURATION,omitempty"`
 	LastPCScan             time.Time    `xml:"LAST_PC_SCANNED_DATE"`
+	LastBoot               *time.Time   `xml:"LAST_BOOT,omitempty"`
 	Detections             []QDetection `xml:"DETECTION_LIST>DETECTION"`
 	NetworkID              *string      `xml:"NETWORK_ID,omitempty"`
 	AssetGroupIDs          string       `xml:"ASSET_GROUP_IDS"`
@@ -97,9 +103,100 @@ type QHost struct {
 	// Host List Additions
 
 	EC2Id string `xml:"EC2_INSTANCE_ID"`
-	//QGHostI					string					`xml:"QG_HOSTID, omitempty"`
-	//Tags					string					`xml:"TAGS>TAG, omitempty"` // TODO
-	Metadata []interface{} `xml:"METADATA>EC2, omitempty"`
+
+	// Cloud identity of the host, populated when the host is a cloud instance
+	CloudProvider   string `xml:"CLOUD_PROVIDER,omitempty"`
+	CloudService    string `xml:"CLOUD_SERVICE,omitempty"`
+	CloudResourceID string `xml:"CLOUD_RESOURCE_ID,omitempty"`
+
+	// Tags are returned when show_tags is set and the cloud provider tags when show_cloud_tags is set
+	Tags              []QHostTag     `xml:"TAGS>TAG,omitempty"`
+	CloudProviderTags []QCloudTag    `xml:"CLOUD_PROVIDER_TAGS>CLOUD_TAG,omitempty"`
+	Metadata          QCloudMetadata `xml:"METADATA"`
+}
+
+// Region returns the region of the cloud instance from the metadata of the host, or an empty string when the host is not
+// a cloud instance or the metadata was not requested
+func (host QHost) Region() (region string) {
+	region = host.Metadata.Attribute(ec2RegionAttribute, azureLocationAttribute)
+	if len(region) == 0 {
+		// Google reports the zone as projects/<number>/zones/<zone>, the region is the zone without the trailing letter
+		if zone := host.Metadata.Attribute(googleZoneAttribute); len(zone) > 0 {
+			zone = zone[strings.LastIndex(zone, "/")+1:]
+			if index := strings.LastIndex(zone, "-"); index > 0 {
+				region = zone[:index]
+			}
+		}
+	}
+
+	return region
+}
+
+// InstanceID returns the ID of the cloud instance, taken from the EC2 instance ID or the metadata of the host
+func (host QHost) InstanceID() (instanceID string) {
+	instanceID = host.EC2Id
+	if len(instanceID) == 0 {
+		instanceID = host.Metadata.Attribute(ec2InstanceIDAttribute, azureVMIDAttribute, googleInstanceIDAttribute)
+	}
+
+	return instanceID
+}
+
+// Metadata attributes holding the region and instance ID of a cloud instance for each of the cloud providers
+const (
+	ec2RegionAttribute        = "latest/dynamic/instance-identity/document/region"
+	ec2InstanceIDAttribute    = "latest/meta-data/instance-id"
+	azureLocationAttribute    = "compute/location"
+	azureVMIDAttribute        = "compute/vmId"
+	googleZoneAttribute       = "instance/zone"
+	googleInstanceIDAttribute = "instance/id"
+)
+
+// QHostTag is a member of QHost and must be exported in order to be marshaled
+type QHostTag struct {
+	XMLName xml.Name `xml:"TAG"`
+	ID      int      `xml:"TAG_ID"`
+	Name    string   `xml:"NAME"`
+}
+
+// QCloudTag is a member of QHost and must be exported in order to be marshaled
+type QCloudTag struct {
+	XMLName     xml.Name `xml:"CLOUD_TAG"`
+	Name        string   `xml:"NAME"`
+	Value       string   `xml:"VALUE"`
+	LastSuccess string   `xml:"LAST_SUCCESS_DATE,omitempty"`
+}
+
+// QCloudMetadata is a member of QHost and must be exported in order to be marshaled
+type QCloudMetadata struct {
+	EC2    []QMetadataAttribute `xml:"EC2>ATTRIBUTE,omitempty"`
+	Azure  []QMetadataAttribute `xml:"AZURE>ATTRIBUTE,omitempty"`
+	Google []QMetadataAttribute `xml:"GOOGLE>ATTRIBUTE,omitempty"`
+}
+
+// Attribute returns the value of the first of the attributes found in the metadata of any of the cloud providers
+func (metadata QCloudMetadata) Attribute(names ...string) (value string) {
+	for _, name := range names {
+		for _, attributes := range [][]QMetadataAttribute{metadata.EC2, metadata.Azure, metadata.Google} {
+			for _, attribute := range attributes {
+				if attribute.Name == name && len(attribute.Value) > 0 {
+					return attribute.Value
+				}
+			}
+		}
+	}
+
+	return value
+}
+
+// QMetadataAttribute is a member of QCloudMetadata and must be exported in order to be marshaled
+type QMetadataAttribute struct {
+	XMLName     xml.Name `xml:"ATTRIBUTE"`
+	Name        string   `xml:"NAME"`
+	Status      string   `xml:"LAST_STATUS,omitempty"`
+	Value       string   `xml:"VALUE"`
+	LastSuccess string   `xml:"LAST_SUCCESS_DATE,omitempty"`
+	LastError   string   `xml:"LAST_ERROR,omitempty"`
 }
 
 // QDetection is a member of QHost and must be exported in order to be marshaled
@@ -168,7 +265,7 @@ type QVulnerability struct {
 	Solution                string             `xml:"SOLUTION,omitempty"`
 	SolutionComment         string             `xml:"SOLUTION_COMMENT,omitempty"`
 	ComplianceList          []QCompliance      `xml:"COMPLIANCE_LIST>COMPLIANCE,omitempty"`
-	Correlations            []QCorrelation     `xml:"CORRELATION>CORRELATION,omitempty"`
+	Correlations            []QCorrelation     `xml:"CORRELATION,omitempty"`
 	CVSS                    *QCVSS             `xml:"CVSS,omitempty"`
 	CVSS3                   *QCVSS3            `xml:"CVSS_V3,omitempty"`
 	PCI                     bool               `xml:"PCI_FLAG"`
@@ -193,11 +290,12 @@ type QBugTrack struct {
 	URL     string   `xml:"URL"`
 }
 
-// QThreatIntel is a member of QVulnerability and must be exported in order to be marshaled
+// QThreatIntel is a member of QVulnerability and must be exported in order to be marshaled. Intel holds the text of the
+// THREAT_INTEL tag, use Flag for its typed value
 type QThreatIntel struct {
 	XMLName xml.Name `xml:"THREAT_INTEL"`
 	ID      int      `xml:"id,attr"`
-	Intel   string   `xml:",innerxml"`
+	Intel   string   `xml:",chardata"`
 }
 
 // QSoftware is a member of QVulnerability and must be exported in order to be marshaled
@@ -269,11 +367,13 @@ type QMalware struct {
 	Link     string   `xml:"MW_LINK,omitempty"`
 }
 
-// QCVSS is a member of QVulnerability and must be exported in order to be marshaled
+// QCVSS is a member of QVulnerability and must be exported in order to be marshaled. Use VectorString for the canonical
+// vector of the metrics
 type QCVSS struct {
 	XMLName          xml.Name     `xml:"CVSS"`
 	Base             float32      `xml:"BASE"`
 	Temporal         float32      `xml:"TEMPORAL,omitempty"`
+	Vector           string       `xml:"VECTOR_STRING,omitempty"`
 	Access           *QCVSSAccess `xml:"ACCESS,omitempty"`
 	Impact           *QCVSSImpact `xml:"IMPACT,omitempty"`
 	Authentication   int          `xml:"AUTHENTICATION,omitempty"`
@@ -282,10 +382,29 @@ type QCVSS struct {
 	ReportConfidence int          `xml:"REPORT_CONFIDENCE,omitempty"`
 }
 
-// QCVSS3 is a member of QVulnerability and must be exported in order to be marshaled
+// QCVSS3 is a member of QVulnerability and must be exported in order to be marshaled. The metrics are held as the
+// enumerations Qualys returns them in, use VectorString for the canonical vector of the metrics
 type QCVSS3 struct {
-	QCVSS
-	XMLName xml.Name `xml:"CVSS_V3"`
+	XMLName             xml.Name     `xml:"CVSS_V3"`
+	Base                float32      `xml:"BASE"`
+	Temporal            float32      `xml:"TEMPORAL,omitempty"`
+	Vector              string       `xml:"VECTOR_STRING,omitempty"`
+	Attack              *QCVSSAttack `xml:"ATTACK,omitempty"`
+	Impact              *QCVSSImpact `xml:"IMPACT,omitempty"`
+	PrivilegesRequired  int          `xml:"PRIVILEGES_REQUIRED,omitempty"`
+	UserInteraction     int          `xml:"USER_INTERACTION,omitempty"`
+	Scope               int          `xml:"SCOPE,omitempty"`
+	ExploitCodeMaturity int          `xml:"EXPLOIT_CODE_MATURITY,omitempty"`
+	RemediationLevel    int          `xml:"REMEDIATION_LEVEL,omitempty"`
+	ReportConfidence    int          `xml:"REPORT_CONFIDENCE,omitempty"`
+	Version             string       `xml:"CVSS3_VERSION,omitempty"`
+}
+
+// QCVSSAttack is a member of QCVSS3 and must be exported in order to be marshaled
+type QCVSSAttack struct {
+	XMLName    xml.Name `xml:"ATTACK"`
+	Vector     int      `xml:"VECTOR,omitempty"`
+	Complexity int      `xml:"COMPLEXITY,omitempty"`
 }
 
 // QCVSSAccess is a member of QCVSS and must be exported in order to be marshaled
@@ -0,0 +1,174 @@
+package qualys
+
+import (
+	"encoding/xml"
+	"fmt"
+	"testing"
+)
+
+// cloudHost builds a HOST element of the Host List Detection API for a cloud instance with the metadata of the provider
+func cloudHost(provider string, trackingMethod string, ec2ID string, metadata string) string {
+	return fmt.Sprintf(`<HOST>
+	<ID>1234</ID>
+	<ASSET_ID>5678</ASSET_ID>
+	<IP>10.0.0.1</IP>
+	<TRACKING_METHOD>%s</TRACKING_METHOD>
+	<OS><![CDATA[Amazon Linux 2]]></OS>
+	<DNS><![CDATA[ip-10-0-0-1.ec2.internal]]></DNS>
+	<QG_HOSTID><![CDATA[0e5ba5a4-5ba9-4fb0-a9b6-2f5a7e9c3a10]]></QG_HOSTID>
+	<EC2_INSTANCE_ID>%s</EC2_INSTANCE_ID>
+	<CLOUD_PROVIDER>%s</CLOUD_PROVIDER>
+	<CLOUD_SERVICE>VM</CLOUD_SERVICE>
+	<CLOUD_RESOURCE_ID><![CDATA[resource-1]]></CLOUD_RESOURCE_ID>
+	<TAGS>
+		<TAG><TAG_ID>11</TAG_ID><NAME><![CDATA[Cloud Agent]]></NAME></TAG>
+		<TAG><TAG_ID>12</TAG_ID><NAME><![CDATA[Production]]></NAME></TAG>
+	</TAGS>
+	<CLOUD_PROVIDER_TAGS>
+		<CLOUD_TAG><NAME><![CDATA[env]]></NAME><VALUE><![CDATA[prod]]></VALUE><LAST_SUCCESS_DATE>2020-01-02T03:04:05Z</LAST_SUCCESS_DATE></CLOUD_TAG>
+	</CLOUD_PROVIDER_TAGS>
+	<METADATA>%s</METADATA>
+	<DETECTION_LIST>
+		<DETECTION><QID>38170</QID><TYPE>Confirmed</TYPE><SEVERITY>3</SEVERITY></DETECTION>
+	</DETECTION_LIST>
+</HOST>`, trackingMethod, ec2ID, provider, metadata)
+}
+
+// metadataAttributes builds the ATTRIBUTE elements of the metadata of a cloud provider from pairs of names and values
+func metadataAttributes(provider string, pairs ...string) string {
+	var attributes string
+	for index := 0; index+1 < len(pairs); index += 2 {
+		attributes += fmt.Sprintf("<ATTRIBUTE><NAME>%s</NAME><LAST_STATUS>Success</LAST_STATUS><VALUE><![CDATA[%s]]></VALUE><LAST_SUCCESS_DATE>2020-01-02T03:04:05Z</LAST_SUCCESS_DATE></ATTRIBUTE>", pairs[index], pairs[index+1])
+	}
+
+	return fmt.Sprintf("<%s>%s</%s>", provider, attributes, provider)
+}
+
+func TestQHostDecode(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		provider   string
+		region     string
+		instanceID string
+	}{
+		{
+			"AWS",
+			cloudHost("AWS", "EC2", "i-0a1b2c3d4e5f", metadataAttributes("EC2",
+				ec2RegionAttribute, "us-east-1",
+				ec2InstanceIDAttribute, "i-metadata",
+			)),
+			"AWS", "us-east-1", "i-0a1b2c3d4e5f",
+		},
+		{
+			"AWS without the EC2 instance ID",
+			cloudHost("AWS", "EC2", "", metadataAttributes("EC2",
+				ec2RegionAttribute, "eu-west-2",
+				ec2InstanceIDAttribute, "i-metadata",
+			)),
+			"AWS", "eu-west-2", "i-metadata",
+		},
+		{
+			"Azure",
+			cloudHost("AZURE", "AZURE", "", metadataAttributes("AZURE",
+				azureLocationAttribute, "westeurope",
+				azureVMIDAttribute, "2a3b4c5d-6e7f-8a9b-0c1d-2e3f4a5b6c7d",
+			)),
+			"AZURE", "westeurope", "2a3b4c5d-6e7f-8a9b-0c1d-2e3f4a5b6c7d",
+		},
+		{
+			"GCP",
+			cloudHost("GCP", "GCP", "", metadataAttributes("GOOGLE",
+				googleZoneAttribute, "projects/123456789/zones/us-central1-a",
+				googleInstanceIDAttribute, "4567890123456789",
+			)),
+			"GCP", "us-central1", "4567890123456789",
+		},
+		{
+			"not a cloud instance",
+			cloudHost("", "IP", "", ""),
+			"", "", "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var host QHost
+			if err := xml.Unmarshal([]byte(test.host), &host); err != nil {
+				t.Fatal(err)
+			}
+
+			if host.HostID != 1234 || host.AssetID != 5678 || host.QGHostID != "0e5ba5a4-5ba9-4fb0-a9b6-2f5a7e9c3a10" {
+				t.Errorf("expected the host, asset and agent IDs, got [%d] [%d] [%s]", host.HostID, host.AssetID, host.QGHostID)
+			}
+
+			if host.CloudProvider != test.provider || host.CloudService != "VM" || host.CloudResourceID != "resource-1" {
+				t.Errorf("expected the cloud identity of [%s], got [%s] [%s] [%s]", test.provider, host.CloudProvider, host.CloudService, host.CloudResourceID)
+			}
+
+			if len(host.Tags) != 2 || host.Tags[0].ID != 11 || host.Tags[0].Name != "Cloud Agent" || host.Tags[1].ID != 12 || host.Tags[1].Name != "Production" {
+				t.Errorf("expected the tags of the host, got %+v", host.Tags)
+			}
+
+			if len(host.CloudProviderTags) != 1 || host.CloudProviderTags[0].Name != "env" || host.CloudProviderTags[0].Value != "prod" {
+				t.Errorf("expected the cloud provider tags of the host, got %+v", host.CloudProviderTags)
+			}
+
+			if len(host.Detections) != 1 || host.Detections[0].QualysID != 38170 {
+				t.Errorf("expected the detections to decode alongside the host additions, got %+v", host.Detections)
+			}
+
+			if region := host.Region(); region != test.region {
+				t.Errorf("expected the region [%s], got [%s]", test.region, region)
+			}
+
+			if instanceID := host.InstanceID(); instanceID != test.instanceID {
+				t.Errorf("expected the instance ID [%s], got [%s]", test.instanceID, instanceID)
+			}
+		})
+	}
+}
+
+func TestHostListHostDecode(t *testing.T) {
+	var host HostListHost
+	if err := xml.Unmarshal([]byte(cloudHost("AZURE", "AZURE", "", metadataAttributes("AZURE", azureLocationAttribute, "westeurope"))), &host); err != nil {
+		t.Fatal(err)
+	}
+
+	if host.ID != "1234" || host.AssetID != "5678" || host.QGHostID != "0e5ba5a4-5ba9-4fb0-a9b6-2f5a7e9c3a10" || host.CloudProvider != "AZURE" {
+		t.Errorf("expected the identity of the host, got [%s] [%s] [%s] [%s]", host.ID, host.AssetID, host.QGHostID, host.CloudProvider)
+	}
+
+	if len(host.Tags) != 2 || len(host.CloudProviderTags) != 1 {
+		t.Errorf("expected the tags of the host, got %+v %+v", host.Tags, host.CloudProviderTags)
+	}
+
+	if location := host.Metadata.Attribute(azureLocationAttribute); location != "westeurope" {
+		t.Errorf("expected the location of the host from its metadata, got [%s]", location)
+	}
+}
+
+func TestQCloudMetadataAttribute(t *testing.T) {
+	var metadata = QCloudMetadata{
+		EC2:   []QMetadataAttribute{{Name: "first", Value: ""}, {Name: "second", Value: "ec2"}},
+		Azure: []QMetadataAttribute{{Name: "first", Value: "azure"}},
+	}
+
+	tests := []struct {
+		name     string
+		names    []string
+		expected string
+	}{
+		{"empty values are skipped", []string{"first"}, "azure"},
+		{"first name found wins", []string{"second", "first"}, "ec2"},
+		{"missing", []string{"third"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := metadata.Attribute(test.names...); value != test.expected {
+				t.Errorf("expected [%s], got [%s]", test.expected, value)
+			}
+		})
+	}
+}
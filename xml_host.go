@@ -1,23 +1,79 @@
 package qualys
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"time"
+)
 
 // HostListOutput is the struct for mapping Host List data from the Qualys API
 type HostListOutput struct {
 	XMLName  xml.Name `xml:"HOST_LIST_OUTPUT"`
 	Text     string   `xml:",chardata"`
 	Response struct {
-		Text     string `xml:",chardata"`
+		Text     string    `xml:",chardata"`
+		Date     time.Time `xml:"DATETIME"`
 		HostList struct {
-			Text string `xml:",chardata"`
-			Host []struct {
-				Text           string `xml:",chardata"`
-				ID             string `xml:"ID"`
-				IP             string `xml:"IP"`
-				NetworkID      string `xml:"NETWORK_ID"`
-				AssetGroupIDs  string `xml:"ASSET_GROUP_IDS"`
-				TrackingMethod string `xml:"TRACKING_METHOD"` // e.g. IP/EC2
-			} `xml:"HOST"`
+			Text string         `xml:",chardata"`
+			Host []HostListHost `xml:"HOST"`
 		} `xml:"HOST_LIST"`
+		Warning *QWarning `xml:"WARNING,omitempty"`
 	} `xml:"RESPONSE"`
 }
+
+// HostListHost is a member of HostListOutput and must be exported in order to be marshaled. The fields that are populated
+// depend on the details requested from the Host List API
+type HostListHost struct {
+	Text           string `xml:",chardata"`
+	ID             string `xml:"ID"`
+	AssetID        string `xml:"ASSET_ID,omitempty"`
+	IP             string `xml:"IP"`
+	IPv6           string `xml:"IPV6,omitempty"`
+	NetworkID      string `xml:"NETWORK_ID"`
+	AssetGroupIDs  string `xml:"ASSET_GROUP_IDS"`
+	TrackingMethod string `xml:"TRACKING_METHOD"` // e.g. IP/EC2
+
+	DNS     CData `xml:"DNS"`
+	DNSData struct {
+		Hostname CData `xml:"HOSTNAME"`
+		Domain   CData `xml:"DOMAIN"`
+		FQDN     CData `xml:"FQDN"`
+	} `xml:"DNS_DATA"`
+	Netbios  CData  `xml:"NETBIOS"`
+	OS       CData  `xml:"OS"`
+	QGHostID string `xml:"QG_HOSTID,omitempty"`
+
+	// Cloud identity of the host, populated when the host is a cloud instance
+	EC2InstanceID   string         `xml:"EC2_INSTANCE_ID,omitempty"`
+	CloudProvider   string         `xml:"CLOUD_PROVIDER,omitempty"`
+	CloudService    string         `xml:"CLOUD_SERVICE,omitempty"`
+	CloudResourceID string         `xml:"CLOUD_RESOURCE_ID,omitempty"`
+	Metadata        QCloudMetadata `xml:"METADATA"`
+
+	// Ownership and the user defined fields of the host
+	Owner    CData `xml:"OWNER"`
+	Comments CData `xml:"COMMENTS"`
+	UserDef  struct {
+		Labels []CData `xml:"LABEL"`
+		Values []CData `xml:"VALUE"`
+	} `xml:"USER_DEF"`
+
+	// Last scan dates of the host for each of the Qualys modules
+	LastVulnScan           *time.Time `xml:"LAST_VULN_SCAN_DATETIME,omitempty"`
+	LastVMScan             *time.Time `xml:"LAST_VM_SCANNED_DATE,omitempty"`
+	LastVMScanDuration     int        `xml:"LAST_VM_SCANNED_DURATION,omitempty"`
+	LastVMAuthScan         *time.Time `xml:"LAST_VM_AUTH_SCANNED_DATE,omitempty"`
+	LastVMAuthScanDuration int        `xml:"LAST_VM_AUTH_SCANNED_DURATION,omitempty"`
+	LastComplianceScan     *time.Time `xml:"LAST_COMPLIANCE_SCAN_DATETIME,omitempty"`
+	LastSCAPScan           *time.Time `xml:"LAST_SCAP_SCAN_DATETIME,omitempty"`
+	LastBoot               *time.Time `xml:"LAST_BOOT,omitempty"`
+
+	// Asset groups are returned when the AGs details are requested
+	AssetGroups []struct {
+		ID    string `xml:"ID"`
+		Title CData  `xml:"TITLE"`
+	} `xml:"ASSET_GROUP_LIST>ASSET_GROUP,omitempty"`
+
+	// Tags are returned when show_tags is set and the cloud provider tags when show_cloud_tags is set
+	Tags              []QHostTag  `xml:"TAGS>TAG,omitempty"`
+	CloudProviderTags []QCloudTag `xml:"CLOUD_PROVIDER_TAGS>CLOUD_TAG,omitempty"`
+}
@@ -0,0 +1,209 @@
+package qualys
+
+import (
+	"strings"
+	"time"
+)
+
+// hostAssetPage holds the host assets within the data of a HostAsset search or get
+type hostAssetPage struct {
+	HostAsset []HostAsset `xml:"HostAsset"`
+}
+
+// HostAsset is a host as it is tracked by the Qualys Asset Management module. Along with the identity of the host it holds
+// the inventory collected by the scanners and cloud agents. The inventory lists are only returned when they have been
+// collected for the host
+type HostAsset struct {
+	ID         int        `xml:"id"`
+	Name       string     `xml:"name"`
+	Type       string     `xml:"type"`
+	Created    *time.Time `xml:"created,omitempty"`
+	Modified   *time.Time `xml:"modified,omitempty"`
+	QWebHostID int        `xml:"qwebHostId"` // the host ID used by the VM APIs
+
+	Address         string `xml:"address"`
+	DNSHostName     string `xml:"dnsHostName"`
+	FQDN            string `xml:"fqdn"`
+	NetbiosName     string `xml:"netbiosName"`
+	NetworkGUID     string `xml:"networkGuid"`
+	OS              string `xml:"os"`
+	TrackingMethod  string `xml:"trackingMethod"`
+	Manufacturer    string `xml:"manufacturer"`
+	Model           string `xml:"model"`
+	BiosDescription string `xml:"biosDescription"`
+	Timezone        string `xml:"timezone"`
+	TotalMemory     int    `xml:"totalMemory"` // in MB
+
+	LastVulnScan       *time.Time `xml:"lastVulnScan,omitempty"`
+	LastComplianceScan *time.Time `xml:"lastComplianceScan,omitempty"`
+	LastSystemBoot     *time.Time `xml:"lastSystemBoot,omitempty"`
+	LastLoggedOnUser   string     `xml:"lastLoggedOnUser"`
+
+	AgentInfo *HostAssetAgent `xml:"agentInfo,omitempty"`
+
+	Software   []HostAssetSoftware  `xml:"software>list>HostAssetSoftware"`
+	OpenPorts  []HostAssetOpenPort  `xml:"openPort>list>HostAssetOpenPort"`
+	Interfaces []HostAssetInterface `xml:"networkInterface>list>HostAssetInterface"`
+	Processors []HostAssetProcessor `xml:"processor>list>HostAssetProcessor"`
+	Tags       []TagSimple          `xml:"tags>list>TagSimple"`
+
+	// The cloud sources the host was discovered through, populated when the host is a cloud instance
+	EC2Sources   []HostAssetEC2Source   `xml:"sourceInfo>list>Ec2AssetSourceSimple"`
+	AzureSources []HostAssetAzureSource `xml:"sourceInfo>list>AzureAssetSourceSimple"`
+	GCPSources   []HostAssetGCPSource   `xml:"sourceInfo>list>GcpAssetSourceSimple"`
+}
+
+// HostAssetSoftware is a software package installed on a HostAsset
+type HostAssetSoftware struct {
+	Name    string `xml:"name"`
+	Version string `xml:"version"`
+}
+
+// HostAssetOpenPort is a port found open on a HostAsset along with the service detected on the port
+type HostAssetOpenPort struct {
+	Port        int    `xml:"port"`
+	Protocol    string `xml:"protocol"`
+	ServiceID   int    `xml:"serviceId"`
+	ServiceName string `xml:"serviceName"`
+}
+
+// HostAssetInterface is a network interface of a HostAsset
+type HostAssetInterface struct {
+	InterfaceName  string `xml:"interfaceName"`
+	MACAddress     string `xml:"macAddress"`
+	Address        string `xml:"address"`
+	GatewayAddress string `xml:"gatewayAddress"`
+	Hostname       string `xml:"hostname"`
+}
+
+// HostAssetProcessor is a processor of a HostAsset
+type HostAssetProcessor struct {
+	Name  string `xml:"name"`
+	Speed int    `xml:"speed"` // in MHz
+}
+
+// HostAssetAgent holds the information of the cloud agent installed on a HostAsset
+type HostAssetAgent struct {
+	AgentID         string     `xml:"agentId"`
+	AgentVersion    string     `xml:"agentVersion"`
+	Status          string     `xml:"status"`
+	Platform        string     `xml:"platform"`
+	ConnectedFrom   string     `xml:"connectedFrom"`
+	Location        string     `xml:"location"`
+	ManifestVersion string     `xml:"manifestVersion"`
+	LastCheckedIn   *time.Time `xml:"lastCheckedIn,omitempty"`
+	ActivatedModule string     `xml:"activatedModule"`
+
+	Configuration struct {
+		ID   int    `xml:"id"`
+		Name string `xml:"name"`
+	} `xml:"agentConfiguration"`
+
+	ActivationKey struct {
+		ActivationID string `xml:"activationId"`
+		Title        string `xml:"title"`
+	} `xml:"activationKey"`
+}
+
+// HostAssetEC2Source is the AWS EC2 instance a HostAsset was discovered through
+type HostAssetEC2Source struct {
+	InstanceID       string `xml:"ec2InstanceId"`
+	InstanceState    string `xml:"instanceState"`
+	InstanceType     string `xml:"instanceType"`
+	Region           string `xml:"region"`
+	AvailabilityZone string `xml:"availabilityZone"`
+	AccountID        string `xml:"accountId"`
+	ImageID          string `xml:"imageId"`
+	VPCID            string `xml:"vpcId"`
+	SubnetID         string `xml:"subnetId"`
+	GroupID          string `xml:"groupId"`
+	GroupName        string `xml:"groupName"`
+	PrivateIPAddress string `xml:"privateIpAddress"`
+	MACAddress       string `xml:"macAddress"`
+}
+
+// HostAssetAzureSource is the Azure virtual machine a HostAsset was discovered through
+type HostAssetAzureSource struct {
+	VMID              string `xml:"vmId"`
+	Name              string `xml:"name"`
+	State             string `xml:"state"`
+	Location          string `xml:"location"`
+	SubscriptionID    string `xml:"subscriptionId"`
+	ResourceGroupName string `xml:"resourceGroupName"`
+	PrivateIPAddress  string `xml:"privateIpAddress"`
+	MACAddress        string `xml:"macAddress"`
+}
+
+// HostAssetGCPSource is the Google Cloud compute instance a HostAsset was discovered through
+type HostAssetGCPSource struct {
+	InstanceID       string `xml:"instanceId"`
+	Hostname         string `xml:"hostname"`
+	State            string `xml:"state"`
+	Zone             string `xml:"zone"`
+	ProjectID        string `xml:"projectId"`
+	Network          string `xml:"network"`
+	PrivateIPAddress string `xml:"privateIpAddress"`
+	MACAddress       string `xml:"macAddress"`
+}
+
+// MACAddress returns the MAC address of the interface holding the IP address. The MAC address of the first interface that
+// reports one is returned when no interface holds the IP address
+func (asset *HostAsset) MACAddress(ip string) (mac string) {
+	for _, iface := range asset.Interfaces {
+		if len(iface.MACAddress) > 0 {
+			if len(ip) > 0 && iface.Address == ip {
+				return iface.MACAddress
+			}
+
+			if len(mac) == 0 {
+				mac = iface.MACAddress
+			}
+		}
+	}
+
+	if len(mac) == 0 {
+		switch {
+		case len(asset.EC2Sources) > 0:
+			mac = asset.EC2Sources[0].MACAddress
+		case len(asset.AzureSources) > 0:
+			mac = asset.AzureSources[0].MACAddress
+		case len(asset.GCPSources) > 0:
+			mac = asset.GCPSources[0].MACAddress
+		}
+	}
+
+	return mac
+}
+
+// Region returns the region of the cloud instance the host was discovered through. For Google Cloud the region is derived
+// from the zone of the instance
+func (asset *HostAsset) Region() (region string) {
+	switch {
+	case len(asset.EC2Sources) > 0:
+		region = asset.EC2Sources[0].Region
+	case len(asset.AzureSources) > 0:
+		region = asset.AzureSources[0].Location
+	case len(asset.GCPSources) > 0:
+		region = asset.GCPSources[0].Zone
+		if index := strings.LastIndex(region, "-"); index > 0 {
+			region = region[:index]
+		}
+	}
+
+	return region
+}
+
+// CloudGroupID returns the group the cloud instance the host was discovered through belongs to: the security group of an
+// EC2 instance, the resource group of an Azure virtual machine or the project of a Google Cloud instance
+func (asset *HostAsset) CloudGroupID() (groupID string) {
+	switch {
+	case len(asset.EC2Sources) > 0:
+		groupID = asset.EC2Sources[0].GroupID
+	case len(asset.AzureSources) > 0:
+		groupID = asset.AzureSources[0].ResourceGroupName
+	case len(asset.GCPSources) > 0:
+		groupID = asset.GCPSources[0].ProjectID
+	}
+
+	return groupID
+}
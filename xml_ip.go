@@ -0,0 +1,40 @@
+package qualys
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// ipListOutput holds the IPs of the subscription returned by the IP List API
+type ipListOutput struct {
+	XMLName xml.Name `xml:"IP_LIST_OUTPUT"`
+	IPs     []string `xml:"RESPONSE>IP_SET>IP"`
+	Ranges  []string `xml:"RESPONSE>IP_SET>IP_RANGE"`
+}
+
+// excludedIPListOutput holds the excluded IPs returned by the Excluded IP List API
+type excludedIPListOutput struct {
+	XMLName xml.Name     `xml:"IP_LIST_OUTPUT"`
+	IPs     []ExcludedIP `xml:"RESPONSE>IP_SET>IP"`
+	Ranges  []ExcludedIP `xml:"RESPONSE>IP_SET>IP_RANGE"`
+}
+
+// ExcludedIP is an IP or IP range excluded from scanning, along with when the exclusion expires
+type ExcludedIP struct {
+	IP                 string `xml:",chardata"` // a single IP or an IP range
+	ExpirationDate     string `xml:"expiration_date,attr"`
+	DistributionGroups string `xml:"dg_names,attr"`
+	Comment            string `xml:"comment,attr"`
+}
+
+// Expires returns when the exclusion expires. False is returned when the exclusion does not expire
+func (excluded ExcludedIP) Expires() (expires time.Time, ok bool) {
+	if len(excluded.ExpirationDate) > 0 {
+		var err error
+		if expires, err = time.Parse(time.RFC3339, excluded.ExpirationDate); err == nil {
+			ok = true
+		}
+	}
+
+	return expires, ok
+}
@@ -0,0 +1,130 @@
+package qualys
+
+import (
+	"time"
+)
+
+// Rule types of a dynamic asset tag. Tags without a rule type are static and are only assigned to assets explicitly
+const (
+	TagRuleStatic       = "STATIC"
+	TagRuleGroovy       = "GROOVY"
+	TagRuleOSRegex      = "OS_REGEX"
+	TagRuleNetworkRange = "NETWORK_RANGE"
+	TagRuleNameContains = "NAME_CONTAINS"
+	TagRuleInstalledSW  = "INSTALLED_SOFTWARE"
+	TagRuleOpenPorts    = "OPEN_PORTS"
+	TagRuleVulnExist    = "VULN_EXIST"
+	TagRuleAssetSearch  = "ASSET_SEARCH"
+	TagRuleCloudAsset   = "CLOUD_ASSET"
+)
+
+// TagSimple is the reference to an asset tag used by the Asset Management API wherever a tag is listed
+type TagSimple struct {
+	ID   int    `xml:"id,omitempty"`
+	Name string `xml:"name,omitempty"`
+}
+
+// Tag is an asset tag of the Qualys Asset Management module. Tags form a hierarchy through the parent tag ID, and a tag
+// with a rule type is re-evaluated by Qualys against the assets as they change
+type Tag struct {
+	ID               int        `xml:"id,omitempty"`
+	Name             string     `xml:"name,omitempty"`
+	ParentTagID      int        `xml:"parentTagId,omitempty"`
+	Color            string     `xml:"color,omitempty"` // e.g. #FFFFFF
+	RuleType         string     `xml:"ruleType,omitempty"`
+	RuleText         string     `xml:"ruleText,omitempty"`
+	CriticalityScore int        `xml:"criticalityScore,omitempty"`
+	Created          *time.Time `xml:"created,omitempty"`
+	Modified         *time.Time `xml:"modified,omitempty"`
+
+	// Children holds the direct children of the tag when the tag is loaded
+	Children []TagSimple `xml:"children>list>TagSimple,omitempty"`
+}
+
+// tagPage holds the tags within the data of a tag call
+type tagPage struct {
+	Tag []Tag `xml:"Tag"`
+}
+
+// tagList holds the tags added, removed or set by a create or update call
+type tagList struct {
+	TagSimple []TagSimple `xml:"TagSimple"`
+}
+
+// newTagList returns nil when there are no tags so that the element is left out of the request
+func newTagList(tags []TagSimple) *tagList {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	return &tagList{TagSimple: tags}
+}
+
+// tagChanges holds the changes made to a list of tags by a create or update call
+type tagChanges struct {
+	Set    *tagList `xml:"set,omitempty"`
+	Add    *tagList `xml:"add,omitempty"`
+	Remove *tagList `xml:"remove,omitempty"`
+}
+
+// newTagChanges returns nil when no changes are made so that the element is left out of the request
+func newTagChanges(set []TagSimple, add []TagSimple, remove []TagSimple) *tagChanges {
+	if len(set) == 0 && len(add) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	return &tagChanges{
+		Set:    newTagList(set),
+		Add:    newTagList(add),
+		Remove: newTagList(remove),
+	}
+}
+
+// tagBody is the tag sent to create or update a tag
+type tagBody struct {
+	Name             string      `xml:"name,omitempty"`
+	ParentTagID      int         `xml:"parentTagId,omitempty"`
+	Color            string      `xml:"color,omitempty"`
+	RuleType         string      `xml:"ruleType,omitempty"`
+	RuleText         string      `xml:"ruleText,omitempty"`
+	CriticalityScore int         `xml:"criticalityScore,omitempty"`
+	Children         *tagChanges `xml:"children,omitempty"`
+}
+
+// TagUpdate holds the changes made to a tag by UpdateTag. Fields left at their zero value are not changed
+type TagUpdate struct {
+	Name             string
+	ParentTagID      int
+	Color            string
+	RuleType         string
+	RuleText         string
+	CriticalityScore int
+	AddChildren      []TagSimple
+	RemoveChildren   []TagSimple
+}
+
+// hostAssetTagUpdate adds or removes tags on the host assets matched by the filters of the request
+type hostAssetTagUpdate struct {
+	HostAsset struct {
+		Tags *tagChanges `xml:"tags"`
+	} `xml:"HostAsset"`
+}
+
+// TagNode is a tag along with the tags beneath it in the tag hierarchy
+type TagNode struct {
+	Tag
+	Nodes []*TagNode
+}
+
+// Walk calls visit for the node and every node beneath it, parents before their children. The depth of the node the walk
+// started from is 0
+func (node *TagNode) Walk(visit func(node *TagNode, depth int)) {
+	node.walk(visit, 0)
+}
+
+func (node *TagNode) walk(visit func(node *TagNode, depth int), depth int) {
+	visit(node, depth)
+	for _, child := range node.Nodes {
+		child.walk(visit, depth+1)
+	}
+}